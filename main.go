@@ -3,18 +3,54 @@ package main
 import (
 	"api"
 	"collector"
+	"context"
+	"health"
+	"imap_listener"
+	"logger"
+	"net"
+	"net/http"
+	"os"
 	"smtp_listener"
+	"tracing"
 )
 
+// address of API
+const httpAddress = ":8080"
+
 func main() {
 
+	// TRACING, spans are exported only if OTEL_TRACES_EXPORTER is set
+	shutdownTracing, err := tracing.Setup(context.Background())
+	if err != nil {
+		logger.Default().Error("Tracing is disabled", "error", err)
+		shutdownTracing = func(context.Context) error { return nil }
+	}
+
 	// GARBAGE COLLECTOR
 	go collector.Collect()
 
 	// Listen emails
 	go smtp_listener.Listen()
 
+	// Serve mailboxes over IMAP
+	go imap_listener.Listen()
+
 	// API HANDLER
 	router := api.Handle()
-	router.Run(":8080")
+	health.Starting("http", "Binding "+httpAddress)
+
+	listener, err := net.Listen("tcp", httpAddress)
+	if err != nil {
+		health.Failed("http", err)
+		logger.Default().Error("HTTP listener failed", "address", httpAddress, "error", err)
+		shutdownTracing(context.Background())
+		os.Exit(1)
+	}
+	health.Ok("http", "Listening on "+httpAddress)
+	logger.Default().Info("HTTP listener started", "address", httpAddress)
+
+	err = http.Serve(listener, router)
+	logger.Default().Error("HTTP listener stopped", "error", err)
+	shutdownTracing(context.Background())
+	os.Exit(1)
 }
@@ -0,0 +1,164 @@
+package api
+
+import (
+	"fmt"
+	"mail_parser"
+	"memdb"
+	"mime"
+	"models"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Attachment of message without its data
+type attachmentInfo struct {
+	Id          int    `json:"id"`
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	ContentId   string `json:"content_id,omitempty"`
+	Inline      bool   `json:"inline"`
+	Size        int    `json:"size"`
+}
+
+/**
+Return decoded content of message: headers, text and HTML bodies and list of attachments
+Message is not marked as seen
+@params email string
+@params message_id string
+
+@return void
+*/
+func messageContent(c *gin.Context) {
+	parsed, ok := parseMessage(c)
+	if !ok {
+		return
+	}
+
+	headers := map[string][]string{}
+	for name, values := range parsed.Header {
+		for _, value := range values {
+			headers[name] = append(headers[name], mail_parser.DecodeHeader(value))
+		}
+	}
+
+	attachments := make([]attachmentInfo, len(parsed.Attachments))
+	for i, part := range parsed.Attachments {
+		attachments[i] = attachmentInfo{
+			Id:          i + 1,
+			Filename:    part.Filename,
+			ContentType: part.ContentType,
+			ContentId:   part.ContentId,
+			Inline:      part.Inline,
+			Size:        len(part.Data),
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": http.StatusOK,
+		"message": "OK",
+		"content": gin.H{
+			"subject":     parsed.Subject,
+			"headers":     headers,
+			"text":        parsed.Text,
+			"html":        parsed.HTML,
+			"attachments": attachments,
+		},
+	})
+}
+
+/**
+Return sanitized HTML body of message, inline images are embedded, so HTML is shown without API key
+Message is not marked as seen
+@params email string
+@params message_id string
+
+@return void
+*/
+func messageHtml(c *gin.Context) {
+	parsed, ok := parseMessage(c)
+	if !ok {
+		return
+	}
+
+	html := parsed.SafeHTML()
+	if html == "" {
+		respondError(c, http.StatusNotFound, memdb.ErrorNotFound.String(), "Message has no HTML body")
+		return
+	}
+
+	// browser doesn't run scripts or fetch remote content even if sanitizer missed something
+	c.Header("Content-Security-Policy", "default-src 'none'; img-src data:; style-src 'unsafe-inline'; sandbox allow-popups")
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(html))
+}
+
+/**
+Download attachment of message
+@params email string
+@params message_id string
+@params attachment_id string - position of attachment in message, starting from 1
+
+@return void
+*/
+func messageAttachment(c *gin.Context) {
+	parsed, ok := parseMessage(c)
+	if !ok {
+		return
+	}
+
+	id, _ := strconv.Atoi(c.Param("attachment_id"))
+	if id < 1 || id > len(parsed.Attachments) {
+		respondError(c, http.StatusNotFound, memdb.ErrorNotFound.String(), "There is not such attachment")
+		return
+	}
+	part := parsed.Attachments[id-1]
+
+	filename := part.Filename
+	if filename == "" {
+		filename = fmt.Sprintf("attachment-%d", id)
+	}
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	contentType := part.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	c.Data(http.StatusOK, contentType, part.Data)
+}
+
+/**
+Get message of request and parse its MIME parts, error response is sent on failure
+@params c gin.Context - context of request
+
+@return *mail_parser.Message, bool - false if response is already sent
+*/
+func parseMessage(c *gin.Context) (*mail_parser.Message, bool) {
+	var messageItem models.MailBox
+	messageItem.Address = c.Param("email")
+	id, _ := strconv.ParseInt(c.Param("message_id"), 10, 0)
+	messageItem.Id = int(id)
+
+	// validate email and message id
+	err := checkEmailAndId(&messageItem, c)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, errorBadRequest, err.Error())
+		return nil, false
+	}
+
+	// get instance of Db
+	instance := memdb.GetInstance().Tenant(tenantName(c)).WithContext(c.Request.Context())
+	// trying to get a message using email address and message id
+	response := instance.GetMessage(messageItem.Address, messageItem.Id)
+	if !response.Success {
+		respondCommandError(c, response, "Failed get message")
+		return nil, false
+	}
+
+	parsed, err := mail_parser.Parse(response.Value.(*models.Message).RawData())
+	if err != nil {
+		respondError(c, http.StatusUnprocessableEntity, memdb.ErrorInvalid.String(), "Message can not be parsed: "+err.Error())
+		return nil, false
+	}
+
+	return parsed, true
+}
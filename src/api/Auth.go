@@ -0,0 +1,166 @@
+package api
+
+import (
+	"auth"
+	"memdb"
+	"models"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// context keys of request tenant and API key
+const (
+	tenantKey = "tenant"
+	apiKeyKey = "api_key"
+)
+
+// error codes of failed authentication
+const (
+	errorUnauthorized = "unauthorized"
+	errorForbidden    = "forbidden"
+)
+
+/**
+Authenticate request by API key from "Authorization: Bearer {key}" or "X-API-Key" header
+and resolve tenant of request
+Admin keys have access to every route of their tenant, mailbox tokens - only to routes of their mailbox
+
+@params c gin.Context - context of request
+
+@return void
+*/
+func authenticate(c *gin.Context) {
+	if !auth.Enabled() {
+		c.Set(tenantKey, requestTenant(c, nil))
+		return
+	}
+
+	apiKey := requestApiKey(c)
+	if apiKey == nil {
+		c.Header("WWW-Authenticate", "Bearer")
+		respondError(c, http.StatusUnauthorized, errorUnauthorized, "API key is missing or invalid")
+		return
+	}
+
+	tenant := requestTenant(c, apiKey)
+	if !auth.CanAccess(apiKey, tenant, c.Param("email")) {
+		respondError(c, http.StatusForbidden, errorForbidden, "API key has no access to this resource")
+		return
+	}
+
+	c.Set(tenantKey, tenant)
+}
+
+/**
+Check if API key of request has access to address other than mailbox of route
+Empty address requires admin key of tenant, if access is denied - send error response
+
+@params c gin.Context - context of request
+@params address string - target address of request
+
+@return bool
+*/
+func authorizeAddress(c *gin.Context, address string) bool {
+	if !auth.Enabled() || auth.CanAccess(requestApiKey(c), tenantName(c), address) {
+		return true
+	}
+
+	respondError(c, http.StatusForbidden, errorForbidden, "API key has no access to this resource")
+	return false
+}
+
+/**
+Authenticate request which manages tenants, only static admin keys are allowed
+
+@params c gin.Context - context of request
+
+@return void
+*/
+func authenticateSuperAdmin(c *gin.Context) {
+	if !auth.Enabled() {
+		return
+	}
+
+	apiKey := requestApiKey(c)
+	if apiKey == nil {
+		c.Header("WWW-Authenticate", "Bearer")
+		respondError(c, http.StatusUnauthorized, errorUnauthorized, "API key is missing or invalid")
+		return
+	}
+
+	if !auth.CanAccess(apiKey, "", "") {
+		respondError(c, http.StatusForbidden, errorForbidden, "API key has no access to this resource")
+		return
+	}
+}
+
+/**
+Get tenant of request from route, otherwise from API key
+Requests without tenant go to default one
+
+@params c gin.Context - context of request
+@params apiKey *models.ApiKey - key of request, nil if authentication is disabled
+
+@return string
+*/
+func requestTenant(c *gin.Context, apiKey *models.ApiKey) string {
+	if tenant := c.Param("tenant"); tenant != "" {
+		return tenant
+	}
+	if apiKey != nil && apiKey.Tenant != "" {
+		return apiKey.Tenant
+	}
+
+	return memdb.DefaultTenant
+}
+
+/**
+Get tenant resolved by authentication
+
+@params c gin.Context - context of request
+
+@return string
+*/
+func tenantName(c *gin.Context) string {
+	return c.GetString(tenantKey)
+}
+
+/**
+Find API key of request among existing keys, found key is kept in context of request
+
+@params c gin.Context - context of request
+
+@return *models.ApiKey - nil if request has no key or key is unknown
+*/
+func requestApiKey(c *gin.Context) *models.ApiKey {
+	if apiKey, ok := c.Get(apiKeyKey); ok {
+		return apiKey.(*models.ApiKey)
+	}
+
+	apiKey := auth.Lookup(requestKey(c))
+	c.Set(apiKeyKey, apiKey)
+
+	return apiKey
+}
+
+/**
+Get API key from request headers
+
+@params c gin.Context - context of request
+
+@return string
+*/
+func requestKey(c *gin.Context) string {
+	if key := c.GetHeader("X-API-Key"); key != "" {
+		return key
+	}
+
+	header := c.GetHeader("Authorization")
+	if strings.HasPrefix(header, "Bearer ") {
+		return strings.TrimSpace(strings.TrimPrefix(header, "Bearer "))
+	}
+
+	return ""
+}
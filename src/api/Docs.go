@@ -0,0 +1,45 @@
+package api
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OpenAPI description of every route
+//go:embed openapi.json
+var openApiSpec []byte
+
+// Static files of viewer of OpenAPI description, they are embedded, so viewer works without network
+//go:embed docs
+var docsAssets embed.FS
+
+// Server of viewer files, paths are relative to /docs
+var docsFiles = func() http.Handler {
+	files, err := fs.Sub(docsAssets, "docs")
+	if err != nil {
+		panic(err)
+	}
+
+	return http.StripPrefix("/docs", http.FileServer(http.FS(files)))
+}()
+
+/**
+Return OpenAPI description of API
+
+@return void
+*/
+func openApi(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json; charset=utf-8", openApiSpec)
+}
+
+/**
+Return file of viewer of API description, index page is returned for /docs/
+
+@return void
+*/
+func docs(c *gin.Context) {
+	docsFiles.ServeHTTP(c.Writer, c.Request)
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"fmt"
+	"memdb"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// error codes of failures which happen in api itself
+const (
+	errorBadRequest = "bad_request"
+	errorBadGateway = "bad_gateway"
+)
+
+// HTTP status for every database error code
+var errorStatuses = map[memdb.ErrorCode]int{
+	memdb.ErrorNotFound:      http.StatusNotFound,
+	memdb.ErrorConflict:      http.StatusConflict,
+	memdb.ErrorInvalid:       http.StatusUnprocessableEntity,
+	memdb.ErrorQuotaExceeded: http.StatusInsufficientStorage,
+	memdb.ErrorInternal:      http.StatusInternalServerError,
+}
+
+/**
+Send error response, every failed request has the same body
+@params c gin.Context - context of request
+@params status int - HTTP status
+@params code string - machine readable error code
+@params message string - description of error
+
+@return void
+*/
+func respondError(c *gin.Context, status int, code string, message string) {
+	// the first error of request is already sent
+	if c.Writer.Written() {
+		return
+	}
+
+	c.AbortWithStatusJSON(status, gin.H{
+		"status":  status,
+		"error":   code,
+		"message": message,
+	})
+}
+
+// Invalid field of request
+type fieldError struct {
+	Field   string `json:"field"`
+	Value   string `json:"value"`
+	Message string `json:"message"`
+}
+
+/**
+Send bad request response which lists every invalid field
+@params c gin.Context - context of request
+@params fields []fieldError
+
+@return void
+*/
+func respondFieldErrors(c *gin.Context, fields []fieldError) {
+	if c.Writer.Written() {
+		return
+	}
+
+	messages := make([]string, len(fields))
+	for i, field := range fields {
+		messages[i] = fmt.Sprintf("Field %s is invalid: %s", field.Field, field.Message)
+	}
+	c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+		"status":  http.StatusBadRequest,
+		"error":   errorBadRequest,
+		"message": strings.Join(messages, "; "),
+		"fields":  fields,
+	})
+}
+
+/**
+Send error response for failed database command
+@params c gin.Context - context of request
+@params response memdb.CommandResult - result of failed command
+@params message string - description of failed action
+
+@return void
+*/
+func respondCommandError(c *gin.Context, response memdb.CommandResult, message string) {
+	code := response.Code
+	if code == memdb.ErrorNone {
+		code = memdb.ErrorInternal
+	}
+
+	respondError(c, errorStatuses[code], code.String(), fmt.Sprintf("%s: %s", message, response.Error))
+}
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"fmt"
+	"mail_parser"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// limits of caller patterns of verification codes
+const (
+	maxCodePatterns      = 10
+	maxCodePatternLength = 500
+)
+
+/**
+Return links of text and HTML parts of message, message is not marked as seen
+@params email string
+@params message_id string
+@params GET - contains string (optional) - only links which url or text contains it, case insensitive
+
+@return void
+*/
+func messageLinks(c *gin.Context) {
+	parsed, ok := parseMessage(c)
+	if !ok {
+		return
+	}
+
+	contains := strings.ToLower(c.Query("contains"))
+	links := []mail_parser.Link{}
+	for _, link := range parsed.Links() {
+		if strings.Contains(strings.ToLower(link.URL), contains) || strings.Contains(strings.ToLower(link.Text), contains) {
+			links = append(links, link)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": http.StatusOK,
+		"links":  links,
+		"count":  len(links),
+	})
+}
+
+/**
+Return verification codes of message subject and body, message is not marked as seen
+Codes of caller patterns go before codes of built-in patterns
+@params email string
+@params message_id string
+@params GET - pattern []string (optional) - regular expressions, code is the first group or the whole match
+@params GET - builtin bool (optional) - false disables built-in patterns
+
+@return void
+*/
+func messageCodes(c *gin.Context) {
+	patterns := []mail_parser.CodePattern{}
+
+	expressions := c.QueryArray("pattern")
+	if len(expressions) > maxCodePatterns {
+		respondError(c, http.StatusBadRequest, errorBadRequest, fmt.Sprintf("At most %d patterns are allowed", maxCodePatterns))
+		return
+	}
+	for _, expression := range expressions {
+		if len(expression) > maxCodePatternLength {
+			respondError(c, http.StatusBadRequest, errorBadRequest, fmt.Sprintf("Pattern is longer than %d characters", maxCodePatternLength))
+			return
+		}
+		regex, err := regexp.Compile(expression)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, errorBadRequest, "Pattern is invalid: "+err.Error())
+			return
+		}
+		patterns = append(patterns, mail_parser.CodePattern{Name: expression, Regex: regex})
+	}
+	if c.Query("builtin") != "false" {
+		patterns = append(patterns, mail_parser.BuiltinCodePatterns...)
+	}
+
+	parsed, ok := parseMessage(c)
+	if !ok {
+		return
+	}
+
+	codes := parsed.Codes(patterns)
+	c.JSON(http.StatusOK, gin.H{
+		"status": http.StatusOK,
+		"codes":  codes,
+		"count":  len(codes),
+	})
+}
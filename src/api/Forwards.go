@@ -0,0 +1,284 @@
+package api
+
+import (
+	"fmt"
+	"memdb"
+	"models"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// New alias of mailbox
+type aliasPost struct {
+	Alias string `form:"alias" json:"alias" binding:"required"`
+}
+
+// New forward rule of mailbox, condition is optional
+type forwardPost struct {
+	To       string `form:"to" json:"to" binding:"required"`
+	Field    string `form:"field" json:"field"`
+	Contains string `form:"contains" json:"contains"`
+}
+
+/**
+Return list of aliases of mailbox
+@params email string
+
+@return void
+*/
+func aliasList(c *gin.Context) {
+	mailbox, ok := readMailbox(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  http.StatusOK,
+		"message": fmt.Sprintf("Aliases of %s", mailbox.Address),
+		"aliases": mailbox.Aliases,
+		"count":   len(mailbox.Aliases),
+	})
+}
+
+/**
+Add alias address to mailbox, messages to alias are delivered to mailbox
+Alias claims new address of tenant, so only admin key can add it
+@params email string
+@params POST - alias string
+
+@return void
+*/
+func aliasAdd(c *gin.Context) {
+	if !authorizeAddress(c, "") {
+		return
+	}
+
+	var mailbox models.MailBox
+	mailbox.Address = c.Param("email")
+	if checkMailbox(c, &mailbox) != nil {
+		return
+	}
+
+	var post aliasPost
+	// trying to bind post params
+	if c.Bind(&post) != nil {
+		respondError(c, http.StatusBadRequest, errorBadRequest, "You must provide alias")
+		return
+	}
+	alias, ok := validateAddress(c, "alias", post.Alias, false)
+	if !ok {
+		return
+	}
+
+	// get instance of Db
+	instance := memdb.GetInstance().Tenant(tenantName(c)).WithContext(c.Request.Context())
+	// trying to add alias
+	response := instance.InsertAlias(mailbox.Address, alias)
+	if !response.Success {
+		respondCommandError(c, response, "Failed add alias")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"status":  http.StatusCreated,
+		"message": fmt.Sprintf("Alias %v added to %s", response.Value, mailbox.Address),
+		"alias":   response.Value,
+	})
+}
+
+/**
+Remove alias of mailbox
+@params email string
+@params alias string
+
+@return void
+*/
+func aliasDelete(c *gin.Context) {
+	var mailbox models.MailBox
+	mailbox.Address = c.Param("email")
+	if checkMailbox(c, &mailbox) != nil {
+		return
+	}
+
+	alias, ok := validateAddress(c, "alias", c.Param("alias"), false)
+	if !ok {
+		return
+	}
+
+	// get instance of Db
+	instance := memdb.GetInstance().Tenant(tenantName(c)).WithContext(c.Request.Context())
+	// trying to remove alias
+	response := instance.DeleteAlias(mailbox.Address, alias)
+	if !response.Success {
+		respondCommandError(c, response, "Failed remove alias")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  http.StatusOK,
+		"message": fmt.Sprintf("Alias %s removed", alias),
+	})
+}
+
+/**
+Return list of forward rules of mailbox
+@params email string
+
+@return void
+*/
+func forwardList(c *gin.Context) {
+	mailbox, ok := readMailbox(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":   http.StatusOK,
+		"message":  fmt.Sprintf("Forward rules of %s", mailbox.Address),
+		"forwards": mailbox.Forwards,
+		"count":    len(mailbox.Forwards),
+	})
+}
+
+/**
+Add rule which copies every new message of mailbox to another mailbox
+API key has to have access to target mailbox too
+@params email string
+@params POST - to string
+@params POST - field string (optional, one of from, to, subject, body, tag)
+@params POST - contains string (required with field)
+
+@return void
+*/
+func forwardAdd(c *gin.Context) {
+	var mailbox models.MailBox
+	mailbox.Address = c.Param("email")
+	if checkMailbox(c, &mailbox) != nil {
+		return
+	}
+
+	var post forwardPost
+	// trying to bind post params
+	if c.Bind(&post) != nil {
+		respondError(c, http.StatusBadRequest, errorBadRequest, "You must provide target address")
+		return
+	}
+
+	// check if target and condition are valid
+	fields := []fieldError{}
+	to, toError := parseAddressField("to", post.To, false)
+	if toError != nil {
+		fields = append(fields, *toError)
+	}
+	rule := &models.ForwardRule{}
+	if post.Field != "" || post.Contains != "" {
+		if !contains(models.ForwardFields, post.Field) {
+			fields = append(fields, fieldError{Field: "field", Value: post.Field, Message: fmt.Sprintf("must be one of %v", models.ForwardFields)})
+		}
+		if post.Contains == "" {
+			fields = append(fields, fieldError{Field: "contains", Value: post.Contains, Message: "must not be empty"})
+		}
+		rule.Condition = &models.ForwardCondition{Field: post.Field, Contains: post.Contains}
+	}
+	if len(fields) > 0 {
+		respondFieldErrors(c, fields)
+		return
+	}
+	if !authorizeAddress(c, to.String()) {
+		return
+	}
+	rule.To = to.String()
+
+	// get instance of Db
+	instance := memdb.GetInstance().Tenant(tenantName(c)).WithContext(c.Request.Context())
+	// trying to add forward rule
+	response := instance.InsertForward(mailbox.Address, rule)
+	if !response.Success {
+		respondCommandError(c, response, "Failed add forward rule")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"status":  http.StatusCreated,
+		"message": fmt.Sprintf("Messages of %s are forwarded to %s", mailbox.Address, response.Value.(*models.ForwardRule).To),
+		"forward": response.Value,
+	})
+}
+
+/**
+Remove forward rule of mailbox
+@params email string
+@params forward_id string
+
+@return void
+*/
+func forwardDelete(c *gin.Context) {
+	var mailbox models.MailBox
+	mailbox.Address = c.Param("email")
+	id, _ := strconv.ParseInt(c.Param("forward_id"), 10, 0)
+	mailbox.Id = int(id)
+
+	// validate email and forward rule id
+	err := checkEmailAndId(&mailbox, c)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, errorBadRequest, "Forward rule ID is invalid")
+		return
+	}
+
+	// get instance of Db
+	instance := memdb.GetInstance().Tenant(tenantName(c)).WithContext(c.Request.Context())
+	// trying to remove forward rule
+	response := instance.DeleteForward(mailbox.Address, mailbox.Id)
+	if !response.Success {
+		respondCommandError(c, response, "Failed remove forward rule")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  http.StatusOK,
+		"message": fmt.Sprintf("Forward rule %d removed", mailbox.Id),
+	})
+}
+
+/**
+Read mailbox of request - if it is not found - send error response
+@params c gin.Context - context of request
+
+@return *models.MailBox, bool
+*/
+func readMailbox(c *gin.Context) (*models.MailBox, bool) {
+	var post models.MailBox
+	post.Address = c.Param("email")
+	if checkMailbox(c, &post) != nil {
+		return nil, false
+	}
+
+	// get instance of Db
+	instance := memdb.GetInstance().Tenant(tenantName(c)).WithContext(c.Request.Context())
+	response := instance.GetMailBox(post.Address)
+	if !response.Success {
+		respondCommandError(c, response, "Failed get mailbox")
+		return nil, false
+	}
+
+	return response.Value.(*models.MailBox), true
+}
+
+/**
+Check if list contains value
+@params list []string
+@params value string
+
+@return bool
+*/
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -1,20 +1,48 @@
 package api
 
 import (
+	"auth"
 	"errors"
 	"fmt"
+	"logger"
+	"mail_address"
 	"memdb"
+	"metrics"
 	"models"
 	"net/http"
-	"regexp"
+	"os"
+	"relay"
 	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
-// messages slice length to retrieve from DB
-const pageLimit = 10
+// default and maximum messages slice length to retrieve from DB
+const (
+	pageLimit    = 10
+	maxPageLimit = 100
+)
+
+// mark message as seen when it is read, MARK_SEEN_ON_READ=false disables it
+var markSeenOnRead atomic.Bool
+
+func init() {
+	markSeenOnRead.Store(os.Getenv("MARK_SEEN_ON_READ") != "false")
+}
+
+/**
+Change marking of read messages as seen
+@params enabled bool
+
+@return void
+*/
+func SetMarkSeenOnRead(enabled bool) {
+	markSeenOnRead.Store(enabled)
+}
 
 /**
 Creates api url route handler
@@ -23,9 +51,54 @@ Creates api url route handler
 */
 func Handle() *gin.Engine {
 	// create new router instance
-	router := gin.Default()
+	router := gin.New()
+	// client IP is taken from headers only of trusted proxies
+	if err := router.SetTrustedProxies(trustedProxies()); err != nil {
+		logger.Default().Error("Trusted proxies are invalid, client IP is taken from connection", "error", err)
+		router.SetTrustedProxies(nil)
+	}
+	// every request is traced, logged with request id, counted and limited by API key or client IP
+	router.Use(gin.Recovery(), traceRequest, requestLogger, observeRequest, rateLimit)
+
+	// description of API, web UI, metrics and health are available without API key
+	router.GET("/healthz", healthz)
+	router.GET("/readyz", readyz)
+	router.GET("/openapi.json", openApi)
+	router.GET("/docs", func(c *gin.Context) { c.Redirect(http.StatusMovedPermanently, "/docs/") })
+	router.GET("/docs/*path", docs)
+	// web UI calls API routes with key which is entered in UI
+	router.GET("/ui", func(c *gin.Context) { c.Redirect(http.StatusMovedPermanently, "/ui/") })
+	router.GET("/ui/*path", ui)
+	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
+
+	// every route requires API key if authentication is enabled
+	// routes without tenant are served inside default tenant or tenant of API key
+	describeRoutes(router.Group("/", authenticate))
+	describeRoutes(router.Group("/tenants/:tenant", authenticate))
+
+	// tenants are managed only by static admin keys
+	tenants := router.Group("/tenants", authenticateSuperAdmin)
+	tenants.GET("", tenantList)
+	tenants.POST("", tenantCreate)
+	tenants.GET("/:tenant", tenantRead)
+	tenants.DELETE("/:tenant", tenantDelete)
+
+	// log level is changed at runtime only by static admin keys
+	logging := router.Group("/log", authenticateSuperAdmin)
+	logging.GET("/level", logLevelRead)
+	logging.PUT("/level", logLevelUpdate)
+
+	return router
+}
+
+/**
+Describe routes of single tenant
+@params router *gin.RouterGroup
 
-	// describe routes
+@return void
+*/
+func describeRoutes(router *gin.RouterGroup) {
+	router.GET("/mailboxes", mailboxList)
 	router.POST("/mailboxes", mailboxCreate)
 	router.DELETE("/mailboxes/:email", mailboxDelete)
 
@@ -33,9 +106,31 @@ func Handle() *gin.Engine {
 	router.POST("/mailboxes/:email/messages", messageAdd)
 
 	router.GET("/mailboxes/:email/messages/:message_id", messageRead)
+	router.GET("/mailboxes/:email/messages/:message_id/raw", messageRaw)
+	router.GET("/mailboxes/:email/messages/:message_id/content", messageContent)
+	router.GET("/mailboxes/:email/messages/:message_id/html", messageHtml)
+	router.GET("/mailboxes/:email/messages/:message_id/links", messageLinks)
+	router.GET("/mailboxes/:email/messages/:message_id/codes", messageCodes)
+	router.GET("/mailboxes/:email/messages/:message_id/attachments/:attachment_id", messageAttachment)
+	router.PATCH("/mailboxes/:email/messages/:message_id", messageUpdate)
 	router.DELETE("/mailboxes/:email/messages/:message_id", messageRemove)
 
-	return router
+	router.POST("/mailboxes/:email/messages/:message_id/release", messageRelease)
+
+	router.GET("/mailboxes/:email/threads", threadList)
+	router.GET("/mailboxes/:email/threads/:thread_id", threadRead)
+
+	router.GET("/mailboxes/:email/aliases", aliasList)
+	router.POST("/mailboxes/:email/aliases", aliasAdd)
+	router.DELETE("/mailboxes/:email/aliases/:alias", aliasDelete)
+
+	router.GET("/mailboxes/:email/forwards", forwardList)
+	router.POST("/mailboxes/:email/forwards", forwardAdd)
+	router.DELETE("/mailboxes/:email/forwards/:forward_id", forwardDelete)
+
+	router.GET("/keys", keyList)
+	router.POST("/keys", keyCreate)
+	router.DELETE("/keys/:key", keyDelete)
 }
 
 /**
@@ -46,25 +141,79 @@ Create new mailbox
 */
 func mailboxCreate(c *gin.Context) {
 	// get instance of Db
-	instance := memdb.GetInstance()
+	instance := memdb.GetInstance().Tenant(tenantName(c)).WithContext(c.Request.Context())
 
 	// generate new random email
 	mailbox := instance.InsertMailBox()
 	if !mailbox.Success {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"message": fmt.Sprintf("Failed adding email: %s", mailbox.Error),
-		})
+		respondCommandError(c, mailbox, "Failed adding email")
 		return
 	}
 
-	// gets created Mailbox instance and return
+	// gets created Mailbox instance
 	mailboxInstance := mailbox.Value.(*models.MailBox)
+
+	// issue token which grants access only to created mailbox
+	token := auth.IssueKey(tenantName(c), false, mailboxInstance.Address)
+	if !token.Success {
+		respondCommandError(c, token, "Failed issue mailbox token")
+		return
+	}
+
 	c.JSON(http.StatusCreated, gin.H{
 		"message": fmt.Sprintf("Email %v added", mailboxInstance.Address),
 		"mailbox": mailboxInstance.Address,
+		"token":   token.Value.(*models.ApiKey).Key,
 	})
 }
 
+/**
+Return list of mailboxes with statistics
+@params GET - prefix string (optional)
+@params GET - domain string (optional)
+
+@return void
+*/
+func mailboxList(c *gin.Context) {
+	// get index cursor for paging
+	cursor, err := pageCursor(c)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, errorBadRequest, err.Error())
+		return
+	}
+
+	filter := &memdb.MailBoxFilter{Prefix: c.Query("prefix"), Domain: c.Query("domain")}
+
+	// get instance of Db
+	instance := memdb.GetInstance().Tenant(tenantName(c)).WithContext(c.Request.Context())
+	// get list of mailboxes
+	response := instance.GetMailBoxes(cursor, filter)
+	if !response.Success {
+		respondCommandError(c, response, "Failed get mailboxes")
+		return
+	}
+
+	mailboxes := response.Value.([]*models.MailBoxStats)
+
+	requestResult := gin.H{
+		"status":    http.StatusOK,
+		"message":   "Mailboxes",
+		"mailboxes": mailboxes,
+		"count":     response.Rows,
+		"total":     cursor.Total,
+		"hasMore":   cursor.HasMore,
+	}
+
+	// cursor of the next page is the last mailbox on current page
+	lastId := 0
+	if len(mailboxes) > 0 {
+		lastId = mailboxes[len(mailboxes)-1].Id
+	}
+	pageLinks(c, cursor, lastId, requestResult)
+
+	c.JSON(http.StatusOK, requestResult)
+}
+
 /**
 Removes existing mailbox
 @params email string
@@ -82,13 +231,11 @@ func mailboxDelete(c *gin.Context) {
 	}
 
 	// get instance of Db
-	instance := memdb.GetInstance()
+	instance := memdb.GetInstance().Tenant(tenantName(c)).WithContext(c.Request.Context())
 	// trying to remove mailbox
 	status := instance.DeleteMailBox(post.Address)
 	if !status.Success {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"message": fmt.Sprintf("Failed removing email: %s", status.Error),
-		})
+		respondCommandError(c, status, "Failed removing email")
 		return
 	}
 
@@ -100,6 +247,7 @@ func mailboxDelete(c *gin.Context) {
 /**
 Return list of messages from existing mailbox
 @params email string
+@params GET - tag string (optional) - only messages to subaddress with this tag, empty value selects base address
 
 @return void
 */
@@ -114,24 +262,32 @@ func messageList(c *gin.Context) {
 	}
 
 	// get index cursor for paging
-	cursorId, _ := strconv.ParseInt(c.DefaultQuery("maxId", "0"), 10, 64)
+	cursor, err := pageCursor(c)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, errorBadRequest, err.Error())
+		return
+	}
 
-	maxId := int(cursorId)
-	cursor := &memdb.PageCursor{Count: pageLimit}
-	if maxId != 0 {
-		cursor = &memdb.PageCursor{Count: pageLimit, MaxId: &maxId}
+	// get filters by message flags
+	filter := &memdb.MessageFilter{}
+	if unread, err := strconv.ParseBool(c.Query("unread")); err == nil {
+		seen := !unread
+		filter.Seen = &seen
+	}
+	if flagged, err := strconv.ParseBool(c.Query("flagged")); err == nil {
+		filter.Flagged = &flagged
+	}
+	if tag, ok := c.GetQuery("tag"); ok {
+		filter.Tag = &tag
 	}
 
 	// get instance of Db
-	instance := memdb.GetInstance()
+	instance := memdb.GetInstance().Tenant(tenantName(c)).WithContext(c.Request.Context())
 	// get list of messages
-	response := instance.GetMailBoxMessages(post.Address, cursor)
+	response := instance.FilterMailBoxMessages(post.Address, cursor, filter)
 
 	if !response.Success {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"message": fmt.Sprintf("Failed get messages: %s", response.Error),
-			"mailbox": post.Address,
-		})
+		respondCommandError(c, response, "Failed get messages")
 		return
 	}
 
@@ -142,73 +298,104 @@ func messageList(c *gin.Context) {
 		"message":  fmt.Sprintf("Messages from %s", post.Address),
 		"messages": messages,
 		"count":    response.Rows,
+		"unread":   cursor.Unread,
+		"total":    cursor.Total,
+		"hasMore":  cursor.HasMore,
 	}
 
-	// check if count results is equal to paging limits
-	countResults := response.Rows
-	if countResults == pageLimit {
-		cursorId = int64(messages[countResults-1].Id)
-		requestResult["maxId"] = cursorId
+	// cursor of the next page is the last message on current page
+	lastId := 0
+	if len(messages) > 0 {
+		lastId = messages[len(messages)-1].Id
 	}
+	pageLinks(c, cursor, lastId, requestResult)
 
 	c.JSON(http.StatusOK, requestResult)
 }
 
 /**
-Add new message for setted address
+Add new message for setted address, recipient has to be delivered to mailbox of request
+@params email string
 @params POST - from string
 @params POST - to string
 @params POST - subject string
 @params POST - message string
+@params POST - message-id string (optional)
+@params POST - in-reply-to string (optional)
+@params POST - references []string (optional)
 
 @return void
 */
 func messageAdd(c *gin.Context) {
+	var mailbox models.MailBox
+	mailbox.Address = c.Param("email")
+	if checkMailbox(c, &mailbox) != nil {
+		return
+	}
+
 	var message models.Message
 
 	// trying to bind post params to data model, validating them
 	if c.Bind(&message) != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  http.StatusBadRequest,
-			"message": "You must provide all needed fields",
-		})
+		respondError(c, http.StatusBadRequest, errorBadRequest, "You must provide all needed fields")
 		return
 	}
 
-	// check if fields are valid
-	if emailValidator(message.To, c) != nil {
-		return
-	}
-	// reciever address check
-	if emailValidator(message.From, c) != nil {
+	// check if fields are valid, display names are allowed
+	to, toError := parseAddressField("to", message.To, true)
+	from, fromError := parseAddressField("from", message.From, true)
+	if toError != nil || fromError != nil {
+		fields := []fieldError{}
+		for _, field := range []*fieldError{toError, fromError} {
+			if field != nil {
+				fields = append(fields, *field)
+			}
+		}
+		respondFieldErrors(c, fields)
 		return
 	}
 
 	// create new model for insertion
 	insertMessage := &models.Message{
-		To:           message.To,
-		From:         message.From,
+		To:           to.String(),
+		From:         from.Header(),
 		Subject:      message.Subject,
 		Body:         message.Body,
+		MessageID:    message.MessageID,
+		InReplyTo:    message.InReplyTo,
+		References:   message.References,
 		ReceivedDate: time.Now(),
 	}
 
 	// get instance of Db
-	instance := memdb.GetInstance()
+	instance := memdb.GetInstance().Tenant(tenantName(c)).WithContext(c.Request.Context())
+
+	// message is saved only to mailbox of request, directly or through subaddress or alias,
+	// so mailbox token can not write to other mailboxes
+	resolved := instance.ResolveAddress(insertMessage.To)
+	if !resolved.Success {
+		metrics.DeliveriesRejected.WithLabelValues(metrics.ChannelApi, resolved.Code.String()).Inc()
+		respondCommandError(c, resolved, "Failed inserting message")
+		return
+	}
+	if !memdb.SameMailbox(resolved.Value.(string), mailbox.Address) {
+		respondFieldErrors(c, []fieldError{{Field: "to", Value: message.To, Message: fmt.Sprintf("is not delivered to mailbox %s", mailbox.Address)}})
+		return
+	}
+
 	// trying to insert message
 	response := instance.InsertMessage(insertMessage.To, insertMessage)
 
 	if !response.Success {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"message": fmt.Sprintf("Failed inserting message: %s", response.Error),
-			"data":    message,
-		})
+		metrics.DeliveriesRejected.WithLabelValues(metrics.ChannelApi, response.Code.String()).Inc()
+		respondCommandError(c, response, "Failed inserting message")
 		return
 	}
+	metrics.MessagesReceived.WithLabelValues(metrics.ChannelApi).Inc()
 
 	c.JSON(http.StatusOK, gin.H{
 		"status":  http.StatusOK,
-		"message": fmt.Sprintf("Message from %s successfully created", message.To),
+		"message": fmt.Sprintf("Message from %s successfully created", insertMessage.To),
 	})
 
 }
@@ -227,27 +414,31 @@ func messageRead(c *gin.Context) {
 	messageItem.Id = int(id)
 
 	// validate email and message id
-	err := checkEmailAndId(messageItem, c)
+	err := checkEmailAndId(&messageItem, c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  http.StatusBadRequest,
-			"message": err.Error(),
-		})
+		respondError(c, http.StatusBadRequest, errorBadRequest, err.Error())
 		return
 	}
 
 	// get instance of Db
-	instance := memdb.GetInstance()
+	instance := memdb.GetInstance().Tenant(tenantName(c)).WithContext(c.Request.Context())
 	// trying to get a message using email address and message id
 	response := instance.GetMessage(messageItem.Address, messageItem.Id)
 	if !response.Success {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"message": fmt.Sprintf("Failed get message: %s", response.Error),
-			"data":    messageItem,
-		})
+		respondCommandError(c, response, "Failed get message")
 		return
 	}
 
+	// message is read, so mark it as seen
+	if markSeenOnRead.Load() && !response.Value.(*models.Message).Seen {
+		seen := true
+		response = instance.UpdateMessageFlags(messageItem.Address, messageItem.Id, models.MessageFlags{Seen: &seen})
+		if !response.Success {
+			respondCommandError(c, response, "Failed mark message as seen")
+			return
+		}
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"status":  http.StatusOK,
 		"message": "OK",
@@ -255,6 +446,84 @@ func messageRead(c *gin.Context) {
 	})
 }
 
+/**
+Return original RFC 5322 data of message, message is not marked as seen
+@params email string
+@params message_id string
+
+@return void
+*/
+func messageRaw(c *gin.Context) {
+	var messageItem models.MailBox
+	messageItem.Address = c.Param("email")
+	id, _ := strconv.ParseInt(c.Param("message_id"), 10, 0)
+	messageItem.Id = int(id)
+
+	// validate email and message id
+	err := checkEmailAndId(&messageItem, c)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, errorBadRequest, err.Error())
+		return
+	}
+
+	// get instance of Db
+	instance := memdb.GetInstance().Tenant(tenantName(c)).WithContext(c.Request.Context())
+	// trying to get a message using email address and message id
+	response := instance.GetMessage(messageItem.Address, messageItem.Id)
+	if !response.Success {
+		respondCommandError(c, response, "Failed get message")
+		return
+	}
+
+	c.Data(http.StatusOK, "message/rfc822", response.Value.(*models.Message).RawData())
+}
+
+/**
+Change message flags
+@params email string
+@params message_id string
+@params PATCH - seen bool (optional)
+@params PATCH - flagged bool (optional)
+@params PATCH - answered bool (optional)
+
+@return void
+*/
+func messageUpdate(c *gin.Context) {
+	var messageItem models.MailBox
+	messageItem.Address = c.Param("email")
+	messageId, _ := strconv.ParseInt(c.Param("message_id"), 10, 0)
+	messageItem.Id = int(messageId)
+
+	// validate email and message id
+	err := checkEmailAndId(&messageItem, c)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, errorBadRequest, err.Error())
+		return
+	}
+
+	// trying to bind changed flags, invalid value is reported by error response
+	var flags models.MessageFlags
+	if c.ShouldBind(&flags) != nil {
+		respondError(c, http.StatusBadRequest, errorBadRequest, "Flags must be boolean")
+		return
+	}
+
+	// get instance of Db
+	instance := memdb.GetInstance().Tenant(tenantName(c)).WithContext(c.Request.Context())
+	// trying to change flags of message
+	response := instance.UpdateMessageFlags(messageItem.Address, messageItem.Id, flags)
+	if !response.Success {
+		respondCommandError(c, response, "Failed update message")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  http.StatusOK,
+		"message": fmt.Sprintf("Updated index %d", messageItem.Id),
+		"content": response.Value,
+	})
+}
+
 /**
 Remove message from DB
 @params email string
@@ -269,25 +538,19 @@ func messageRemove(c *gin.Context) {
 	messageItem.Id = int(messageId)
 
 	// validate email and message id
-	err := checkEmailAndId(messageItem, c)
+	err := checkEmailAndId(&messageItem, c)
 
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  http.StatusBadRequest,
-			"message": err.Error(),
-		})
+		respondError(c, http.StatusBadRequest, errorBadRequest, err.Error())
 		return
 	}
 
 	// get instance of Db
-	instance := memdb.GetInstance()
+	instance := memdb.GetInstance().Tenant(tenantName(c)).WithContext(c.Request.Context())
 	// trying to remove message by email and message id
 	response := instance.DeleteMessage(messageItem.Address, messageItem.Id)
 	if !response.Success {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"message": fmt.Sprintf("Failed remove message: %s", response.Error),
-			"data":    messageItem,
-		})
+		respondCommandError(c, response, "Failed remove message")
 		return
 	}
 
@@ -297,40 +560,289 @@ func messageRemove(c *gin.Context) {
 	})
 }
 
-// HELPERS
 /**
-Validate email - if not valid - return error and send bad request msg
+Get envelope sender of released message
+From of API and IMAP messages may have display name, which is not allowed in MAIL FROM
+@params from string - sender of stored message
+
+@return string - bare address, empty (null sender) if address is invalid
+*/
+func envelopeSender(from string) string {
+	address, err := mail_address.Parse(from)
+	if err != nil {
+		return ""
+	}
+
+	return address.String()
+}
+
+/**
+Release message to upstream SMTP server
 @params email string
+@params message_id string
+@params POST - to string (optional, recipient of original message by default)
 
-@return Error
+@return void
 */
-func emailValidator(email string, c *gin.Context) error {
-	valid := regexp.MustCompile(`([\d\w]+[\.\w\d]*)\+?([\.\w\d]*)?@([\w\d]+[\.\w\d]*){1,}`)
-	mess := valid.MatchString(email)
-	if !mess {
-		message := fmt.Sprintf("Email %s is invalid", email)
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  http.StatusBadRequest,
-			"message": message,
-		})
-		return errors.New(message)
+func messageRelease(c *gin.Context) {
+	var messageItem models.MailBox
+	messageItem.Address = c.Param("email")
+	messageId, _ := strconv.ParseInt(c.Param("message_id"), 10, 0)
+	messageItem.Id = int(messageId)
+
+	// validate email and message id
+	err := checkEmailAndId(&messageItem, c)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, errorBadRequest, err.Error())
+		return
 	}
 
-	return nil
+	// get instance of Db
+	instance := memdb.GetInstance().Tenant(tenantName(c)).WithContext(c.Request.Context())
+	// trying to get a message which should be released
+	response := instance.GetMessage(messageItem.Address, messageItem.Id)
+	if !response.Success {
+		respondCommandError(c, response, "Failed get message")
+		return
+	}
+	message := response.Value.(*models.Message)
+
+	// recipient check
+	to, ok := validateAddress(c, "to", c.DefaultPostForm("to", message.To), true)
+	if !ok {
+		return
+	}
+
+	// relay stored message and remember the result
+	config := relay.ConfigFromEnv()
+	release := models.Release{To: to, Host: config.Host, Date: time.Now(), Success: true}
+	err = relay.Send(config, envelopeSender(message.From), []string{to}, message.RawData())
+	if err != nil {
+		release.Success = false
+		release.Error = err.Error()
+	}
+	instance.AddMessageRelease(messageItem.Address, messageItem.Id, release)
+
+	if !release.Success {
+		respondError(c, http.StatusBadGateway, errorBadGateway, fmt.Sprintf("Failed release message: %s", release.Error))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  http.StatusOK,
+		"message": fmt.Sprintf("Message %d released to %s", messageItem.Id, to),
+		"release": release,
+	})
+}
+
+/**
+Return list of conversations from existing mailbox
+@params email string
+
+@return void
+*/
+func threadList(c *gin.Context) {
+	var post models.MailBox
+	post.Address = c.Param("email")
+
+	// validate post params
+	err := checkMailbox(c, &post)
+	if err != nil {
+		return
+	}
+
+	// get instance of Db
+	instance := memdb.GetInstance().Tenant(tenantName(c)).WithContext(c.Request.Context())
+	// get list of threads
+	response := instance.GetMailBoxThreads(post.Address)
+	if !response.Success {
+		respondCommandError(c, response, "Failed get threads")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  http.StatusOK,
+		"message": fmt.Sprintf("Threads from %s", post.Address),
+		"threads": response.Value,
+		"count":   response.Rows,
+	})
+}
+
+/**
+Read conversation with all its messages
+@params email string
+@params thread_id string
+
+@return void
+*/
+func threadRead(c *gin.Context) {
+	var threadItem models.MailBox
+	threadItem.Address = c.Param("email")
+	id, _ := strconv.ParseInt(c.Param("thread_id"), 10, 0)
+	threadItem.Id = int(id)
+
+	// validate email and thread id
+	err := checkEmailAndId(&threadItem, c)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, errorBadRequest, err.Error())
+		return
+	}
+
+	// get instance of Db
+	instance := memdb.GetInstance().Tenant(tenantName(c)).WithContext(c.Request.Context())
+	// trying to get a thread using email address and thread id
+	response := instance.GetThread(threadItem.Address, threadItem.Id)
+	if !response.Success {
+		respondCommandError(c, response, "Failed get thread")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  http.StatusOK,
+		"message": "OK",
+		"content": response.Value,
+	})
+}
+
+// HELPERS
+/**
+Parse address of request field, address is RFC 5321 mailbox with optional display name
+@params field string - name of field for error
+@params value string
+@params withName bool - display name is allowed, e.g. "Name" <local@domain>
+
+@return *mail_address.Address, *fieldError
+*/
+func parseAddressField(field string, value string, withName bool) (*mail_address.Address, *fieldError) {
+	parse := mail_address.ParseAddress
+	if withName {
+		parse = mail_address.Parse
+	}
+
+	address, err := parse(value)
+	if err != nil {
+		return nil, &fieldError{Field: field, Value: value, Message: err.Error()}
+	}
+
+	return address, nil
 }
 
 /**
-Check message address and id
-@params messageItem models.Mailbox
+Validate address of request field - if not valid - send bad request response
+@params c gin.Context - context of request
+@params field string - name of field for error
+@params value string
+@params withName bool - display name is allowed
+
+@return string, bool - canonical address without display name, false if it is invalid
+*/
+func validateAddress(c *gin.Context, field string, value string, withName bool) (string, bool) {
+	address, invalid := parseAddressField(field, value, withName)
+	if invalid != nil {
+		respondFieldErrors(c, []fieldError{*invalid})
+		return "", false
+	}
+
+	return address.String(), true
+}
+
+/**
+Set Link header (RFC 5988) with the first and the next pages
+and add cursor of the next page to response
+Only cursor of the page order is moved, the opposite bound of requested range is kept in links
+@params c gin.Context - context of request
+@params cursor memdb.PageCursor - cursor of current page
+@params lastId int - id of the last item on current page
+@params requestResult gin.H - response
+
+@return void
+*/
+func pageLinks(c *gin.Context, cursor *memdb.PageCursor, lastId int, requestResult gin.H) {
+	// newest first pages move maxId down to sinceId, oldest first pages move sinceId up to maxId
+	cursorName := "maxId"
+	if cursor.Ascending {
+		cursorName = "sinceId"
+	}
+
+	query := c.Request.URL.Query()
+	query.Del(cursorName)
+	query.Set("limit", strconv.Itoa(cursor.Count))
+	links := []string{fmt.Sprintf("<%s?%s>; rel=\"first\"", c.Request.URL.Path, query.Encode())}
+
+	if cursor.HasMore {
+		requestResult[cursorName] = lastId
+
+		query.Set(cursorName, strconv.Itoa(lastId))
+		links = append(links, fmt.Sprintf("<%s?%s>; rel=\"next\"", c.Request.URL.Path, query.Encode()))
+	}
+
+	c.Header("Link", strings.Join(links, ", "))
+}
+
+/**
+Read cursor pagination params from query
+@params GET - limit int (optional, 10 by default, 100 at most)
+@params GET - maxId int (optional)
+@params GET - sinceId int (optional)
+@params GET - order string (optional, "desc" - newest first by default, "asc" - oldest first)
+
+@return *memdb.PageCursor, Error
+*/
+func pageCursor(c *gin.Context) (*memdb.PageCursor, error) {
+	cursor := &memdb.PageCursor{Count: pageLimit}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(pageLimit)))
+	if err != nil || limit < 1 {
+		return nil, errors.New("Limit must be positive number")
+	}
+	if limit > maxPageLimit {
+		limit = maxPageLimit
+	}
+	cursor.Count = limit
+
+	if c.Query("maxId") != "" {
+		maxId, err := strconv.Atoi(c.Query("maxId"))
+		if err != nil {
+			return nil, errors.New("maxId must be a number")
+		}
+		// zero is kept as "no cursor" for old clients
+		if maxId != 0 {
+			cursor.MaxId = &maxId
+		}
+	}
+
+	if c.Query("sinceId") != "" {
+		sinceId, err := strconv.Atoi(c.Query("sinceId"))
+		if err != nil {
+			return nil, errors.New("sinceId must be a number")
+		}
+		cursor.SinceId = &sinceId
+	}
+
+	switch c.DefaultQuery("order", "desc") {
+	case "desc":
+		cursor.Ascending = false
+	case "asc":
+		cursor.Ascending = true
+	default:
+		return nil, errors.New("Order must be asc or desc")
+	}
+
+	return cursor, nil
+}
+
+/**
+Check message address and id, address is replaced with its canonical form
+@params messageItem *models.Mailbox
 
 @return Error
 */
-func checkEmailAndId(messageItem models.MailBox, c *gin.Context) error {
+func checkEmailAndId(messageItem *models.MailBox, c *gin.Context) error {
 	if messageItem.Id == 0 {
 		return errors.New("Message ID is invalid")
 	}
 	// check if email is valid
-	err := checkMailbox(c, &messageItem)
+	err := checkMailbox(c, messageItem)
 	if err != nil {
 		return err
 	}
@@ -340,6 +852,7 @@ func checkEmailAndId(messageItem models.MailBox, c *gin.Context) error {
 
 /**
 Checks if email is present in request and if it valid
+Address is taken only from path, which is authorized, query parameters can not replace it
 
 @params c gin.Context - context of request
 @params post models.Mailbox - post interface
@@ -347,19 +860,15 @@ Checks if email is present in request and if it valid
 @return Error
 */
 func checkMailbox(c *gin.Context, post *models.MailBox) error {
-	// trying to bind post data to data model
-	err := c.Bind(post)
-	if err == nil {
-		// validate email by regular expression
-		err = emailValidator(post.Address, c)
-		if err != nil {
-			return err
-		}
-	} else {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"message": fmt.Sprintf("Email is empty"),
-		})
-		return err
+	if post.Address == "" {
+		respondError(c, http.StatusBadRequest, errorBadRequest, "Email is empty")
+		return errors.New("Email is empty")
+	}
+	// validate email and use its canonical form
+	address, ok := validateAddress(c, "email", post.Address, false)
+	if !ok {
+		return errors.New("Email is invalid")
 	}
+	post.Address = address
 	return nil
 }
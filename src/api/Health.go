@@ -0,0 +1,88 @@
+package api
+
+import (
+	"health"
+	"memdb"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// time in which database engine has to handle command
+const engineTimeout = time.Second
+
+/**
+Report if service is alive: no component failed and database engine handles commands
+Components which are still starting don't fail liveness
+
+@return void
+*/
+func healthz(c *gin.Context) {
+	components := checkComponents()
+
+	healthy := true
+	for _, component := range components {
+		if component.Status == health.StatusFailed {
+			healthy = false
+		}
+	}
+
+	respondHealth(c, healthy, components)
+}
+
+/**
+Report if service is ready to receive mail: every component works
+
+@return void
+*/
+func readyz(c *gin.Context) {
+	components := checkComponents()
+
+	ready := true
+	for _, component := range components {
+		if component.Status != health.StatusOk {
+			ready = false
+		}
+	}
+
+	respondHealth(c, ready, components)
+}
+
+/**
+Get reported state of components and check database engine
+
+@return map[string]health.Component
+*/
+func checkComponents() map[string]health.Component {
+	components := health.Components()
+
+	engine := health.Component{Status: health.StatusOk, Message: "In-memory storage, no persistent backend", Updated: time.Now()}
+	if err := memdb.GetInstance().Ping(engineTimeout); err != nil {
+		engine.Status = health.StatusFailed
+		engine.Message = err.Error()
+	}
+	components["memdb"] = engine
+
+	return components
+}
+
+/**
+Send state of components, 503 if service is not healthy
+@params c gin.Context - context of request
+@params healthy bool
+@params components map[string]health.Component
+
+@return void
+*/
+func respondHealth(c *gin.Context, healthy bool, components map[string]health.Component) {
+	status, code := "ok", http.StatusOK
+	if !healthy {
+		status, code = "unavailable", http.StatusServiceUnavailable
+	}
+
+	c.JSON(code, gin.H{
+		"status":     status,
+		"components": components,
+	})
+}
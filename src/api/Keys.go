@@ -0,0 +1,95 @@
+package api
+
+import (
+	"auth"
+	"fmt"
+	"memdb"
+	"models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+/**
+Return list of API keys
+
+@return void
+*/
+func keyList(c *gin.Context) {
+	// get instance of Db
+	instance := memdb.GetInstance().Tenant(tenantName(c)).WithContext(c.Request.Context())
+	// get list of keys
+	response := instance.GetApiKeys()
+	if !response.Success {
+		respondCommandError(c, response, "Failed get keys")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": http.StatusOK,
+		"keys":   response.Value,
+		"count":  response.Rows,
+	})
+}
+
+/**
+Create new API key
+@params POST - admin bool
+@params POST - mailbox string (required for not admin key)
+
+@return void
+*/
+func keyCreate(c *gin.Context) {
+	var post models.ApiKey
+
+	// trying to bind post params to data model
+	if c.Bind(&post) != nil {
+		respondError(c, http.StatusBadRequest, errorBadRequest, "Admin must be boolean")
+		return
+	}
+
+	// key without admin rights is bound to mailbox
+	if !post.Admin {
+		mailbox, ok := validateAddress(c, "mailbox", post.Mailbox, false)
+		if !ok {
+			return
+		}
+		post.Mailbox = mailbox
+	}
+
+	response := auth.IssueKey(tenantName(c), post.Admin, post.Mailbox)
+	if !response.Success {
+		respondCommandError(c, response, "Failed create key")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"status":  http.StatusCreated,
+		"message": "Key created",
+		"key":     response.Value,
+	})
+}
+
+/**
+Remove API key
+@params key string
+
+@return void
+*/
+func keyDelete(c *gin.Context) {
+	key := c.Param("key")
+
+	// get instance of Db
+	instance := memdb.GetInstance().Tenant(tenantName(c)).WithContext(c.Request.Context())
+	// trying to remove key
+	response := instance.DeleteApiKey(key)
+	if !response.Success {
+		respondCommandError(c, response, "Failed remove key")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  http.StatusOK,
+		"message": fmt.Sprintf("Key %s removed", key),
+	})
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"logger"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// header which carries request id from client and back to it
+const requestIdHeader = "X-Request-ID"
+
+/**
+Log request as JSON line, request id is taken from header or generated
+and is available to every handler through request context
+
+@params c gin.Context - context of request
+
+@return void
+*/
+func requestLogger(c *gin.Context) {
+	started := time.Now()
+
+	id := c.GetHeader(requestIdHeader)
+	if id == "" || len(id) > 128 {
+		id = logger.NewId()
+	}
+	c.Header(requestIdHeader, id)
+	c.Request = c.Request.WithContext(logger.WithRequestId(c.Request.Context(), id))
+
+	c.Next()
+
+	log := logger.From(c.Request.Context())
+	attrs := []any{
+		"method", c.Request.Method,
+		"path", c.Request.URL.Path,
+		"route", c.FullPath(),
+		"status", c.Writer.Status(),
+		"duration", time.Since(started),
+		"client_ip", c.ClientIP(),
+	}
+
+	if c.Writer.Status() >= http.StatusInternalServerError {
+		log.Error("Request handled", attrs...)
+		return
+	}
+	log.Info("Request handled", attrs...)
+}
+
+/**
+Return current log level
+
+@return void
+*/
+func logLevelRead(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status": http.StatusOK,
+		"level":  logger.Level(),
+	})
+}
+
+/**
+Change log level without restart
+@params PUT - level string - debug, info, warn or error
+
+@return void
+*/
+func logLevelUpdate(c *gin.Context) {
+	level := c.PostForm("level")
+	if err := logger.SetLevel(level); level == "" || err != nil {
+		respondError(c, http.StatusBadRequest, errorBadRequest, "You must provide level: debug, info, warn or error")
+		return
+	}
+
+	logger.From(c.Request.Context()).Warn("Log level changed", "level", logger.Level())
+	c.JSON(http.StatusOK, gin.H{
+		"status": http.StatusOK,
+		"level":  logger.Level(),
+	})
+}
@@ -0,0 +1,30 @@
+package api
+
+import (
+	"metrics"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+/**
+Count request and measure its time by route
+
+@params c gin.Context - context of request
+
+@return void
+*/
+func observeRequest(c *gin.Context) {
+	started := time.Now()
+	c.Next()
+
+	// requests to unknown routes are counted together
+	route := c.FullPath()
+	if route == "" {
+		route = "unmatched"
+	}
+
+	metrics.HttpRequests.WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).Inc()
+	metrics.HttpDuration.WithLabelValues(c.Request.Method, route).Observe(time.Since(started).Seconds())
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"math"
+	"net/http"
+	"os"
+	"ratelimit"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+)
+
+// error code of rejected request
+const errorTooManyRequests = "too_many_requests"
+
+// limiter of API requests, RATE_LIMIT_API requests per minute for every API key or client IP
+// it is replaced at runtime while requests are handled
+var requestLimiter atomic.Pointer[ratelimit.Limiter]
+
+// health probes and metrics are not limited, so monitoring works while clients exceed limits
+var unlimitedRoutes = map[string]bool{"/healthz": true, "/readyz": true, "/metrics": true}
+
+func init() {
+	requestLimiter.Store(ratelimit.FromEnv("RATE_LIMIT_API", 600))
+}
+
+/**
+Change limit of API requests
+@params perMinute int - count of requests per minute, zero disables limit
+@params burst int - count of requests allowed at once
+
+@return void
+*/
+func SetRateLimit(perMinute int, burst int) {
+	requestLimiter.Store(ratelimit.NewLimiter(perMinute, burst))
+}
+
+/**
+Limit requests by API key, or by client IP if request has no key or key is unknown,
+so random keys can not bypass limit of client
+Limits are sent in X-RateLimit-Limit, X-RateLimit-Remaining and X-RateLimit-Reset headers
+
+@params c gin.Context - context of request
+
+@return void
+*/
+func rateLimit(c *gin.Context) {
+	limiter := requestLimiter.Load()
+	if !limiter.Enabled() || unlimitedRoutes[c.FullPath()] {
+		return
+	}
+
+	bucket := "ip:" + c.ClientIP()
+	if apiKey := requestApiKey(c); apiKey != nil {
+		bucket = "key:" + apiKey.Key
+	}
+
+	result := limiter.Allow(bucket)
+	c.Header("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+	c.Header("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	c.Header("X-RateLimit-Reset", strconv.Itoa(int(math.Ceil(result.Reset.Seconds()))))
+
+	if !result.Allowed {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(result.RetryAfter.Seconds()))))
+		respondError(c, http.StatusTooManyRequests, errorTooManyRequests, "Rate limit is exceeded, try again later")
+		return
+	}
+}
+
+/**
+Get proxies which are trusted to send client IP in X-Forwarded-For and X-Real-IP headers
+TRUSTED_PROXIES contains comma separated IPs or CIDRs, by default client IP is taken only from connection,
+so headers of client can not bypass limit of its IP
+
+@return []string
+*/
+func trustedProxies() []string {
+	proxies := []string{}
+	for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return proxies
+}
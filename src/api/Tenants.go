@@ -0,0 +1,113 @@
+package api
+
+import (
+	"fmt"
+	"memdb"
+	"models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+/**
+Return list of tenants
+
+@return void
+*/
+func tenantList(c *gin.Context) {
+	// get instance of Db
+	instance := memdb.GetInstance().WithContext(c.Request.Context())
+	// get list of tenants
+	response := instance.GetTenants()
+	if !response.Success {
+		respondCommandError(c, response, "Failed get tenants")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  http.StatusOK,
+		"tenants": response.Value,
+		"count":   response.Rows,
+	})
+}
+
+/**
+Create new tenant
+@params POST - name string
+@params POST - max_mailboxes int (optional)
+@params POST - max_messages int (optional)
+@params POST - max_size int (optional, bytes)
+
+@return void
+*/
+func tenantCreate(c *gin.Context) {
+	var post models.Tenant
+
+	// trying to bind post params to data model, validating them
+	if c.Bind(&post) != nil {
+		respondError(c, http.StatusBadRequest, errorBadRequest, "You must provide tenant name and numeric quotas")
+		return
+	}
+
+	// get instance of Db
+	instance := memdb.GetInstance().WithContext(c.Request.Context())
+	// trying to create tenant
+	response := instance.InsertTenant(&post)
+	if !response.Success {
+		respondCommandError(c, response, "Failed create tenant")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"status":  http.StatusCreated,
+		"message": fmt.Sprintf("Tenant %s created", post.Name),
+		"tenant":  response.Value,
+	})
+}
+
+/**
+Read tenant
+@params tenant string
+
+@return void
+*/
+func tenantRead(c *gin.Context) {
+	// get instance of Db
+	instance := memdb.GetInstance().WithContext(c.Request.Context())
+	// trying to get tenant
+	response := instance.GetTenant(c.Param("tenant"))
+	if !response.Success {
+		respondCommandError(c, response, "Failed get tenant")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  http.StatusOK,
+		"message": "OK",
+		"content": response.Value,
+	})
+}
+
+/**
+Remove tenant with all its mailboxes and keys
+@params tenant string
+
+@return void
+*/
+func tenantDelete(c *gin.Context) {
+	name := c.Param("tenant")
+
+	// get instance of Db
+	instance := memdb.GetInstance().WithContext(c.Request.Context())
+	// trying to remove tenant
+	response := instance.DeleteTenant(name)
+	if !response.Success {
+		respondCommandError(c, response, "Failed remove tenant")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  http.StatusOK,
+		"message": fmt.Sprintf("Tenant %s removed", name),
+	})
+}
@@ -0,0 +1,48 @@
+package api
+
+import (
+	"logger"
+	"net/http"
+	"tracing"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+/**
+Start span of request, trace of client is continued from traceparent header
+Database commands of handlers are child spans of this one
+
+@params c gin.Context - context of request
+
+@return void
+*/
+func traceRequest(c *gin.Context) {
+	// requests to unknown routes have the same span name
+	route := c.FullPath()
+	if route == "" {
+		route = "unmatched"
+	}
+
+	ctx := tracing.Extract(c.Request.Context(), c.Request.Header)
+	ctx, span := tracing.Start(ctx, c.Request.Method+" "+route, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(
+		semconv.HTTPRequestMethodKey.String(c.Request.Method),
+		semconv.HTTPRoute(route),
+		semconv.URLPath(c.Request.URL.Path),
+		semconv.ClientAddress(c.ClientIP())))
+	defer span.End()
+
+	c.Request = c.Request.WithContext(ctx)
+	c.Next()
+
+	status := c.Writer.Status()
+	span.SetAttributes(
+		semconv.HTTPResponseStatusCode(status),
+		attribute.String(logger.RequestIdKey, logger.RequestId(c.Request.Context())))
+	if status >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, http.StatusText(status))
+	}
+}
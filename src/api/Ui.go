@@ -0,0 +1,32 @@
+package api
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Static files of web UI
+//go:embed ui
+var uiAssets embed.FS
+
+// Server of web UI files, paths are relative to /ui
+var uiFiles = func() http.Handler {
+	files, err := fs.Sub(uiAssets, "ui")
+	if err != nil {
+		panic(err)
+	}
+
+	return http.StripPrefix("/ui", http.FileServer(http.FS(files)))
+}()
+
+/**
+Return file of web UI, index page is returned for /ui/
+
+@return void
+*/
+func ui(c *gin.Context) {
+	uiFiles.ServeHTTP(c.Writer, c.Request)
+}
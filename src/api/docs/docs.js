@@ -0,0 +1,282 @@
+// Viewer of OpenAPI description, operations are grouped by tags and can be sent with entered API key
+(function () {
+	"use strict";
+
+	var state = {
+		key: localStorage.getItem("key") || "",
+		spec: null
+	};
+
+	var $ = function (selector) { return document.querySelector(selector); };
+
+	function showError(message) {
+		var footer = $("#error");
+		footer.hidden = !message;
+		footer.textContent = message || "";
+	}
+
+	function element(tag, className, text) {
+		var node = document.createElement(tag);
+		if (className) {
+			node.className = className;
+		}
+		if (text !== undefined) {
+			node.textContent = text;
+		}
+		return node;
+	}
+
+	// Follow local $ref of spec, other values are returned as is
+	function resolve(value) {
+		while (value && value.$ref) {
+			value = value.$ref.replace(/^#\//, "").split("/").reduce(function (item, name) {
+				return item ? item[name] : undefined;
+			}, state.spec);
+		}
+		return value || {};
+	}
+
+	// Short description of schema type, referenced schemas are shown by name
+	function schemaType(schema) {
+		if (!schema) {
+			return "";
+		}
+		if (schema.$ref) {
+			return schema.$ref.split("/").pop();
+		}
+		if (schema.type === "array") {
+			return schemaType(schema.items) + "[]";
+		}
+		var type = schema.type || "object";
+		if (schema.format) {
+			type += " (" + schema.format + ")";
+		}
+		if (schema.enum) {
+			type += " " + schema.enum.join(" | ");
+		}
+		return type;
+	}
+
+	// Table of object properties, required ones are marked
+	function propertiesTable(schema) {
+		schema = resolve(schema);
+		var table = element("table");
+		var required = schema.required || [];
+		Object.keys(schema.properties || {}).forEach(function (name) {
+			var property = schema.properties[name];
+			var row = table.insertRow();
+			row.insertCell().appendChild(element("span", "schema" + (required.indexOf(name) >= 0 ? " required" : ""), name));
+			row.insertCell().appendChild(element("span", "schema", schemaType(property)));
+			row.insertCell().textContent = resolve(property).description || "";
+		});
+		return table;
+	}
+
+	// Table of parameters with inputs for sending request
+	function parametersTable(parameters, inputs) {
+		var table = element("table");
+		var header = table.insertRow();
+		["Name", "In", "Type", "Description", "Value"].forEach(function (title) {
+			header.appendChild(element("th", null, title));
+		});
+
+		parameters.forEach(function (parameter) {
+			var row = table.insertRow();
+			row.insertCell().appendChild(element("span", "schema" + (parameter.required ? " required" : ""), parameter.name));
+			row.insertCell().textContent = parameter.in;
+			row.insertCell().appendChild(element("span", "schema", schemaType(parameter.schema)));
+			row.insertCell().textContent = parameter.description || "";
+
+			var input = element("input");
+			var schema = parameter.schema || {};
+			if (schema.default !== undefined) {
+				input.value = schema.default;
+			}
+			row.insertCell().appendChild(input);
+			inputs.push({parameter: parameter, input: input});
+		});
+		return table;
+	}
+
+	// Build URL of operation from parameter inputs
+	function requestUrl(server, path, inputs) {
+		var query = new URLSearchParams();
+		var url = (server === "/" ? "" : server) + path;
+		inputs.forEach(function (item) {
+			var value = item.input.value;
+			var parameter = item.parameter;
+			if (parameter.in === "path") {
+				url = url.replace("{" + parameter.name + "}", encodeURIComponent(value));
+			} else if (parameter.in === "query" && value !== "") {
+				query.append(parameter.name, value);
+			}
+		});
+		var search = query.toString();
+		return search ? url + "?" + search : url;
+	}
+
+	// Send request of operation and show its response
+	function send(method, url, body, output) {
+		var headers = {};
+		if (state.key) {
+			headers["X-API-Key"] = state.key;
+		}
+		var options = {method: method.toUpperCase(), headers: headers};
+		if (body) {
+			headers["Content-Type"] = "application/x-www-form-urlencoded";
+			options.body = body;
+		}
+
+		output.hidden = false;
+		output.textContent = options.method + " " + url;
+		fetch(url, options).then(function (response) {
+			return response.text().then(function (text) {
+				try {
+					text = JSON.stringify(JSON.parse(text), null, 2);
+				} catch (error) {
+					// response is not JSON, it is shown as is
+				}
+				output.textContent = options.method + " " + url + "\n" + response.status + " " + response.statusText + "\n\n" + text;
+			});
+		}).catch(function (error) {
+			showError(error.message);
+		});
+	}
+
+	function renderOperation(path, method, operation, pathItem) {
+		var details = element("details", "operation");
+		var summary = element("summary");
+		summary.appendChild(element("span", "method " + method, method));
+		summary.appendChild(element("span", "path", path));
+		summary.appendChild(element("span", "summary", operation.summary || ""));
+		details.appendChild(summary);
+
+		var body = element("div", "body");
+		if (operation.description) {
+			body.appendChild(element("p", null, operation.description));
+		}
+
+		// routes with own servers are not available inside tenant
+		var servers = (pathItem.servers || state.spec.servers || [{url: "/"}]).map(function (server) { return server.url; });
+		var server = element("select");
+		servers.forEach(function (url) {
+			server.appendChild(element("option", null, url));
+		});
+		var inputs = [];
+		var parameters = (pathItem.parameters || []).concat(operation.parameters || []).map(resolve);
+		if (servers.indexOf("/tenants/{tenant}") >= 0) {
+			parameters.push({name: "tenant", in: "path", description: "Used with /tenants/{tenant} server", schema: {type: "string", default: "default"}});
+		}
+		if (parameters.length) {
+			body.appendChild(element("h3", null, "Parameters"));
+			body.appendChild(parametersTable(parameters, inputs));
+		}
+
+		var form = null;
+		if (operation.requestBody) {
+			var content = resolve(operation.requestBody).content || {};
+			var schema = (content["application/x-www-form-urlencoded"] || content["application/json"] || {}).schema;
+			body.appendChild(element("h3", null, "Request body " + schemaType(schema)));
+			body.appendChild(propertiesTable(schema));
+			form = element("textarea");
+			form.rows = 2;
+			form.cols = 80;
+			form.placeholder = "name=value&other=value";
+			body.appendChild(form);
+		}
+
+		body.appendChild(element("h3", null, "Responses"));
+		var responses = element("table");
+		Object.keys(operation.responses || {}).forEach(function (code) {
+			var response = resolve(operation.responses[code]);
+			var row = responses.insertRow();
+			row.insertCell().textContent = code;
+			row.insertCell().textContent = response.description || "";
+			var types = Object.keys(response.content || {}).map(function (type) {
+				return type + " " + schemaType(response.content[type].schema);
+			});
+			row.insertCell().appendChild(element("span", "schema", types.join(", ")));
+		});
+		body.appendChild(responses);
+
+		var output = element("pre", "response");
+		output.hidden = true;
+		var button = element("button", null, "Send");
+		button.addEventListener("click", function () {
+			var serverUrl = server.value;
+			var tenant = inputs.filter(function (item) { return item.parameter.name === "tenant"; })[0];
+			if (tenant) {
+				serverUrl = serverUrl.replace("{tenant}", encodeURIComponent(tenant.input.value));
+			}
+			send(method, requestUrl(serverUrl, path, inputs), form && form.value, output);
+		});
+		body.appendChild(server);
+		body.appendChild(button);
+		body.appendChild(output);
+
+		details.appendChild(body);
+		return details;
+	}
+
+	function render(spec) {
+		state.spec = spec;
+		document.title = spec.info.title;
+		$("#version").textContent = spec.info.version;
+		$("#description").textContent = spec.info.description || "";
+
+		// operations are grouped by their first tag in order of spec tags
+		var groups = {};
+		(spec.tags || []).forEach(function (tag) {
+			groups[tag.name] = [];
+		});
+		Object.keys(spec.paths).forEach(function (path) {
+			var pathItem = spec.paths[path];
+			["get", "post", "put", "patch", "delete"].forEach(function (method) {
+				var operation = pathItem[method];
+				if (!operation) {
+					return;
+				}
+				var tag = (operation.tags || ["Other"])[0];
+				(groups[tag] = groups[tag] || []).push(renderOperation(path, method, operation, pathItem));
+			});
+		});
+
+		var operations = $("#operations");
+		Object.keys(groups).forEach(function (tag) {
+			if (!groups[tag].length) {
+				return;
+			}
+			operations.appendChild(element("h2", null, tag));
+			groups[tag].forEach(function (node) {
+				operations.appendChild(node);
+			});
+		});
+
+		var schemas = $("#schemas");
+		var components = (spec.components || {}).schemas || {};
+		Object.keys(components).forEach(function (name) {
+			var details = element("details", "operation");
+			var summary = element("summary");
+			summary.appendChild(element("span", "path", name));
+			summary.appendChild(element("span", "summary", components[name].description || ""));
+			details.appendChild(summary);
+			var body = element("div", "body");
+			body.appendChild(propertiesTable(components[name]));
+			details.appendChild(body);
+			schemas.appendChild(details);
+		});
+	}
+
+	$("#settings").key.value = state.key;
+	$("#settings").addEventListener("submit", function (event) {
+		event.preventDefault();
+		state.key = this.key.value.trim();
+		localStorage.setItem("key", state.key);
+	});
+
+	fetch("/openapi.json").then(function (response) {
+		return response.json();
+	}).then(render).catch(function (error) {
+		showError("API description can not be loaded: " + error.message);
+	});
+})();
@@ -0,0 +1,278 @@
+// Web UI over REST API, mailboxes and messages are polled for live updates
+(function () {
+	"use strict";
+
+	var pollInterval = 3000;
+
+	var state = {
+		tenant: localStorage.getItem("tenant") || "",
+		key: localStorage.getItem("key") || "",
+		mailbox: null,
+		message: null,
+		// ids of messages shown in list, new ones are highlighted
+		known: null
+	};
+
+	var $ = function (selector) { return document.querySelector(selector); };
+
+	// Path of API route inside chosen tenant
+	function path(route) {
+		return (state.tenant ? "/tenants/" + encodeURIComponent(state.tenant) : "") + route;
+	}
+
+	// Request API, JSON responses are decoded, errors are shown in footer
+	function request(method, route, raw) {
+		var headers = {};
+		if (state.key) {
+			headers["X-API-Key"] = state.key;
+		}
+
+		return fetch(path(route), {method: method, headers: headers}).then(function (response) {
+			if (!response.ok) {
+				return response.json().catch(function () { return {}; }).then(function (body) {
+					throw new Error(body.message || response.status + " " + response.statusText);
+				});
+			}
+			showError(null);
+			return raw ? response : response.json();
+		}).catch(function (error) {
+			showError(error.message);
+			throw error;
+		});
+	}
+
+	function showError(message) {
+		var footer = $("#error");
+		footer.hidden = !message;
+		footer.textContent = message || "";
+	}
+
+	function element(tag, className, text) {
+		var node = document.createElement(tag);
+		if (className) {
+			node.className = className;
+		}
+		if (text !== undefined) {
+			node.textContent = text;
+		}
+		return node;
+	}
+
+	function mailboxRoute(address) {
+		return "/mailboxes/" + encodeURIComponent(address);
+	}
+
+	function messageRoute(id) {
+		return mailboxRoute(state.mailbox) + "/messages/" + id;
+	}
+
+	function formatDate(value) {
+		return value ? new Date(value).toLocaleString() : "";
+	}
+
+	function formatSize(bytes) {
+		if (bytes < 1024) {
+			return bytes + " B";
+		}
+		return bytes < 1048576 ? Math.round(bytes / 1024) + " KB" : (bytes / 1048576).toFixed(1) + " MB";
+	}
+
+	// MAILBOXES
+
+	function loadMailboxes() {
+		return request("GET", "/mailboxes?limit=100").then(function (body) {
+			var list = $("#mailboxes ul");
+			list.textContent = "";
+
+			(body.mailboxes || []).forEach(function (mailbox) {
+				var item = element("li", mailbox.unread ? "unread" : "", mailbox.email);
+				item.insertBefore(element("span", "count", mailbox.unread + "/" + mailbox.messages), item.firstChild);
+				item.appendChild(element("span", "meta", formatSize(mailbox.size) + (mailbox.last_received ? ", " + formatDate(mailbox.last_received) : "")));
+				if (mailbox.email === state.mailbox) {
+					item.classList.add("selected");
+				}
+				item.onclick = function () { selectMailbox(mailbox.email); };
+				list.appendChild(item);
+			});
+		});
+	}
+
+	function selectMailbox(address) {
+		state.mailbox = address;
+		state.known = null;
+		selectMessage(null);
+		$("#mailbox-delete").hidden = !address;
+		loadMailboxes();
+		loadMessages();
+	}
+
+	$("#mailbox-create").onclick = function () {
+		request("POST", "/mailboxes").then(loadMailboxes);
+	};
+
+	$("#mailbox-delete").onclick = function () {
+		if (state.mailbox && confirm("Delete mailbox " + state.mailbox + " with all messages?")) {
+			request("DELETE", mailboxRoute(state.mailbox)).then(function () { selectMailbox(null); });
+		}
+	};
+
+	// MESSAGES
+
+	function loadMessages() {
+		var list = $("#messages ul");
+		if (!state.mailbox) {
+			list.textContent = "";
+			return Promise.resolve();
+		}
+
+		var address = state.mailbox;
+		return request("GET", mailboxRoute(address) + "/messages?limit=100").then(function (body) {
+			// mailbox was changed while request was in progress
+			if (address !== state.mailbox) {
+				return;
+			}
+
+			var known = {};
+			list.textContent = "";
+			(body.messages || []).forEach(function (message) {
+				var item = element("li", message.seen ? "" : "unread", message.subject || "(no subject)");
+				item.appendChild(element("span", "meta", message.from + ", " + formatDate(message.ReceivedDate)));
+				if (state.known && !state.known[message.Id]) {
+					item.classList.add("new");
+				}
+				if (message.Id === state.message) {
+					item.classList.add("selected");
+				}
+				item.onclick = function () { selectMessage(message.Id); };
+				list.appendChild(item);
+				known[message.Id] = true;
+			});
+			state.known = known;
+		});
+	}
+
+	function selectMessage(id) {
+		state.message = id;
+		$("#message .empty").hidden = !!id;
+		$("#message article").hidden = !id;
+		if (!id) {
+			return;
+		}
+
+		// reading message marks it as seen
+		request("GET", messageRoute(id)).then(function (body) {
+			var message = body.content;
+			$("#message .subject").textContent = message.subject || "(no subject)";
+			$("#message .from").textContent = message.from;
+			$("#message .to").textContent = message.to;
+			$("#message .date").textContent = formatDate(message.ReceivedDate);
+			loadMessages();
+			loadMailboxes();
+		});
+
+		request("GET", messageRoute(id) + "/content").then(function (body) {
+			var content = body.content;
+			$("#message pre[data-tab=text]").textContent = content.text;
+			$("#message iframe").srcdoc = "";
+			if (content.html) {
+				showHtml(id);
+			}
+
+			var headers = $("#message table");
+			headers.textContent = "";
+			Object.keys(content.headers).sort().forEach(function (name) {
+				content.headers[name].forEach(function (value) {
+					var row = element("tr");
+					row.appendChild(element("td", "", name));
+					row.appendChild(element("td", "", value));
+					headers.appendChild(row);
+				});
+			});
+
+			var attachments = $("#message .attachments");
+			attachments.textContent = "";
+			content.attachments.forEach(function (attachment) {
+				var item = element("li");
+				var button = element("button", "", (attachment.filename || "attachment-" + attachment.id) + " (" + formatSize(attachment.size) + ")");
+				button.onclick = function () { download(id, attachment); };
+				item.appendChild(button);
+				attachments.appendChild(item);
+			});
+		});
+
+		request("GET", messageRoute(id) + "/raw", true).then(function (response) {
+			return response.text();
+		}).then(function (raw) {
+			$("#message pre[data-tab=raw]").textContent = raw;
+		});
+	}
+
+	// HTML is sanitized by API, sandbox of frame doesn't run scripts anyway
+	function showHtml(id) {
+		request("GET", messageRoute(id) + "/html", true).then(function (response) {
+			return response.text();
+		}).then(function (html) {
+			if (id === state.message) {
+				$("#message iframe").srcdoc = html;
+			}
+		});
+	}
+
+	// Attachments are fetched with API key and saved from memory
+	function download(id, attachment) {
+		request("GET", messageRoute(id) + "/attachments/" + attachment.id, true).then(function (response) {
+			return response.blob();
+		}).then(function (blob) {
+			var link = element("a");
+			link.href = URL.createObjectURL(blob);
+			link.download = attachment.filename || "attachment-" + attachment.id;
+			link.click();
+			setTimeout(function () { URL.revokeObjectURL(link.href); }, 1000);
+		});
+	}
+
+	$("#message-delete").onclick = function () {
+		if (state.message) {
+			request("DELETE", messageRoute(state.message)).then(function () {
+				selectMessage(null);
+				loadMessages();
+				loadMailboxes();
+			});
+		}
+	};
+
+	Array.prototype.forEach.call(document.querySelectorAll(".tabs button"), function (button) {
+		button.onclick = function () {
+			Array.prototype.forEach.call(document.querySelectorAll(".tabs button"), function (tab) {
+				tab.classList.toggle("active", tab === button);
+			});
+			Array.prototype.forEach.call(document.querySelectorAll("#message .tab"), function (tab) {
+				tab.hidden = tab.getAttribute("data-tab") !== button.getAttribute("data-tab");
+			});
+		};
+	});
+
+	// SETTINGS
+
+	var settings = $("#settings");
+	settings.tenant.value = state.tenant;
+	settings.key.value = state.key;
+	settings.onsubmit = function (event) {
+		event.preventDefault();
+		state.tenant = settings.tenant.value.trim();
+		state.key = settings.key.value.trim();
+		localStorage.setItem("tenant", state.tenant);
+		localStorage.setItem("key", state.key);
+		selectMailbox(null);
+	};
+
+	// LIVE UPDATES
+
+	function poll() {
+		Promise.all([loadMailboxes(), loadMessages()]).catch(function () {}).then(function () {
+			setTimeout(poll, pollInterval);
+		});
+	}
+
+	poll();
+})();
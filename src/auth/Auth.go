@@ -0,0 +1,148 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"memdb"
+	"models"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// length of generated keys in bytes
+const keyLength = 24
+
+// Static admin keys, ADMIN_API_KEYS environment variable - comma separated list
+// Authentication is enabled only when at least one admin key is configured
+// keys are replaced at runtime while requests are authenticated
+var adminKeys atomic.Pointer[[]string]
+
+func init() {
+	SetAdminKeys(parseKeys(os.Getenv("ADMIN_API_KEYS")))
+}
+
+/**
+Replace static admin keys
+
+@params keys []string
+
+@return void
+*/
+func SetAdminKeys(keys []string) {
+	adminKeys.Store(&keys)
+}
+
+/**
+Check if requests have to be authenticated
+
+@return bool
+*/
+func Enabled() bool {
+	return len(*adminKeys.Load()) > 0
+}
+
+/**
+Generate new random key
+
+@return string, error
+*/
+func GenerateKey() (string, error) {
+	key := make([]byte, keyLength)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(key), nil
+}
+
+/**
+Create and save new key
+@params tenant string - tenant of the key
+@params admin bool - key grants access to everything inside tenant
+@params mailbox string - the only mailbox available for not admin key
+
+@return memdb.CommandResult
+*/
+func IssueKey(tenant string, admin bool, mailbox string) memdb.CommandResult {
+	key, err := GenerateKey()
+	if err != nil {
+		return memdb.CommandResult{Success: false, Error: err.Error(), Code: memdb.ErrorInternal}
+	}
+
+	apiKey := &models.ApiKey{Key: key, Admin: admin, Created: time.Now()}
+	if !admin {
+		apiKey.Mailbox = mailbox
+	}
+
+	return memdb.GetInstance().Tenant(tenant).InsertApiKey(apiKey)
+}
+
+/**
+Find key among static admin keys and saved ones
+@params key string
+
+@return *models.ApiKey - nil if key is unknown
+*/
+func Lookup(key string) *models.ApiKey {
+	if key == "" {
+		return nil
+	}
+
+	for _, adminKey := range *adminKeys.Load() {
+		if subtle.ConstantTimeCompare([]byte(adminKey), []byte(key)) == 1 {
+			return &models.ApiKey{Key: key, Admin: true}
+		}
+	}
+
+	response := memdb.GetInstance().GetApiKey(key)
+	if !response.Success {
+		return nil
+	}
+
+	return response.Value.(*models.ApiKey)
+}
+
+/**
+Check if key grants access to mailbox
+Static admin keys have access to every tenant
+@params apiKey *models.ApiKey
+@params tenant string - empty for actions not related to single tenant
+@params address string - empty for actions not related to single mailbox
+
+@return bool
+*/
+func CanAccess(apiKey *models.ApiKey, tenant string, address string) bool {
+	if apiKey == nil {
+		return false
+	}
+	if apiKey.Admin && apiKey.Tenant == "" {
+		return true
+	}
+	if tenant == "" || apiKey.Tenant != tenant {
+		return false
+	}
+	if apiKey.Admin {
+		return true
+	}
+
+	return address != "" && memdb.SameMailbox(apiKey.Mailbox, address)
+}
+
+/**
+Split comma separated list of keys
+
+@return []string
+*/
+func parseKeys(value string) []string {
+	keys := []string{}
+	for _, key := range strings.Split(value, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
@@ -0,0 +1,264 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Default settings of client
+const (
+	defaultRetries      = 3
+	defaultRetryWait    = 500 * time.Millisecond
+	defaultPollInterval = time.Second
+)
+
+// Client of mail service HTTP API
+type Client struct {
+	// address of API, e.g. http://127.0.0.1:8080
+	BaseURL string
+	// admin key or mailbox token, empty if authentication is disabled
+	APIKey string
+	// routes are prefixed with /tenants/{tenant} if set
+	Tenant string
+	// count of repeated attempts after network failures, 429 and 5xx responses
+	Retries int
+	// delay before the first retry, it is doubled for every next one
+	RetryWait time.Duration
+	// delay between checks of WaitForMessage
+	PollInterval time.Duration
+	HTTPClient   *http.Client
+}
+
+// Failed request, Code is machine readable error of API like "not_found"
+type Error struct {
+	Status  int    `json:"status"`
+	Code    string `json:"error"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d %s: %s", e.Status, e.Code, e.Message)
+}
+
+/**
+Check if error is API error with some code
+@params err error
+@params code string - e.g. "not_found"
+
+@return bool
+*/
+func IsErrorCode(err error, code string) bool {
+	apiError, ok := err.(*Error)
+	return ok && apiError.Code == code
+}
+
+/**
+Create new client with default settings
+@params baseURL string - address of API
+@params apiKey string - admin key or mailbox token
+
+@return *Client
+*/
+func New(baseURL string, apiKey string) *Client {
+	return &Client{
+		BaseURL:      strings.TrimRight(baseURL, "/"),
+		APIKey:       apiKey,
+		Retries:      defaultRetries,
+		RetryWait:    defaultRetryWait,
+		PollInterval: defaultPollInterval,
+		HTTPClient:   http.DefaultClient,
+	}
+}
+
+/**
+Copy client with another API key, e.g. with token of created mailbox
+@params apiKey string
+
+@return *Client
+*/
+func (c *Client) WithKey(apiKey string) *Client {
+	other := *c
+	other.APIKey = apiKey
+	return &other
+}
+
+/**
+Copy client which works inside tenant
+@params tenant string
+
+@return *Client
+*/
+func (c *Client) WithTenant(tenant string) *Client {
+	other := *c
+	other.Tenant = tenant
+	return &other
+}
+
+/**
+Build path of route inside tenant of client
+@params format string
+@params args ...interface{} - escaped as path segments
+
+@return string
+*/
+func (c *Client) path(format string, args ...interface{}) string {
+	for i, arg := range args {
+		if value, ok := arg.(string); ok {
+			args[i] = url.PathEscape(value)
+		}
+	}
+
+	path := fmt.Sprintf(format, args...)
+	if c.Tenant != "" {
+		path = "/tenants/" + url.PathEscape(c.Tenant) + path
+	}
+
+	return path
+}
+
+/**
+Send request and decode JSON response, failed attempts are retried
+@params ctx context.Context
+@params method string
+@params path string
+@params query url.Values - may be nil
+@params form url.Values - body of request, may be nil
+@params result interface{} - decoded response, *[]byte for not JSON response, may be nil
+
+@return *http.Response, error
+*/
+func (c *Client) do(ctx context.Context, method string, path string, query url.Values, form url.Values, result interface{}) (*http.Response, error) {
+	wait := c.RetryWait
+	for attempt := 0; ; attempt++ {
+		response, body, err := c.send(ctx, method, path, query, form)
+
+		retry := attempt < c.Retries && c.shouldRetry(method, response, err)
+		if !retry {
+			if err != nil {
+				return response, err
+			}
+			if response.StatusCode >= http.StatusBadRequest {
+				return response, decodeError(response, body)
+			}
+			if raw, ok := result.(*[]byte); ok {
+				*raw = body
+			} else if result != nil {
+				if err := json.Unmarshal(body, result); err != nil {
+					return response, fmt.Errorf("Failed decode response: %v", err)
+				}
+			}
+			return response, nil
+		}
+
+		// server tells when the next request is allowed
+		delay := wait
+		if response != nil {
+			if seconds, err := strconv.Atoi(response.Header.Get("Retry-After")); err == nil {
+				delay = time.Duration(seconds) * time.Second
+			}
+		}
+		wait *= 2
+
+		select {
+		case <-ctx.Done():
+			return response, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+/**
+Send single request and read its body
+@params ctx context.Context
+@params method string
+@params path string
+@params query url.Values
+@params form url.Values
+
+@return *http.Response, []byte, error
+*/
+func (c *Client) send(ctx context.Context, method string, path string, query url.Values, form url.Values) (*http.Response, []byte, error) {
+	address := c.BaseURL + path
+	if len(query) > 0 {
+		address += "?" + query.Encode()
+	}
+
+	var body io.Reader
+	if form != nil {
+		body = strings.NewReader(form.Encode())
+	}
+
+	request, err := http.NewRequest(method, address, body)
+	if err != nil {
+		return nil, nil, err
+	}
+	request = request.WithContext(ctx)
+	if form != nil {
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	if c.APIKey != "" {
+		request.Header.Set("Authorization", "Bearer "+c.APIKey)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer response.Body.Close()
+
+	data, err := ioutil.ReadAll(response.Body)
+	return response, data, err
+}
+
+/**
+Check if failed attempt can be repeated
+Rejected by rate limit requests are always repeated, other failures - only for idempotent methods
+
+@return bool
+*/
+func (c *Client) shouldRetry(method string, response *http.Response, err error) bool {
+	if response != nil && response.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+	if method == http.MethodPost {
+		return false
+	}
+	if err != nil {
+		return true
+	}
+
+	switch response.StatusCode {
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+
+	return false
+}
+
+/**
+Decode error body of API
+
+@return error
+*/
+func decodeError(response *http.Response, body []byte) error {
+	apiError := &Error{}
+	if json.Unmarshal(body, apiError) != nil || apiError.Code == "" {
+		apiError.Message = strings.TrimSpace(string(body))
+	}
+	apiError.Status = response.StatusCode
+
+	return apiError
+}
@@ -0,0 +1,120 @@
+package client
+
+import (
+	"context"
+	"models"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// Created mailbox with token which grants access only to it
+type Mailbox struct {
+	Address string `json:"mailbox"`
+	Token   string `json:"token"`
+}
+
+// Cursor pagination params, zero values are not sent
+type ListOptions struct {
+	// page size, 10 by default, 100 at most
+	Limit int
+	// items older than id
+	MaxId int
+	// items newer than id
+	SinceId int
+	// oldest first, newest first by default
+	Ascending bool
+}
+
+// Page of mailboxes with statistics
+type MailboxPage struct {
+	Mailboxes []*models.MailBoxStats `json:"mailboxes"`
+	Count     int                    `json:"count"`
+	Total     int                    `json:"total"`
+	HasMore   bool                   `json:"hasMore"`
+	// cursor of the next page
+	MaxId   int `json:"maxId"`
+	SinceId int `json:"sinceId"`
+}
+
+/**
+Convert options to query params
+
+@return url.Values
+*/
+func (o *ListOptions) query() url.Values {
+	query := url.Values{}
+	if o == nil {
+		return query
+	}
+
+	if o.Limit > 0 {
+		query.Set("limit", strconv.Itoa(o.Limit))
+	}
+	if o.MaxId > 0 {
+		query.Set("maxId", strconv.Itoa(o.MaxId))
+	}
+	if o.SinceId > 0 {
+		query.Set("sinceId", strconv.Itoa(o.SinceId))
+	}
+	if o.Ascending {
+		query.Set("order", "asc")
+	}
+
+	return query
+}
+
+/**
+Create mailbox with random address
+@params ctx context.Context
+
+@return *Mailbox, error
+*/
+func (c *Client) CreateMailbox(ctx context.Context) (*Mailbox, error) {
+	mailbox := &Mailbox{}
+	_, err := c.do(ctx, http.MethodPost, c.path("/mailboxes"), nil, url.Values{}, mailbox)
+	if err != nil {
+		return nil, err
+	}
+
+	return mailbox, nil
+}
+
+/**
+Return page of mailboxes
+@params ctx context.Context
+@params prefix string - address prefix, may be empty
+@params domain string - address domain, may be empty
+@params options *ListOptions - may be nil
+
+@return *MailboxPage, error
+*/
+func (c *Client) ListMailboxes(ctx context.Context, prefix string, domain string, options *ListOptions) (*MailboxPage, error) {
+	query := options.query()
+	if prefix != "" {
+		query.Set("prefix", prefix)
+	}
+	if domain != "" {
+		query.Set("domain", domain)
+	}
+
+	page := &MailboxPage{}
+	_, err := c.do(ctx, http.MethodGet, c.path("/mailboxes"), query, nil, page)
+	if err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
+/**
+Remove mailbox with its messages
+@params ctx context.Context
+@params address string
+
+@return error
+*/
+func (c *Client) DeleteMailbox(ctx context.Context, address string) error {
+	_, err := c.do(ctx, http.MethodDelete, c.path("/mailboxes/%s", address), nil, nil, nil)
+	return err
+}
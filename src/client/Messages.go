@@ -0,0 +1,335 @@
+package client
+
+import (
+	"context"
+	"mail_parser"
+	"models"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Filters of messages list, nil values are not sent
+type MessageFilter struct {
+	Unread  *bool
+	Flagged *bool
+}
+
+// Page of messages
+type MessagePage struct {
+	Messages []*models.Message `json:"messages"`
+	Count    int               `json:"count"`
+	// count of unread messages in mailbox
+	Unread  int  `json:"unread"`
+	Total   int  `json:"total"`
+	HasMore bool `json:"hasMore"`
+	// cursor of the next page
+	MaxId   int `json:"maxId"`
+	SinceId int `json:"sinceId"`
+}
+
+// Conditions of message search, every not empty field has to be contained (case insensitive)
+type SearchQuery struct {
+	From    string
+	To      string
+	Subject string
+	Body    string
+}
+
+/**
+Check if message satisfies every condition of query
+@params message *models.Message
+
+@return bool
+*/
+func (q SearchQuery) Match(message *models.Message) bool {
+	return containsFold(message.From, q.From) &&
+		containsFold(message.To, q.To) &&
+		containsFold(message.Subject, q.Subject) &&
+		containsFold(message.Body, q.Body)
+}
+
+/**
+Return page of mailbox messages
+@params ctx context.Context
+@params address string
+@params filter *MessageFilter - may be nil
+@params options *ListOptions - may be nil
+
+@return *MessagePage, error
+*/
+func (c *Client) ListMessages(ctx context.Context, address string, filter *MessageFilter, options *ListOptions) (*MessagePage, error) {
+	query := options.query()
+	if filter != nil && filter.Unread != nil {
+		query.Set("unread", strconv.FormatBool(*filter.Unread))
+	}
+	if filter != nil && filter.Flagged != nil {
+		query.Set("flagged", strconv.FormatBool(*filter.Flagged))
+	}
+
+	page := &MessagePage{}
+	_, err := c.do(ctx, http.MethodGet, c.path("/mailboxes/%s/messages", address), query, nil, page)
+	if err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
+/**
+Read message, it is marked as seen unless server disables it
+@params ctx context.Context
+@params address string
+@params id int
+
+@return *models.Message, error
+*/
+func (c *Client) GetMessage(ctx context.Context, address string, id int) (*models.Message, error) {
+	result := &struct {
+		Content *models.Message `json:"content"`
+	}{}
+	_, err := c.do(ctx, http.MethodGet, c.path("/mailboxes/%s/messages/%d", address, id), nil, nil, result)
+	if err != nil {
+		return nil, err
+	}
+
+	return result.Content, nil
+}
+
+/**
+Read original RFC 5322 data of message, message is not marked as seen
+@params ctx context.Context
+@params address string
+@params id int
+
+@return []byte, error
+*/
+func (c *Client) GetRawMessage(ctx context.Context, address string, id int) ([]byte, error) {
+	var raw []byte
+	_, err := c.do(ctx, http.MethodGet, c.path("/mailboxes/%s/messages/%d/raw", address, id), nil, nil, &raw)
+	if err != nil {
+		return nil, err
+	}
+
+	return raw, nil
+}
+
+/**
+Find links of message, message is not marked as seen
+@params ctx context.Context
+@params address string
+@params id int
+@params contains string - only links which url or text contains it, empty for all links
+
+@return []mail_parser.Link, error
+*/
+func (c *Client) GetLinks(ctx context.Context, address string, id int, contains string) ([]mail_parser.Link, error) {
+	query := url.Values{}
+	if contains != "" {
+		query.Set("contains", contains)
+	}
+
+	result := &struct {
+		Links []mail_parser.Link `json:"links"`
+	}{}
+	_, err := c.do(ctx, http.MethodGet, c.path("/mailboxes/%s/messages/%d/links", address, id), query, nil, result)
+	if err != nil {
+		return nil, err
+	}
+
+	return result.Links, nil
+}
+
+/**
+Find verification codes of message, message is not marked as seen
+@params ctx context.Context
+@params address string
+@params id int
+@params patterns ...string - regular expressions which are applied before built-in patterns
+
+@return []mail_parser.Code, error
+*/
+func (c *Client) GetCodes(ctx context.Context, address string, id int, patterns ...string) ([]mail_parser.Code, error) {
+	query := url.Values{}
+	for _, pattern := range patterns {
+		query.Add("pattern", pattern)
+	}
+
+	result := &struct {
+		Codes []mail_parser.Code `json:"codes"`
+	}{}
+	_, err := c.do(ctx, http.MethodGet, c.path("/mailboxes/%s/messages/%d/codes", address, id), query, nil, result)
+	if err != nil {
+		return nil, err
+	}
+
+	return result.Codes, nil
+}
+
+/**
+Add message to mailbox of its recipient
+@params ctx context.Context
+@params message *models.Message - from, to, subject and body are required
+
+@return error
+*/
+func (c *Client) AddMessage(ctx context.Context, message *models.Message) error {
+	form := url.Values{
+		"from":    {message.From},
+		"to":      {message.To},
+		"subject": {message.Subject},
+		"message": {message.Body},
+	}
+	if message.MessageID != "" {
+		form.Set("message-id", message.MessageID)
+	}
+	if message.InReplyTo != "" {
+		form.Set("in-reply-to", message.InReplyTo)
+	}
+	for _, reference := range message.References {
+		form.Add("references", reference)
+	}
+
+	_, err := c.do(ctx, http.MethodPost, c.path("/mailboxes/%s/messages", message.To), nil, form, nil)
+	return err
+}
+
+/**
+Change flags of message, nil flags are not changed
+@params ctx context.Context
+@params address string
+@params id int
+@params flags models.MessageFlags
+
+@return *models.Message, error
+*/
+func (c *Client) UpdateMessage(ctx context.Context, address string, id int, flags models.MessageFlags) (*models.Message, error) {
+	form := url.Values{}
+	if flags.Seen != nil {
+		form.Set("seen", strconv.FormatBool(*flags.Seen))
+	}
+	if flags.Flagged != nil {
+		form.Set("flagged", strconv.FormatBool(*flags.Flagged))
+	}
+	if flags.Answered != nil {
+		form.Set("answered", strconv.FormatBool(*flags.Answered))
+	}
+
+	result := &struct {
+		Content *models.Message `json:"content"`
+	}{}
+	_, err := c.do(ctx, http.MethodPatch, c.path("/mailboxes/%s/messages/%d", address, id), nil, form, result)
+	if err != nil {
+		return nil, err
+	}
+
+	return result.Content, nil
+}
+
+/**
+Remove message
+@params ctx context.Context
+@params address string
+@params id int
+
+@return error
+*/
+func (c *Client) DeleteMessage(ctx context.Context, address string, id int) error {
+	_, err := c.do(ctx, http.MethodDelete, c.path("/mailboxes/%s/messages/%d", address, id), nil, nil, nil)
+	return err
+}
+
+/**
+Find every message of mailbox which matches query, oldest first
+@params ctx context.Context
+@params address string
+@params query SearchQuery
+
+@return []*models.Message, error
+*/
+func (c *Client) Search(ctx context.Context, address string, query SearchQuery) ([]*models.Message, error) {
+	found := []*models.Message{}
+	err := c.eachMessage(ctx, address, 0, func(message *models.Message) bool {
+		if query.Match(message) {
+			found = append(found, message)
+		}
+		return true
+	})
+
+	return found, err
+}
+
+/**
+Wait until message which matches query arrives to mailbox
+Messages received before the call are checked as well, deadline is taken from context
+@params ctx context.Context
+@params address string
+@params query SearchQuery
+
+@return *models.Message, error
+*/
+func (c *Client) WaitForMessage(ctx context.Context, address string, query SearchQuery) (*models.Message, error) {
+	var found *models.Message
+	sinceId := 0
+
+	for {
+		err := c.eachMessage(ctx, address, sinceId, func(message *models.Message) bool {
+			sinceId = message.Id
+			if query.Match(message) {
+				found = message
+				return false
+			}
+			return true
+		})
+		if err != nil || found != nil {
+			return found, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(c.PollInterval):
+		}
+	}
+}
+
+/**
+Walk through mailbox messages newer than id, oldest first
+@params ctx context.Context
+@params address string
+@params sinceId int - zero for every message
+@params handle func(*models.Message) bool - stops walking when returns false
+
+@return error
+*/
+func (c *Client) eachMessage(ctx context.Context, address string, sinceId int, handle func(*models.Message) bool) error {
+	options := &ListOptions{Limit: 100, SinceId: sinceId, Ascending: true}
+	for {
+		page, err := c.ListMessages(ctx, address, nil, options)
+		if err != nil {
+			return err
+		}
+
+		for _, message := range page.Messages {
+			if !handle(message) {
+				return nil
+			}
+		}
+
+		if !page.HasMore {
+			return nil
+		}
+		options.SinceId = page.SinceId
+	}
+}
+
+/**
+Case insensitive check of substring, empty substring matches everything
+
+@return bool
+*/
+func containsFold(value string, substring string) bool {
+	return strings.Contains(strings.ToLower(value), strings.ToLower(substring))
+}
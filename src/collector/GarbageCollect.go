@@ -1,9 +1,18 @@
 package collector
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"health"
+	"logger"
 	"memdb"
+	"metrics"
+	"models"
 	"time"
+	"tracing"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
 const (
@@ -11,16 +20,22 @@ const (
 	messageExpirationDurution = 60
 	// Time in seconds for ticker interval
 	tickerInterval = 180
+	// Name of collector in health status
+	healthName = "collector"
 )
 
 func Collect() {
+	// collector is failed if a few runs in a row are missed
+	health.Ok(healthName, "Waiting for the first run")
+	health.Expect(healthName, 3*tickerInterval*time.Second)
+
 	garbage := make(chan string)
 	// create new ticker for garbage collect
 	ticker := time.NewTicker(time.Second * tickerInterval)
 	go Tick(ticker, garbage)
 
 	for {
-		fmt.Println(<-garbage)
+		logger.Default().Info(<-garbage)
 	}
 }
 
@@ -31,7 +46,27 @@ func Tick(ticker *time.Ticker, garbage chan<- string) {
 }
 
 func Clear(tick time.Time, garbage chan<- string) {
-	memdb.GetInstance().ClearNotRelevantMessages(messageExpirationDurution * time.Minute)
+	// commands of every tenant are child spans of the run
+	ctx, span := tracing.Start(context.Background(), "collector run")
+	defer span.End()
+
+	instance := memdb.GetInstance().WithContext(ctx)
+	// every tenant is cleared separately, so expired messages are removed only inside own tenant
+	response := instance.GetTenants()
+	if response.Success {
+		deleted := 0
+		for _, tenant := range response.Value.([]*models.Tenant) {
+			cleared := instance.Tenant(tenant.Name).ClearNotRelevantMessages(messageExpirationDurution * time.Minute)
+			deleted += cleared.Rows
+		}
+		span.SetAttributes(attribute.Int("collector.deleted", deleted))
+		metrics.CollectorDeleted.Add(float64(deleted))
+		health.Ok(healthName, fmt.Sprintf("Removed %d expired messages", deleted))
+	} else {
+		tracing.Fail(span, errors.New(response.Error))
+		health.Failed(healthName, errors.New(response.Error))
+	}
+	metrics.CollectorRuns.Inc()
 
-	garbage <- fmt.Sprintf("Tick at %v", tick)
+	garbage <- fmt.Sprintf("Collector run at %s", tick.Format(time.RFC3339))
 }
@@ -0,0 +1,127 @@
+package health
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// States of components
+const (
+	StatusOk       = "ok"
+	StatusStarting = "starting"
+	StatusFailed   = "failed"
+)
+
+// Last reported state of component
+type Component struct {
+	Status  string    `json:"status"`
+	Message string    `json:"message,omitempty"`
+	Updated time.Time `json:"updated"`
+	// component fails if it doesn't report success for this time, zero means forever
+	maxAge time.Duration
+}
+
+var (
+	mutex      sync.Mutex
+	components = map[string]*Component{}
+)
+
+/**
+Report that component is starting
+@params name string
+@params message string
+
+@return void
+*/
+func Starting(name string, message string) {
+	report(name, StatusStarting, message)
+}
+
+/**
+Report that component works
+@params name string
+@params message string
+
+@return void
+*/
+func Ok(name string, message string) {
+	report(name, StatusOk, message)
+}
+
+/**
+Report that component failed
+@params name string
+@params err error
+
+@return void
+*/
+func Failed(name string, err error) {
+	report(name, StatusFailed, err.Error())
+}
+
+/**
+Fail component if it doesn't report success for some time
+@params name string
+@params maxAge time.Duration
+
+@return void
+*/
+func Expect(name string, maxAge time.Duration) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	component(name).maxAge = maxAge
+}
+
+/**
+Get copy of every component state, outdated components are failed
+
+@return map[string]Component
+*/
+func Components() map[string]Component {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	result := make(map[string]Component, len(components))
+	for name, state := range components {
+		current := *state
+		if current.Status == StatusOk && current.maxAge > 0 && time.Since(current.Updated) > current.maxAge {
+			current.Status = StatusFailed
+			current.Message = fmt.Sprintf("No successful report since %s", current.Updated.Format(time.RFC3339))
+		}
+		result[name] = current
+	}
+
+	return result
+}
+
+/**
+Save state of component
+
+@return void
+*/
+func report(name string, status string, message string) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	state := component(name)
+	state.Status = status
+	state.Message = message
+	state.Updated = time.Now()
+}
+
+/**
+Get existing component or create new one, mutex has to be locked
+
+@return *Component
+*/
+func component(name string) *Component {
+	state := components[name]
+	if state == nil {
+		state = &Component{Status: StatusStarting, Updated: time.Now()}
+		components[name] = state
+	}
+
+	return state
+}
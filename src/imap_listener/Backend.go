@@ -0,0 +1,177 @@
+package imap_listener
+
+import (
+	"auth"
+	"logger"
+	"memdb"
+	"models"
+	"strings"
+	"sync"
+
+	"github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/backend"
+	"github.com/emersion/go-imap/server"
+)
+
+// name of the only mailbox exposed for every address
+const inboxName = "INBOX"
+
+type imapBackend struct {
+	// server of backend, its connections are closed when database events are lost
+	server *server.Server
+	// current subscription to database events, nil after backend is closed
+	mutex  sync.Mutex
+	events <-chan memdb.Event
+	closed bool
+}
+
+type imapUser struct {
+	address string
+}
+
+/**
+Create new backend for IMAP server
+
+@return *imapBackend
+*/
+func newBackend() *imapBackend {
+	return &imapBackend{}
+}
+
+/**
+Authenticate user. Username is a mailbox address
+If API authentication is enabled, password is an API key with access to the mailbox
+
+@params connInfo *imap.ConnInfo
+@params username string
+@params password string
+
+@return backend.User, error
+*/
+func (b *imapBackend) Login(connInfo *imap.ConnInfo, username, password string) (backend.User, error) {
+	// mailbox is searched inside tenant of its address
+	tenant := memdb.AddressTenant(username)
+	response := memdb.GetInstance().Tenant(tenant).GetMailBox(username)
+	if !response.Success {
+		logger.Default().Warn("IMAP login failed", "mailbox", username, "client", connInfo.RemoteAddr.String(), "error", response.Error)
+		return nil, backend.ErrInvalidCredentials
+	}
+
+	if auth.Enabled() && !auth.CanAccess(auth.Lookup(password), tenant, username) {
+		logger.Default().Warn("IMAP login failed", "mailbox", username, "client", connInfo.RemoteAddr.String(), "error", "access denied")
+		return nil, backend.ErrInvalidCredentials
+	}
+
+	// updates are matched by address of mailbox, username may differ in case or subaddress
+	address := response.Value.(*models.MailBox).Address
+	logger.Default().Info("IMAP login", "mailbox", address, "client", connInfo.RemoteAddr.String())
+	return &imapUser{address: address}, nil
+}
+
+/**
+Translate database events to IMAP updates, so IDLE clients see changes
+If events are lost, sequence numbers of clients are wrong, so backend subscribes again
+and closes connections, clients reconnect and select mailbox with actual state
+
+@return <-chan backend.Update
+*/
+func (b *imapBackend) Updates() <-chan backend.Update {
+	updates := make(chan backend.Update)
+	events := b.subscribe()
+
+	go func() {
+		for events != nil {
+			for event := range events {
+				update := backend.NewUpdate(event.Address, inboxName)
+
+				switch event.Type {
+				case memdb.MessageInserted:
+					// new message is always the last one in mailbox
+					status := imap.NewMailboxStatus(inboxName, []imap.StatusItem{imap.StatusMessages})
+					status.Messages = uint32(event.Index + 1)
+					updates <- &backend.MailboxUpdate{Update: update, MailboxStatus: status}
+				case memdb.MessageUpdated:
+					message := imap.NewMessage(uint32(event.Index+1), []imap.FetchItem{imap.FetchFlags})
+					message.Flags = event.Message.AllFlags()
+					updates <- &backend.MessageUpdate{Update: update, Message: message}
+				case memdb.MessageDeleted:
+					updates <- &backend.ExpungeUpdate{Update: update, SeqNum: uint32(event.Index + 1)}
+				}
+			}
+
+			// chanel is closed by database because events are lost, or by backend which is closed
+			events = b.subscribe()
+			if events != nil && b.server != nil {
+				logger.Default().Warn("IMAP updates are lost, connections are closed")
+				b.server.ForEachConn(func(conn server.Conn) {
+					conn.Close()
+				})
+			}
+		}
+	}()
+
+	return updates
+}
+
+/**
+Subscribe to database events
+
+@return <-chan memdb.Event - nil if backend is closed
+*/
+func (b *imapBackend) subscribe() <-chan memdb.Event {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.closed {
+		return nil
+	}
+	b.events = memdb.GetInstance().Subscribe()
+	return b.events
+}
+
+/**
+Stop translating database events, called when server is stopped
+
+@return void
+*/
+func (b *imapBackend) close() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.closed = true
+	if b.events != nil {
+		memdb.GetInstance().Unsubscribe(b.events)
+	}
+}
+
+func (u *imapUser) Username() string {
+	return u.address
+}
+
+func (u *imapUser) ListMailboxes(subscribed bool) ([]backend.Mailbox, error) {
+	return []backend.Mailbox{&imapMailbox{address: u.address}}, nil
+}
+
+func (u *imapUser) GetMailbox(name string) (backend.Mailbox, error) {
+	if !strings.EqualFold(name, inboxName) {
+		return nil, backend.ErrNoSuchMailbox
+	}
+
+	return &imapMailbox{address: u.address}, nil
+}
+
+func (u *imapUser) CreateMailbox(name string) error {
+	return backend.ErrMailboxAlreadyExists
+}
+
+func (u *imapUser) DeleteMailbox(name string) error {
+	return errReadOnlyHierarchy
+}
+
+func (u *imapUser) RenameMailbox(existingName, newName string) error {
+	return errReadOnlyHierarchy
+}
+
+func (u *imapUser) Logout() error {
+	return nil
+}
@@ -0,0 +1,60 @@
+package imap_listener
+
+import (
+	"fmt"
+	"health"
+	"logger"
+	"net"
+
+	"github.com/emersion/go-imap/server"
+)
+
+const listenPort = 1143
+
+// name of listener in health status
+const healthName = "imap"
+
+/**
+Create new IMAP listener
+
+@return void
+*/
+func Listen() {
+	address := fmt.Sprintf("127.0.0.1:%d", listenPort)
+	health.Starting(healthName, "Binding "+address)
+
+	// port is bound separately, so failure is visible in health status
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		logger.Default().Error("IMAP listener failed", "address", address, "error", err)
+		health.Failed(healthName, err)
+		return
+	}
+
+	logger.Default().Info("IMAP listener started", "address", address)
+	health.Ok(healthName, "Listening on "+address)
+
+	if err := Serve(listener); err != nil {
+		logger.Default().Error("IMAP listener stopped", "error", err)
+		health.Failed(healthName, err)
+	}
+}
+
+/**
+Serve IMAP sessions on listener until it is closed, used by Listen and tests
+@params listener net.Listener
+
+@return error
+*/
+func Serve(listener net.Listener) error {
+	// create server over in-memory mailboxes
+	imapBackend := newBackend()
+	imapServer := server.New(imapBackend)
+	imapBackend.server = imapServer
+	defer imapBackend.close()
+
+	// there is no TLS, so plain text LOGIN has to be allowed
+	imapServer.AllowInsecureAuth = true
+
+	return imapServer.Serve(listener)
+}
@@ -0,0 +1,373 @@
+package imap_listener
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"memdb"
+	"metrics"
+	"models"
+	"net/mail"
+	"time"
+
+	"github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/backend/backendutil"
+	"github.com/emersion/go-message"
+	"github.com/emersion/go-message/textproto"
+)
+
+var errReadOnlyHierarchy = errors.New("Mailboxes can not be changed")
+
+// flags which can be stored on messages
+var mailboxFlags = []string{
+	imap.SeenFlag,
+	imap.AnsweredFlag,
+	imap.FlaggedFlag,
+	imap.DeletedFlag,
+	imap.DraftFlag,
+}
+
+// INBOX of single models.MailBox
+// Every call reads fresh state from database, message UIDs are database ids
+type imapMailbox struct {
+	address string
+}
+
+/**
+Get copy of mailbox from database
+
+@return *models.MailBox, error
+*/
+func (m *imapMailbox) load() (*models.MailBox, error) {
+	response := memdb.GetInstance().Tenant(memdb.AddressTenant(m.address)).GetMailBox(m.address)
+	if !response.Success {
+		return nil, errors.New(response.Error)
+	}
+
+	return response.Value.(*models.MailBox), nil
+}
+
+func (m *imapMailbox) Name() string {
+	return inboxName
+}
+
+func (m *imapMailbox) Info() (*imap.MailboxInfo, error) {
+	return &imap.MailboxInfo{Delimiter: "/", Name: inboxName}, nil
+}
+
+func (m *imapMailbox) Status(items []imap.StatusItem) (*imap.MailboxStatus, error) {
+	box, err := m.load()
+	if err != nil {
+		return nil, err
+	}
+
+	status := imap.NewMailboxStatus(inboxName, items)
+	status.Flags = mailboxFlags
+	status.PermanentFlags = append([]string{imap.TryCreateFlag}, mailboxFlags...)
+
+	unseen := 0
+	for index, message := range box.Messages {
+		if message.Seen {
+			continue
+		}
+		if unseen == 0 {
+			status.UnseenSeqNum = uint32(index + 1)
+		}
+		unseen++
+	}
+
+	for _, item := range items {
+		switch item {
+		case imap.StatusMessages:
+			status.Messages = uint32(len(box.Messages))
+		case imap.StatusUidNext:
+			status.UidNext = 1
+			if len(box.Messages) > 0 {
+				status.UidNext = uint32(box.Messages[len(box.Messages)-1].Id + 1)
+			}
+		case imap.StatusUidValidity:
+			// recreated mailbox gets new id, so cached UIDs are invalidated
+			status.UidValidity = uint32(box.Id)
+		case imap.StatusRecent:
+			status.Recent = 0
+		case imap.StatusUnseen:
+			status.Unseen = uint32(unseen)
+		}
+	}
+
+	return status, nil
+}
+
+func (m *imapMailbox) SetSubscribed(subscribed bool) error {
+	return nil
+}
+
+func (m *imapMailbox) Check() error {
+	return nil
+}
+
+func (m *imapMailbox) ListMessages(uid bool, seqSet *imap.SeqSet, items []imap.FetchItem, ch chan<- *imap.Message) error {
+	defer close(ch)
+
+	box, err := m.load()
+	if err != nil {
+		return err
+	}
+
+	// reading of body without PEEK marks message as seen
+	markSeen := false
+	for _, item := range items {
+		section, err := imap.ParseBodySectionName(item)
+		if err == nil && !section.Peek {
+			markSeen = true
+		}
+	}
+
+	instance := memdb.GetInstance().Tenant(memdb.AddressTenant(m.address))
+	seen := true
+	for index, message := range box.Messages {
+		seqNum := uint32(index + 1)
+		if !seqSet.Contains(messageId(uid, seqNum, message)) {
+			continue
+		}
+
+		if markSeen && !message.Seen {
+			response := instance.UpdateMessageFlags(m.address, message.Id, models.MessageFlags{Seen: &seen})
+			if response.Success {
+				message = response.Value.(*models.Message)
+			}
+		}
+
+		fetched, err := fetchMessage(seqNum, message, items)
+		if err != nil {
+			continue
+		}
+		ch <- fetched
+	}
+
+	return nil
+}
+
+func (m *imapMailbox) SearchMessages(uid bool, criteria *imap.SearchCriteria) ([]uint32, error) {
+	box, err := m.load()
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []uint32
+	for index, message := range box.Messages {
+		seqNum := uint32(index + 1)
+
+		entity, err := messageEntity(message)
+		if err != nil {
+			continue
+		}
+
+		matched, err := backendutil.Match(entity, seqNum, uint32(message.Id), message.ReceivedDate, message.AllFlags(), criteria)
+		if err != nil || !matched {
+			continue
+		}
+
+		ids = append(ids, messageId(uid, seqNum, message))
+	}
+
+	return ids, nil
+}
+
+/**
+APPEND command - saves message to mailbox
+
+@return error
+*/
+func (m *imapMailbox) CreateMessage(flags []string, date time.Time, body imap.Literal) error {
+	raw, err := ioutil.ReadAll(body)
+	if err != nil {
+		return err
+	}
+
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return err
+	}
+	text, err := ioutil.ReadAll(msg.Body)
+	if err != nil {
+		return err
+	}
+
+	if date.IsZero() {
+		date = time.Now()
+	}
+
+	insertMessage := &models.Message{
+		To:           m.address,
+		From:         msg.Header.Get("From"),
+		Subject:      msg.Header.Get("Subject"),
+		Body:         string(text),
+		Raw:          raw,
+		ReceivedDate: date,
+	}
+	insertMessage.SetFlags(flags)
+	insertMessage.SetThreadHeaders(msg.Header)
+
+	response := memdb.GetInstance().Tenant(memdb.AddressTenant(m.address)).InsertMessage(m.address, insertMessage)
+	if !response.Success {
+		metrics.DeliveriesRejected.WithLabelValues(metrics.ChannelImap, response.Code.String()).Inc()
+		return errors.New(response.Error)
+	}
+	metrics.MessagesReceived.WithLabelValues(metrics.ChannelImap).Inc()
+
+	return nil
+}
+
+func (m *imapMailbox) UpdateMessagesFlags(uid bool, seqSet *imap.SeqSet, operation imap.FlagsOp, flags []string) error {
+	box, err := m.load()
+	if err != nil {
+		return err
+	}
+
+	instance := memdb.GetInstance().Tenant(memdb.AddressTenant(m.address))
+	for index, message := range box.Messages {
+		if !seqSet.Contains(messageId(uid, uint32(index+1), message)) {
+			continue
+		}
+
+		newFlags := backendutil.UpdateFlags(message.AllFlags(), operation, flags)
+		response := instance.SetMessageFlags(m.address, message.Id, newFlags)
+		if !response.Success {
+			return errors.New(response.Error)
+		}
+	}
+
+	return nil
+}
+
+func (m *imapMailbox) CopyMessages(uid bool, seqSet *imap.SeqSet, dest string) error {
+	return errReadOnlyHierarchy
+}
+
+/**
+Removes all messages with \Deleted flag
+Clients are notified through database events
+
+@return error
+*/
+func (m *imapMailbox) Expunge() error {
+	box, err := m.load()
+	if err != nil {
+		return err
+	}
+
+	instance := memdb.GetInstance().Tenant(memdb.AddressTenant(m.address))
+	for _, message := range box.Messages {
+		if !hasFlag(message.Flags, imap.DeletedFlag) {
+			continue
+		}
+
+		response := instance.DeleteMessage(m.address, message.Id)
+		if !response.Success {
+			return errors.New(response.Error)
+		}
+	}
+
+	return nil
+}
+
+// HELPERS
+/**
+Build IMAP representation of message with requested items
+
+@return *imap.Message, error
+*/
+func fetchMessage(seqNum uint32, message *models.Message, items []imap.FetchItem) (*imap.Message, error) {
+	fetched := imap.NewMessage(seqNum, items)
+	raw := message.RawData()
+
+	for _, item := range items {
+		switch item {
+		case imap.FetchEnvelope:
+			header, _, err := headerAndBody(raw)
+			if err != nil {
+				return nil, err
+			}
+			fetched.Envelope, _ = backendutil.FetchEnvelope(header)
+		case imap.FetchBody, imap.FetchBodyStructure:
+			header, body, err := headerAndBody(raw)
+			if err != nil {
+				return nil, err
+			}
+			fetched.BodyStructure, _ = backendutil.FetchBodyStructure(header, body, item == imap.FetchBodyStructure)
+		case imap.FetchFlags:
+			fetched.Flags = message.AllFlags()
+		case imap.FetchInternalDate:
+			fetched.InternalDate = message.ReceivedDate
+		case imap.FetchRFC822Size:
+			fetched.Size = uint32(len(raw))
+		case imap.FetchUid:
+			fetched.Uid = uint32(message.Id)
+		default:
+			section, err := imap.ParseBodySectionName(item)
+			if err != nil {
+				break
+			}
+
+			header, body, err := headerAndBody(raw)
+			if err != nil {
+				return nil, err
+			}
+			literal, _ := backendutil.FetchBodySection(header, body, section)
+			fetched.Body[section] = literal
+		}
+	}
+
+	return fetched, nil
+}
+
+/**
+Split raw message to header and body reader
+
+@return textproto.Header, *bufio.Reader, error
+*/
+func headerAndBody(raw []byte) (textproto.Header, *bufio.Reader, error) {
+	body := bufio.NewReader(bytes.NewReader(raw))
+	header, err := textproto.ReadHeader(body)
+
+	return header, body, err
+}
+
+/**
+Parse message for searching
+
+@return *message.Entity, error
+*/
+func messageEntity(msg *models.Message) (*message.Entity, error) {
+	return message.Read(bytes.NewReader(msg.RawData()))
+}
+
+/**
+Returns UID or sequence number of message depending on command
+
+@return uint32
+*/
+func messageId(uid bool, seqNum uint32, message *models.Message) uint32 {
+	if uid {
+		return uint32(message.Id)
+	}
+
+	return seqNum
+}
+
+/**
+Check if flag is present in list
+
+@return bool
+*/
+func hasFlag(flags []string, flag string) bool {
+	for _, item := range flags {
+		if item == flag {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,161 @@
+package logger
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync/atomic"
+	"tracing"
+)
+
+// Keys of correlation ids in log lines
+const (
+	RequestIdKey = "request_id"
+	SessionIdKey = "session_id"
+	TraceIdKey   = "trace_id"
+)
+
+type contextKey int
+
+const (
+	requestIdContextKey contextKey = iota
+	sessionIdContextKey
+)
+
+var (
+	// level is changed at runtime, so it is shared by every logger
+	level = new(slog.LevelVar)
+	// output is replaced at runtime while other goroutines log
+	base atomic.Pointer[slog.Logger]
+)
+
+func init() {
+	SetOutput(os.Stdout)
+
+	if name := os.Getenv("LOG_LEVEL"); name != "" {
+		if err := SetLevel(name); err != nil {
+			Default().Warn("Invalid LOG_LEVEL, info level is used", "error", err)
+		}
+	}
+}
+
+/**
+Change level of every logger
+@params name string - debug, info, warn or error
+
+@return error
+*/
+func SetLevel(name string) error {
+	var parsed slog.Level
+	if err := parsed.UnmarshalText([]byte(name)); err != nil {
+		return err
+	}
+
+	level.Set(parsed)
+	return nil
+}
+
+/**
+Get current level
+
+@return string - debug, info, warn or error
+*/
+func Level() string {
+	return strings.ToLower(level.Level().String())
+}
+
+/**
+Write log lines to another destination, used by tests
+@params writer io.Writer
+
+@return void
+*/
+func SetOutput(writer io.Writer) {
+	base.Store(slog.New(slog.NewJSONHandler(writer, &slog.HandlerOptions{Level: level})))
+}
+
+/**
+Generate new random id for request or session
+
+@return string
+*/
+func NewId() string {
+	id := make([]byte, 8)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+/**
+Put request id to context
+@params ctx context.Context
+@params id string
+
+@return context.Context
+*/
+func WithRequestId(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIdContextKey, id)
+}
+
+/**
+Put session id to context
+@params ctx context.Context
+@params id string
+
+@return context.Context
+*/
+func WithSessionId(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, sessionIdContextKey, id)
+}
+
+/**
+Get request id from context
+@params ctx context.Context
+
+@return string - empty if absent
+*/
+func RequestId(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+
+	id, _ := ctx.Value(requestIdContextKey).(string)
+	return id
+}
+
+/**
+Get logger with correlation ids from context
+@params ctx context.Context - may be nil
+
+@return *slog.Logger
+*/
+func From(ctx context.Context) *slog.Logger {
+	logger := base.Load()
+	if ctx == nil {
+		return logger
+	}
+
+	if id, ok := ctx.Value(sessionIdContextKey).(string); ok {
+		logger = logger.With(SessionIdKey, id)
+	}
+	if id, ok := ctx.Value(requestIdContextKey).(string); ok {
+		logger = logger.With(RequestIdKey, id)
+	}
+	if id := tracing.TraceId(ctx); id != "" {
+		logger = logger.With(TraceIdKey, id)
+	}
+
+	return logger
+}
+
+/**
+Get logger without correlation ids
+
+@return *slog.Logger
+*/
+func Default() *slog.Logger {
+	return base.Load()
+}
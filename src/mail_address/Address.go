@@ -0,0 +1,408 @@
+package mail_address
+
+import (
+	"mime"
+	"strings"
+	"unicode/utf8"
+)
+
+// Length limits of RFC 5321 section 4.5.3.1
+const (
+	maxLocalLength   = 64
+	maxDomainLength  = 253
+	maxLabelLength   = 63
+	maxAddressLength = 254
+)
+
+// Parts of address which are reported by errors
+const (
+	PartAddress     = "address"
+	PartLocal       = "local part"
+	PartDomain      = "domain"
+	PartDisplayName = "display name"
+)
+
+// Address is a parsed mailbox, e.g. "Name" <local@domain>
+type Address struct {
+	// decoded display name, empty if absent
+	Name string
+	// local part without quotes, it may contain UTF-8 characters
+	Local string
+	// ASCII domain with punycode labels or address literal, e.g. [127.0.0.1]
+	Domain string
+}
+
+// Error of parsing which names the invalid part of address
+type Error struct {
+	Part   string
+	Reason string
+}
+
+func (e *Error) Error() string {
+	return e.Part + " " + e.Reason
+}
+
+/**
+Parse RFC 5322 mailbox: addr-spec with optional display name, e.g. "Name" <local@domain>
+The whole value has to be a single mailbox, surrounding whitespace is ignored
+@params value string
+
+@return *Address, error - error is *Error
+*/
+func Parse(value string) (*Address, error) {
+	value = strings.TrimSpace(value)
+	if !strings.HasSuffix(value, ">") {
+		return ParseAddress(value)
+	}
+
+	open := unquotedIndex(value, '<')
+	if open < 0 {
+		return nil, &Error{PartAddress, "has > without <"}
+	}
+	name, err := parseDisplayName(strings.TrimSpace(value[:open]))
+	if err != nil {
+		return nil, err
+	}
+	address, err := ParseAddress(value[open+1 : len(value)-1])
+	if err != nil {
+		return nil, err
+	}
+	address.Name = name
+
+	return address, nil
+}
+
+/**
+Parse RFC 5321 mailbox without display name, e.g. local@domain or "quoted local"@domain
+Non-ASCII local part is allowed as in SMTPUTF8, non-ASCII domain is converted to punycode
+@params value string
+
+@return *Address, error - error is *Error
+*/
+func ParseAddress(value string) (*Address, error) {
+	if value == "" {
+		return nil, &Error{PartAddress, "is empty"}
+	}
+
+	local, rest, err := parseLocal(value)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(rest, "@") {
+		return nil, &Error{PartAddress, "has no @ after local part"}
+	}
+
+	return newAddress(local, rest[1:])
+}
+
+/**
+Parse path of SMTP command which is already decoded by SMTP server, quotes of local part are removed
+Local part is taken as is, domain is validated
+@params value string
+
+@return *Address, error - error is *Error
+*/
+func ParsePath(value string) (*Address, error) {
+	at := strings.LastIndex(value, "@")
+	if at < 0 {
+		return nil, &Error{PartAddress, "has no @"}
+	}
+	local := value[:at]
+	if local == "" {
+		return nil, &Error{PartLocal, "is empty"}
+	}
+	for _, char := range local {
+		if char < ' ' || char == 0x7f || char == utf8.RuneError {
+			return nil, &Error{PartLocal, "contains control or invalid UTF-8 characters"}
+		}
+	}
+
+	return newAddress(local, value[at+1:])
+}
+
+/**
+Create address from validated local part and domain
+@params local string
+@params domain string
+
+@return *Address, error
+*/
+func newAddress(local string, domain string) (*Address, error) {
+	if len(local) > maxLocalLength {
+		return nil, &Error{PartLocal, "is longer than 64 octets"}
+	}
+	domain, err := parseDomain(domain)
+	if err != nil {
+		return nil, err
+	}
+
+	address := &Address{Local: local, Domain: domain}
+	if len(address.String()) > maxAddressLength {
+		return nil, &Error{PartAddress, "is longer than 254 octets"}
+	}
+
+	return address, nil
+}
+
+/**
+Format addr-spec, local part is quoted if it is not a dot-atom
+
+@return string
+*/
+func (a *Address) String() string {
+	if isDotAtom(a.Local) {
+		return a.Local + "@" + a.Domain
+	}
+
+	return quote(a.Local) + "@" + a.Domain
+}
+
+/**
+Format addr-spec with domain in Unicode form
+
+@return string
+*/
+func (a *Address) Unicode() string {
+	domain, err := unicodeDomain(a.Domain)
+	if err != nil {
+		return a.String()
+	}
+
+	return strings.TrimSuffix(a.String(), a.Domain) + domain
+}
+
+/**
+Format mailbox for header: display name is quoted or RFC 2047 encoded if needed
+
+@return string
+*/
+func (a *Address) Header() string {
+	if a.Name == "" {
+		return a.String()
+	}
+
+	name := a.Name
+	switch {
+	case !isASCII(name):
+		name = mime.QEncoding.Encode("utf-8", name)
+	case !isPhrase(name):
+		name = quote(name)
+	}
+
+	return name + " <" + a.String() + ">"
+}
+
+/**
+Check if address can be sent only to servers with SMTPUTF8 extension
+
+@return bool
+*/
+func (a *Address) RequiresSMTPUTF8() bool {
+	return !isASCII(a.Local)
+}
+
+/**
+Parse local part at start of value
+@params value string
+
+@return string, string, error - unquoted local part and the rest of value
+*/
+func parseLocal(value string) (string, string, error) {
+	if !strings.HasPrefix(value, `"`) {
+		at := strings.LastIndex(value, "@")
+		if at < 0 {
+			at = len(value)
+		}
+		local := value[:at]
+		switch {
+		case local == "":
+			return "", "", &Error{PartLocal, "is empty"}
+		case strings.HasPrefix(local, ".") || strings.HasSuffix(local, ".") || strings.Contains(local, ".."):
+			return "", "", &Error{PartLocal, "has dot at start, at end or twice in a row"}
+		case !isDotAtom(local):
+			return "", "", &Error{PartLocal, "contains characters which are allowed only inside quotes"}
+		}
+		return local, value[at:], nil
+	}
+
+	local, rest, reason := unquote(value)
+	switch {
+	case reason != "":
+		return "", "", &Error{PartLocal, reason}
+	case local == "":
+		return "", "", &Error{PartLocal, "is empty"}
+	}
+
+	return local, rest, nil
+}
+
+/**
+Parse quoted string at start of value, quoted pairs are unescaped
+@params value string - value which starts with quote
+
+@return string, string, string - content, the rest of value and reason why string is invalid
+*/
+func unquote(value string) (string, string, string) {
+	var content strings.Builder
+	for i := 1; i < len(value); {
+		char, size := utf8.DecodeRuneInString(value[i:])
+		switch {
+		case char == '"':
+			return content.String(), value[i+1:], ""
+		case char == '\\' && i+1 < len(value):
+			// quoted pair
+			char, size = utf8.DecodeRuneInString(value[i+1:])
+			size++
+		}
+		if char == utf8.RuneError || (char < ' ' && char != '\t') || char == 0x7f {
+			return "", "", "contains control or invalid UTF-8 characters"
+		}
+		content.WriteRune(char)
+		i += size
+	}
+
+	return "", "", "has no closing quote"
+}
+
+/**
+Parse display name: quoted string or words, RFC 2047 encoded words are decoded
+@params value string
+
+@return string, error
+*/
+func parseDisplayName(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+
+	if strings.HasPrefix(value, `"`) {
+		name, rest, reason := unquote(value)
+		if reason != "" {
+			return "", &Error{PartDisplayName, reason}
+		}
+		if strings.TrimSpace(rest) != "" {
+			return "", &Error{PartDisplayName, "has characters after closing quote"}
+		}
+		return decodeWords(name), nil
+	}
+
+	if !isPhrase(value) {
+		return "", &Error{PartDisplayName, "contains special characters which are allowed only inside quotes"}
+	}
+
+	return decodeWords(strings.Join(strings.Fields(value), " ")), nil
+}
+
+/**
+Decode RFC 2047 encoded words, value is returned as is if it can't be decoded
+@params value string
+
+@return string
+*/
+func decodeWords(value string) string {
+	decoded, err := new(mime.WordDecoder).DecodeHeader(value)
+	if err != nil {
+		return value
+	}
+
+	return decoded
+}
+
+/**
+Find character outside of quoted strings
+@params value string
+@params char byte
+
+@return int - -1 if absent
+*/
+func unquotedIndex(value string, char byte) int {
+	quoted := false
+	for i := 0; i < len(value); i++ {
+		switch {
+		case value[i] == '\\' && quoted:
+			i++
+		case value[i] == '"':
+			quoted = !quoted
+		case value[i] == char && !quoted:
+			return i
+		}
+	}
+
+	return -1
+}
+
+/**
+Check if value is dot-atom of RFC 5322 with UTF-8 characters of RFC 6532
+
+@return bool
+*/
+func isDotAtom(value string) bool {
+	if value == "" || strings.HasPrefix(value, ".") || strings.HasSuffix(value, ".") || strings.Contains(value, "..") {
+		return false
+	}
+	for _, char := range value {
+		if char != '.' && !isAtext(char) {
+			return false
+		}
+	}
+
+	return true
+}
+
+/**
+Check if value is phrase of atoms, dots and whitespace, e.g. John Q. Public
+
+@return bool
+*/
+func isPhrase(value string) bool {
+	for _, char := range value {
+		if char != '.' && char != ' ' && char != '\t' && !isAtext(char) {
+			return false
+		}
+	}
+
+	return true
+}
+
+/**
+Check if character is atext of RFC 5322, non-ASCII characters are allowed by RFC 6532
+@params char rune
+
+@return bool
+*/
+func isAtext(char rune) bool {
+	switch {
+	case char >= 'a' && char <= 'z', char >= 'A' && char <= 'Z', char >= '0' && char <= '9':
+		return true
+	case char >= utf8.RuneSelf:
+		return char != utf8.RuneError
+	}
+
+	return strings.ContainsRune("!#$%&'*+-/=?^_`{|}~", char)
+}
+
+/**
+Quote value, quotes and backslashes are escaped
+@params value string
+
+@return string
+*/
+func quote(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
+
+/**
+Check if value contains only ASCII characters
+@params value string
+
+@return bool
+*/
+func isASCII(value string) bool {
+	for i := 0; i < len(value); i++ {
+		if value[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,116 @@
+package mail_address
+
+import (
+	"net"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+/**
+Validate domain or address literal, non-ASCII labels are converted to punycode
+Letter case of ASCII labels is kept
+@params domain string
+
+@return string, error
+*/
+func parseDomain(domain string) (string, error) {
+	if domain == "" {
+		return "", &Error{PartDomain, "is empty"}
+	}
+	if strings.HasPrefix(domain, "[") {
+		return parseLiteral(domain)
+	}
+	if strings.HasSuffix(domain, ".") || strings.HasPrefix(domain, ".") || strings.Contains(domain, "..") {
+		return "", &Error{PartDomain, "has empty label"}
+	}
+
+	labels := strings.Split(domain, ".")
+	for i, label := range labels {
+		if !isASCII(label) {
+			converted, err := idna.Lookup.ToASCII(label)
+			if err != nil {
+				return "", &Error{PartDomain, "label " + label + " is not a valid internationalized label"}
+			}
+			label = converted
+			labels[i] = label
+		}
+		if len(label) > maxLabelLength {
+			return "", &Error{PartDomain, "label is longer than 63 octets"}
+		}
+		if strings.HasPrefix(label, "-") || strings.HasSuffix(label, "-") {
+			return "", &Error{PartDomain, "label " + label + " starts or ends with hyphen"}
+		}
+		for _, char := range label {
+			if !(char >= 'a' && char <= 'z' || char >= 'A' && char <= 'Z' || char >= '0' && char <= '9' || char == '-') {
+				return "", &Error{PartDomain, "label " + label + " contains characters other than letters, digits and hyphen"}
+			}
+		}
+		// punycode label has to decode
+		if strings.HasPrefix(strings.ToLower(label), "xn--") {
+			if _, err := idna.Lookup.ToUnicode(label); err != nil {
+				return "", &Error{PartDomain, "label " + label + " is not valid punycode"}
+			}
+		}
+	}
+
+	domain = strings.Join(labels, ".")
+	if len(domain) > maxDomainLength {
+		return "", &Error{PartDomain, "is longer than 253 octets"}
+	}
+
+	return domain, nil
+}
+
+/**
+Validate address literal of RFC 5321 section 4.1.3, e.g. [192.0.2.1] or [IPv6:2001:db8::1]
+@params domain string
+
+@return string, error
+*/
+func parseLiteral(domain string) (string, error) {
+	if !strings.HasSuffix(domain, "]") {
+		return "", &Error{PartDomain, "literal has no closing bracket"}
+	}
+
+	literal := domain[1 : len(domain)-1]
+	if strings.HasPrefix(strings.ToLower(literal), "ipv6:") {
+		ip := net.ParseIP(literal[5:])
+		if ip == nil || ip.To4() != nil && !strings.Contains(literal[5:], ":") {
+			return "", &Error{PartDomain, "literal is not a valid IPv6 address"}
+		}
+		return "[IPv6:" + ip.String() + "]", nil
+	}
+
+	ip := net.ParseIP(literal)
+	if ip == nil || ip.To4() == nil || strings.Contains(literal, ":") {
+		return "", &Error{PartDomain, "literal is not a valid IPv4 address"}
+	}
+
+	return "[" + ip.String() + "]", nil
+}
+
+/**
+Convert punycode labels of domain to Unicode, address literal is kept
+@params domain string
+
+@return string, error
+*/
+func unicodeDomain(domain string) (string, error) {
+	if strings.HasPrefix(domain, "[") {
+		return domain, nil
+	}
+
+	labels := strings.Split(domain, ".")
+	for i, label := range labels {
+		if strings.HasPrefix(strings.ToLower(label), "xn--") {
+			converted, err := idna.Lookup.ToUnicode(label)
+			if err != nil {
+				return "", err
+			}
+			labels[i] = converted
+		}
+	}
+
+	return strings.Join(labels, "."), nil
+}
@@ -0,0 +1,107 @@
+package mail_auth
+
+import (
+	"bytes"
+	"context"
+	"models"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-msgauth/dkim"
+)
+
+// Time limit of key lookups of single message
+const lookupTimeout = 5 * time.Second
+
+// Signatures over this count are not verified
+const maxSignatures = 10
+
+/**
+Verify every DKIM-Signature header of message, public keys are resolved by resolver
+@params ctx context.Context
+@params resolver Resolver
+@params raw []byte - RFC 5322 message
+
+@return []models.DkimResult - empty if message is not signed
+*/
+func VerifyDkim(ctx context.Context, resolver Resolver, raw []byte) []models.DkimResult {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil
+	}
+	// selectors are not reported by verifier, verifications are in the order of headers
+	signatures := msg.Header["Dkim-Signature"]
+	if len(signatures) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, lookupTimeout)
+	defer cancel()
+
+	verifications, err := dkim.VerifyWithOptions(bytes.NewReader(raw), &dkim.VerifyOptions{
+		LookupTXT: func(name string) ([]string, error) {
+			return resolver.LookupTXT(ctx, name)
+		},
+		MaxVerifications: maxSignatures,
+	})
+	if err != nil && err != dkim.ErrTooManySignatures {
+		return []models.DkimResult{{Result: models.AuthPermError, Reason: err.Error()}}
+	}
+
+	results := make([]models.DkimResult, len(verifications))
+	for i, verification := range verifications {
+		tags := signatureTags(signatures[i])
+		results[i] = models.DkimResult{
+			Result:     dkimResult(verification.Err),
+			Domain:     verification.Domain,
+			Selector:   tags["s"],
+			Identifier: verification.Identifier,
+		}
+		if verification.Err != nil {
+			results[i].Reason = verification.Err.Error()
+		}
+		if results[i].Domain == "" {
+			results[i].Domain = tags["d"]
+		}
+	}
+
+	return results
+}
+
+/**
+Convert error of verification to result name
+@params err error
+
+@return string
+*/
+func dkimResult(err error) string {
+	switch {
+	case err == nil:
+		return models.AuthPass
+	case dkim.IsTempFail(err):
+		return models.AuthTempError
+	case dkim.IsPermFail(err):
+		return models.AuthPermError
+	}
+
+	return models.AuthFail
+}
+
+/**
+Parse tags of DKIM-Signature header, e.g. d=example.com; s=selector
+@params value string
+
+@return map[string]string
+*/
+func signatureTags(value string) map[string]string {
+	tags := map[string]string{}
+	for _, tag := range strings.Split(value, ";") {
+		name, value, ok := strings.Cut(tag, "=")
+		if ok {
+			tags[strings.TrimSpace(name)] = strings.Join(strings.Fields(value), "")
+		}
+	}
+
+	return tags
+}
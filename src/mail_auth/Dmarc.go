@@ -0,0 +1,138 @@
+package mail_auth
+
+import (
+	"context"
+	"models"
+	"net/mail"
+	"strings"
+
+	"github.com/emersion/go-msgauth/dmarc"
+	"golang.org/x/net/publicsuffix"
+)
+
+/**
+Evaluate DMARC policy of From header domain, policy is only reported and never enforced
+@params ctx context.Context
+@params resolver Resolver
+@params from string - value of From header
+@params spf models.SpfResult
+@params dkim []models.DkimResult
+
+@return models.DmarcResult
+*/
+func CheckDmarc(ctx context.Context, resolver Resolver, from string, spf models.SpfResult, dkim []models.DkimResult) models.DmarcResult {
+	domain, reason := fromDomain(from)
+	result := models.DmarcResult{Domain: domain}
+	if domain == "" {
+		result.Result, result.Reason = models.AuthPermError, reason
+		return result
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, lookupTimeout)
+	defer cancel()
+	options := &dmarc.LookupOptions{LookupTXT: func(name string) ([]string, error) {
+		return resolver.LookupTXT(ctx, name)
+	}}
+
+	// policy of organizational domain is applied to its subdomains
+	organization := organizationalDomain(domain)
+	record, err := dmarc.LookupWithOptions(domain, options)
+	policy := dmarc.Policy("")
+	if err == nil {
+		policy = record.Policy
+	} else if err == dmarc.ErrNoPolicy && organization != domain {
+		record, err = dmarc.LookupWithOptions(organization, options)
+		if err == nil {
+			policy = record.SubdomainPolicy
+			if policy == "" {
+				policy = record.Policy
+			}
+		}
+	}
+
+	switch {
+	case err == dmarc.ErrNoPolicy:
+		result.Result, result.Reason = models.AuthNone, "no DMARC record of "+domain
+		return result
+	case dmarc.IsTempFail(err):
+		result.Result, result.Reason = models.AuthTempError, err.Error()
+		return result
+	case err != nil:
+		result.Result, result.Reason = models.AuthPermError, err.Error()
+		return result
+	}
+	result.Policy = string(policy)
+
+	result.SpfAligned = spf.Result == models.AuthPass && aligned(spf.Domain, domain, record.SPFAlignment)
+	for _, signature := range dkim {
+		if signature.Result == models.AuthPass && aligned(signature.Domain, domain, record.DKIMAlignment) {
+			result.DkimAligned = true
+		}
+	}
+
+	result.Result = models.AuthPass
+	if !result.SpfAligned && !result.DkimAligned {
+		result.Result, result.Reason = models.AuthFail, "neither SPF nor DKIM result is aligned with "+domain
+	}
+
+	return result
+}
+
+/**
+Get domain of single author of From header
+@params from string
+
+@return string, string - lowercase domain or reason why it can't be used
+*/
+func fromDomain(from string) (string, string) {
+	addresses, err := mail.ParseAddressList(from)
+	if err != nil || len(addresses) == 0 {
+		return "", "From header has no valid address"
+	}
+
+	domain := ""
+	for _, address := range addresses {
+		current := normalizeName(address.Address[strings.LastIndex(address.Address, "@")+1:])
+		if domain != "" && current != domain {
+			return "", "From header has addresses of different domains"
+		}
+		domain = current
+	}
+
+	return domain, ""
+}
+
+/**
+Check identifier alignment of RFC 7489 section 3.1
+@params domain string - authenticated domain
+@params from string - domain of From header
+@params mode dmarc.AlignmentMode - strict requires equal domains, relaxed equal organizational domains
+
+@return bool
+*/
+func aligned(domain string, from string, mode dmarc.AlignmentMode) bool {
+	domain = normalizeName(domain)
+	if domain == "" {
+		return false
+	}
+	if mode == dmarc.AlignmentStrict {
+		return domain == from
+	}
+
+	return organizationalDomain(domain) == organizationalDomain(from)
+}
+
+/**
+Get registered domain under public suffix, e.g. some.domain for mail.some.domain
+@params domain string
+
+@return string
+*/
+func organizationalDomain(domain string) string {
+	organization, err := publicsuffix.EffectiveTLDPlusOne(domain)
+	if err != nil {
+		return domain
+	}
+
+	return organization
+}
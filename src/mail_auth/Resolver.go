@@ -0,0 +1,253 @@
+package mail_auth
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Source of DNS records which are used by authentication checks
+type Resolver interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+	// network is "ip" for both families, "ip4" for A or "ip6" for AAAA records
+	LookupIP(ctx context.Context, network string, host string) ([]net.IP, error)
+	LookupMX(ctx context.Context, name string) ([]*net.MX, error)
+}
+
+// Resolver which queries DNS servers of system
+type dnsResolver struct {
+	resolver *net.Resolver
+}
+
+/**
+Create resolver which queries DNS servers of system
+
+@return Resolver
+*/
+func DNS() Resolver {
+	return &dnsResolver{resolver: net.DefaultResolver}
+}
+
+func (r *dnsResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	return r.resolver.LookupTXT(ctx, name)
+}
+
+func (r *dnsResolver) LookupIP(ctx context.Context, network string, host string) ([]net.IP, error) {
+	return r.resolver.LookupIP(ctx, network, host)
+}
+
+func (r *dnsResolver) LookupMX(ctx context.Context, name string) ([]*net.MX, error) {
+	return r.resolver.LookupMX(ctx, name)
+}
+
+/**
+Create resolver from environment: static zone of MAIL_AUTH_ZONE_FILE if it is set, DNS otherwise
+
+@return Resolver, error
+*/
+func FromEnv() (Resolver, error) {
+	path := os.Getenv("MAIL_AUTH_ZONE_FILE")
+	if path == "" {
+		return DNS(), nil
+	}
+
+	return LoadZone(path)
+}
+
+// Static DNS records, e.g. public DKIM keys and SPF policies of test domains
+type Zone struct {
+	mutex sync.RWMutex
+	// records by type and lowercase name without trailing dot
+	records map[string]map[string][]string
+}
+
+/**
+Create empty zone
+
+@return *Zone
+*/
+func NewZone() *Zone {
+	return &Zone{records: map[string]map[string][]string{}}
+}
+
+/**
+Read zone from file, every line is a record: NAME TYPE VALUE
+Quoted TXT strings of one record are joined, lines starting with ; or # are comments
+@params path string
+
+@return *Zone, error
+*/
+func LoadZone(path string) (*Zone, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	zone := NewZone()
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for number := 1; scanner.Scan(); number++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, ";") || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("%s:%d: record has to contain name, type and value", path, number)
+		}
+
+		// value starts after type, it may contain spaces
+		value := strings.TrimSpace(line[len(fields[0]):])
+		value = strings.TrimSpace(value[len(fields[1]):])
+		if strings.EqualFold(fields[1], "TXT") {
+			value = joinQuoted(value)
+		}
+		zone.Add(fields[0], fields[1], value)
+	}
+
+	return zone, scanner.Err()
+}
+
+/**
+Add record to zone
+@params name string - domain name, trailing dot is optional
+@params kind string - type of record: TXT, A, AAAA or MX
+@params value string - value of record, TXT value without quotes, MX value is preference and host
+
+@return *Zone
+*/
+func (z *Zone) Add(name string, kind string, value string) *Zone {
+	z.mutex.Lock()
+	defer z.mutex.Unlock()
+
+	kind = strings.ToUpper(kind)
+	if z.records[kind] == nil {
+		z.records[kind] = map[string][]string{}
+	}
+	name = normalizeName(name)
+	z.records[kind][name] = append(z.records[kind][name], value)
+
+	return z
+}
+
+func (z *Zone) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	return z.lookup("TXT", name)
+}
+
+func (z *Zone) LookupIP(ctx context.Context, network string, host string) ([]net.IP, error) {
+	kinds := map[string][]string{"ip": {"A", "AAAA"}, "ip4": {"A"}, "ip6": {"AAAA"}}[network]
+	if kinds == nil {
+		return nil, &net.DNSError{Err: "unsupported network " + network, Name: host}
+	}
+
+	ips := []net.IP{}
+	found := false
+	for _, kind := range kinds {
+		values, err := z.lookup(kind, host)
+		if err != nil {
+			continue
+		}
+		found = true
+		for _, value := range values {
+			if ip := net.ParseIP(value); ip != nil {
+				ips = append(ips, ip)
+			}
+		}
+	}
+	if !found {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+
+	return ips, nil
+}
+
+func (z *Zone) LookupMX(ctx context.Context, name string) ([]*net.MX, error) {
+	values, err := z.lookup("MX", name)
+	if err != nil {
+		return nil, err
+	}
+
+	records := []*net.MX{}
+	for _, value := range values {
+		fields := strings.Fields(value)
+		if len(fields) != 2 {
+			continue
+		}
+		preference, err := strconv.ParseUint(fields[0], 10, 16)
+		if err != nil {
+			continue
+		}
+		records = append(records, &net.MX{Host: fields[1], Pref: uint16(preference)})
+	}
+	sort.SliceStable(records, func(i, j int) bool { return records[i].Pref < records[j].Pref })
+
+	return records, nil
+}
+
+/**
+Get records of type, absent name is reported like missing DNS name
+@params kind string
+@params name string
+
+@return []string, error
+*/
+func (z *Zone) lookup(kind string, name string) ([]string, error) {
+	z.mutex.RLock()
+	defer z.mutex.RUnlock()
+
+	values, ok := z.records[kind][normalizeName(name)]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+	}
+
+	return append([]string(nil), values...), nil
+}
+
+/**
+Convert domain name to the form of zone keys
+@params name string
+
+@return string
+*/
+func normalizeName(name string) string {
+	return strings.TrimSuffix(strings.ToLower(name), ".")
+}
+
+/**
+Join quoted strings of TXT record, unquoted value is returned as is
+@params value string
+
+@return string
+*/
+func joinQuoted(value string) string {
+	if !strings.HasPrefix(value, `"`) {
+		return value
+	}
+
+	var joined strings.Builder
+	quoted := false
+	escaped := false
+	for _, char := range value {
+		switch {
+		case escaped:
+			joined.WriteRune(char)
+			escaped = false
+		case char == '\\' && quoted:
+			escaped = true
+		case char == '"':
+			quoted = !quoted
+		case quoted:
+			joined.WriteRune(char)
+		}
+	}
+
+	return joined.String()
+}
@@ -0,0 +1,46 @@
+package mail_auth
+
+import (
+	"models"
+
+	"github.com/emersion/go-msgauth/authres"
+)
+
+/**
+Format Authentication-Results header of results, nil results are skipped
+@params authServId string - host name of this server
+@params dkim []models.DkimResult
+@params spf *models.SpfResult
+@params dmarc *models.DmarcResult
+
+@return string - header field with CRLF
+*/
+func ResultsHeader(authServId string, dkim []models.DkimResult, spf *models.SpfResult, dmarcResult *models.DmarcResult) string {
+	results := []authres.Result{}
+	for _, signature := range dkim {
+		results = append(results, &authres.DKIMResult{
+			Value:      authres.ResultValue(signature.Result),
+			Reason:     signature.Reason,
+			Domain:     signature.Domain,
+			Identifier: signature.Identifier,
+		})
+	}
+	if spf != nil {
+		result := &authres.SPFResult{Value: authres.ResultValue(spf.Result), Reason: spf.Reason}
+		if spf.Identity == IdentityHelo {
+			result.Helo = spf.Domain
+		} else {
+			result.From = spf.Domain
+		}
+		results = append(results, result)
+	}
+	if dmarcResult != nil {
+		results = append(results, &authres.DMARCResult{
+			Value:  authres.ResultValue(dmarcResult.Result),
+			Reason: dmarcResult.Reason,
+			From:   dmarcResult.Domain,
+		})
+	}
+
+	return "Authentication-Results: " + authres.Format(authServId, results) + "\r\n"
+}
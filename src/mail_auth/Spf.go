@@ -0,0 +1,599 @@
+package mail_auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"models"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Limits of RFC 7208 section 4.6.4
+const (
+	maxSpfLookups     = 10
+	maxSpfVoidLookups = 2
+	maxSpfMxHosts     = 10
+)
+
+// Identities of SPF check
+const (
+	IdentityMailFrom = "mailfrom"
+	IdentityHelo     = "helo"
+)
+
+// Result of SPF record which stops evaluation
+type spfError struct {
+	result string
+	reason string
+}
+
+func (e *spfError) Error() string {
+	return e.result + ": " + e.reason
+}
+
+// State of single SPF check, lookups are counted across included records
+type spfCheck struct {
+	ctx      context.Context
+	resolver Resolver
+	ip       net.IP
+	sender   string
+	helo     string
+	lookups  int
+	voids    int
+}
+
+/**
+Check SPF policy of sender domain for client IP, HELO name is checked if envelope sender is null
+Mechanism ptr is not supported and never matches, exp modifier is ignored
+@params ctx context.Context
+@params resolver Resolver
+@params ip net.IP - address of SMTP client
+@params helo string - HELO name of SMTP client
+@params sender string - envelope sender, empty for bounces
+
+@return models.SpfResult
+*/
+func CheckSpf(ctx context.Context, resolver Resolver, ip net.IP, helo string, sender string) models.SpfResult {
+	identity := IdentityMailFrom
+	if sender == "" {
+		identity = IdentityHelo
+		sender = "postmaster@" + helo
+	}
+	domain := normalizeName(sender[strings.LastIndex(sender, "@")+1:])
+	if !strings.Contains(sender, "@") {
+		sender = "postmaster@" + domain
+	}
+
+	result := models.SpfResult{Domain: domain, Identity: identity}
+	if ip == nil {
+		result.Result, result.Reason = models.AuthNone, "client IP is unknown"
+		return result
+	}
+	result.ClientIP = ip.String()
+	if domain == "" || !strings.Contains(domain, ".") {
+		result.Result, result.Reason = models.AuthNone, "sender domain is not a fully qualified domain name"
+		return result
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, lookupTimeout)
+	defer cancel()
+
+	check := &spfCheck{ctx: ctx, resolver: resolver, ip: ip, sender: sender, helo: helo}
+	result.Result, result.Reason = check.evaluate(domain)
+
+	return result
+}
+
+/**
+Evaluate SPF record of domain
+@params domain string
+
+@return string, string - result and reason
+*/
+func (c *spfCheck) evaluate(domain string) (string, string) {
+	record, err := c.record(domain)
+	if err != nil {
+		return spfFailure(err)
+	}
+
+	redirect := ""
+	for _, term := range strings.Fields(record)[1:] {
+		name, value, isModifier := modifier(term)
+		if isModifier {
+			if name == "redirect" {
+				redirect = value
+			}
+			continue
+		}
+
+		qualifier := models.AuthPass
+		switch term[0] {
+		case '+', '-', '~', '?':
+			qualifier = map[byte]string{'+': models.AuthPass, '-': models.AuthFail, '~': models.AuthSoftFail, '?': models.AuthNeutral}[term[0]]
+			term = term[1:]
+		}
+
+		matched, err := c.match(domain, term)
+		if err != nil {
+			return spfFailure(err)
+		}
+		if matched {
+			return qualifier, fmt.Sprintf("%s matched %s of %s", c.ip, term, domain)
+		}
+	}
+
+	if redirect != "" {
+		target, err := c.expand(redirect, domain)
+		if err == nil {
+			err = c.count()
+		}
+		if err != nil {
+			return spfFailure(err)
+		}
+		result, reason := c.evaluate(target)
+		if result == models.AuthNone {
+			return models.AuthPermError, "redirect to domain without SPF record: " + target
+		}
+		return result, reason
+	}
+
+	return models.AuthNeutral, fmt.Sprintf("no mechanism of %s matched %s", domain, c.ip)
+}
+
+/**
+Find single SPF record of domain
+@params domain string
+
+@return string, error
+*/
+func (c *spfCheck) record(domain string) (string, error) {
+	values, err := c.resolver.LookupTXT(c.ctx, domain)
+	if err != nil && !isNotFound(err) {
+		return "", &spfError{models.AuthTempError, "TXT lookup failed: " + err.Error()}
+	}
+
+	records := []string{}
+	for _, value := range values {
+		if lower := strings.ToLower(value); lower == "v=spf1" || strings.HasPrefix(lower, "v=spf1 ") {
+			records = append(records, value)
+		}
+	}
+	switch len(records) {
+	case 0:
+		return "", &spfError{models.AuthNone, "no SPF record of " + domain}
+	case 1:
+		return records[0], nil
+	}
+
+	return "", &spfError{models.AuthPermError, "multiple SPF records of " + domain}
+}
+
+/**
+Check if mechanism matches client IP
+@params domain string - domain of current record
+@params mechanism string - mechanism without qualifier
+
+@return bool, error
+*/
+func (c *spfCheck) match(domain string, mechanism string) (bool, error) {
+	name, argument := mechanism, ""
+	if index := strings.IndexAny(mechanism, ":/"); index >= 0 {
+		name, argument = mechanism[:index], mechanism[index:]
+	}
+	name = strings.ToLower(name)
+
+	switch name {
+	case "all":
+		return argument == "", syntaxError(argument != "", mechanism)
+	case "ip4", "ip6":
+		return c.matchNetwork(name, argument, mechanism)
+	case "include", "exists":
+		if !strings.HasPrefix(argument, ":") {
+			return false, syntaxError(true, mechanism)
+		}
+		if err := c.count(); err != nil {
+			return false, err
+		}
+		target, err := c.expand(argument[1:], domain)
+		if err != nil {
+			return false, err
+		}
+		if name == "exists" {
+			ips, err := c.lookupIP("ip4", target)
+			return len(ips) > 0, err
+		}
+		return c.include(target)
+	case "a", "mx":
+		if err := c.count(); err != nil {
+			return false, err
+		}
+		target, prefix4, prefix6, err := c.targetNetwork(argument, domain, mechanism)
+		if err != nil {
+			return false, err
+		}
+		hosts := []string{target}
+		if name == "mx" {
+			if hosts, err = c.lookupMX(target); err != nil {
+				return false, err
+			}
+		}
+		for _, host := range hosts {
+			ips, err := c.lookupIP("ip", host)
+			if err != nil {
+				return false, err
+			}
+			for _, ip := range ips {
+				if containsIP(ip, prefix4, prefix6, c.ip) {
+					return true, nil
+				}
+			}
+		}
+		return false, nil
+	case "ptr":
+		// deprecated by RFC 7208, lookup is counted but never matches
+		return false, c.count()
+	}
+
+	return false, &spfError{models.AuthPermError, "unknown mechanism " + mechanism}
+}
+
+/**
+Evaluate included record, only its pass is a match
+@params domain string
+
+@return bool, error
+*/
+func (c *spfCheck) include(domain string) (bool, error) {
+	result, reason := c.evaluate(domain)
+	switch result {
+	case models.AuthPass:
+		return true, nil
+	case models.AuthTempError:
+		return false, &spfError{result, reason}
+	case models.AuthPermError, models.AuthNone:
+		return false, &spfError{models.AuthPermError, "include of " + domain + ": " + reason}
+	}
+
+	return false, nil
+}
+
+/**
+Check ip4 and ip6 mechanisms
+@params name string - ip4 or ip6
+@params argument string - network with optional prefix length
+@params mechanism string
+
+@return bool, error
+*/
+func (c *spfCheck) matchNetwork(name string, argument string, mechanism string) (bool, error) {
+	if !strings.HasPrefix(argument, ":") {
+		return false, syntaxError(true, mechanism)
+	}
+	address, length, hasLength := strings.Cut(argument[1:], "/")
+	ip := net.ParseIP(address)
+	if ip == nil || (name == "ip4") != (ip.To4() != nil) {
+		return false, syntaxError(true, mechanism)
+	}
+
+	bits := 8 * len(ip.To16())
+	if name == "ip4" {
+		ip, bits = ip.To4(), 32
+	}
+	prefix := bits
+	if hasLength {
+		parsed, err := strconv.Atoi(length)
+		if err != nil || parsed < 0 || parsed > bits {
+			return false, syntaxError(true, mechanism)
+		}
+		prefix = parsed
+	}
+
+	network := &net.IPNet{IP: ip, Mask: net.CIDRMask(prefix, bits)}
+	return (name == "ip4") == (c.ip.To4() != nil) && network.Contains(c.ip), nil
+}
+
+/**
+Parse domain and prefix lengths of a and mx mechanisms, e.g. :mail.some.domain/24//64
+@params argument string
+@params domain string - domain of current record, it is used if argument has no domain
+@params mechanism string
+
+@return string, int, int, error - target domain, prefix lengths of IPv4 and IPv6 networks
+*/
+func (c *spfCheck) targetNetwork(argument string, domain string, mechanism string) (string, int, int, error) {
+	prefix4, prefix6 := 32, 128
+	if index := strings.Index(argument, "/"); index >= 0 {
+		lengths := strings.SplitN(argument[index+1:], "//", 2)
+		argument = argument[:index]
+		var err error
+		if strings.HasPrefix(lengths[0], "/") {
+			// only IPv6 prefix: //64
+			lengths = []string{"", strings.TrimPrefix(lengths[0], "/")}
+		}
+		if lengths[0] != "" {
+			if prefix4, err = strconv.Atoi(lengths[0]); err != nil || prefix4 < 0 || prefix4 > 32 {
+				return "", 0, 0, syntaxError(true, mechanism)
+			}
+		}
+		if len(lengths) > 1 {
+			if prefix6, err = strconv.Atoi(lengths[1]); err != nil || prefix6 < 0 || prefix6 > 128 {
+				return "", 0, 0, syntaxError(true, mechanism)
+			}
+		}
+	}
+
+	if argument == "" {
+		return domain, prefix4, prefix6, nil
+	}
+	if !strings.HasPrefix(argument, ":") {
+		return "", 0, 0, syntaxError(true, mechanism)
+	}
+	target, err := c.expand(argument[1:], domain)
+
+	return target, prefix4, prefix6, err
+}
+
+/**
+Lookup addresses of host, missing host is a void lookup
+@params network string
+@params host string
+
+@return []net.IP, error
+*/
+func (c *spfCheck) lookupIP(network string, host string) ([]net.IP, error) {
+	ips, err := c.resolver.LookupIP(c.ctx, network, host)
+	if err != nil && !isNotFound(err) {
+		return nil, &spfError{models.AuthTempError, "address lookup failed: " + err.Error()}
+	}
+	if len(ips) == 0 {
+		return nil, c.void()
+	}
+
+	return ips, nil
+}
+
+/**
+Lookup mail exchangers of domain, missing domain is a void lookup
+@params domain string
+
+@return []string, error - hosts of exchangers
+*/
+func (c *spfCheck) lookupMX(domain string) ([]string, error) {
+	records, err := c.resolver.LookupMX(c.ctx, domain)
+	if err != nil && !isNotFound(err) {
+		return nil, &spfError{models.AuthTempError, "MX lookup failed: " + err.Error()}
+	}
+	if len(records) == 0 {
+		return nil, c.void()
+	}
+	if len(records) > maxSpfMxHosts {
+		return nil, &spfError{models.AuthPermError, "too many MX records of " + domain}
+	}
+
+	hosts := make([]string, len(records))
+	for i, record := range records {
+		hosts[i] = record.Host
+	}
+
+	return hosts, nil
+}
+
+/**
+Count DNS lookup of mechanism or modifier
+
+@return error - permerror if limit is exceeded
+*/
+func (c *spfCheck) count() error {
+	c.lookups++
+	if c.lookups > maxSpfLookups {
+		return &spfError{models.AuthPermError, "too many DNS lookups"}
+	}
+
+	return nil
+}
+
+/**
+Count lookup without records
+
+@return error - permerror if limit is exceeded
+*/
+func (c *spfCheck) void() error {
+	c.voids++
+	if c.voids > maxSpfVoidLookups {
+		return &spfError{models.AuthPermError, "too many void DNS lookups"}
+	}
+
+	return nil
+}
+
+/**
+Expand macros of domain spec, e.g. %{l}._spf.%{d}
+@params spec string
+@params domain string - domain of current record
+
+@return string, error
+*/
+func (c *spfCheck) expand(spec string, domain string) (string, error) {
+	if !strings.Contains(spec, "%") {
+		return normalizeName(spec), nil
+	}
+
+	local := c.sender[:strings.LastIndex(c.sender, "@")]
+	ip := c.ip.String()
+	if c.ip.To4() == nil {
+		// IPv6 is dot-separated nibbles
+		nibbles := []string{}
+		for _, b := range c.ip.To16() {
+			nibbles = append(nibbles, fmt.Sprintf("%x", b>>4), fmt.Sprintf("%x", b&0xf))
+		}
+		ip = strings.Join(nibbles, ".")
+	}
+	values := map[byte]string{
+		's': c.sender, 'l': local, 'o': c.sender[len(local)+1:], 'd': domain,
+		'i': ip, 'h': c.helo, 'v': map[bool]string{true: "in-addr", false: "ip6"}[c.ip.To4() != nil],
+	}
+
+	var expanded strings.Builder
+	for i := 0; i < len(spec); i++ {
+		if spec[i] != '%' {
+			expanded.WriteByte(spec[i])
+			continue
+		}
+		if i+1 >= len(spec) {
+			return "", &spfError{models.AuthPermError, "invalid macro in " + spec}
+		}
+		i++
+		switch spec[i] {
+		case '%':
+			expanded.WriteByte('%')
+			continue
+		case '_':
+			expanded.WriteByte(' ')
+			continue
+		case '-':
+			expanded.WriteString("%20")
+			continue
+		case '{':
+		default:
+			return "", &spfError{models.AuthPermError, "invalid macro in " + spec}
+		}
+
+		end := strings.IndexByte(spec[i:], '}')
+		if end < 2 {
+			return "", &spfError{models.AuthPermError, "invalid macro in " + spec}
+		}
+		macro := spec[i+1 : i+end]
+		i += end
+
+		value, ok := values[macro[0]|0x20]
+		if !ok {
+			return "", &spfError{models.AuthPermError, "invalid macro in " + spec}
+		}
+		value, err := transformMacro(value, macro[1:])
+		if err != nil {
+			return "", &spfError{models.AuthPermError, "invalid macro in " + spec}
+		}
+		expanded.WriteString(value)
+	}
+
+	return normalizeName(expanded.String()), nil
+}
+
+/**
+Apply transformers and delimiters of macro, e.g. 2r. keeps two labels of reversed value
+@params value string
+@params transformers string
+
+@return string, error
+*/
+func transformMacro(value string, transformers string) (string, error) {
+	digits := 0
+	for digits < len(transformers) && transformers[digits] >= '0' && transformers[digits] <= '9' {
+		digits++
+	}
+	keep := 0
+	if digits > 0 {
+		var err error
+		if keep, err = strconv.Atoi(transformers[:digits]); err != nil || keep == 0 {
+			return "", errors.New("invalid number of labels")
+		}
+	}
+	transformers = transformers[digits:]
+	reverse := strings.HasPrefix(strings.ToLower(transformers), "r")
+	if reverse {
+		transformers = transformers[1:]
+	}
+	delimiters := "."
+	if transformers != "" {
+		if strings.Trim(transformers, ".-+,/_=") != "" {
+			return "", errors.New("invalid delimiter")
+		}
+		delimiters = transformers
+	}
+
+	parts := strings.FieldsFunc(value, func(r rune) bool { return strings.ContainsRune(delimiters, r) })
+	if reverse {
+		for i, j := 0, len(parts)-1; i < j; i, j = i+1, j-1 {
+			parts[i], parts[j] = parts[j], parts[i]
+		}
+	}
+	if keep > 0 && keep < len(parts) {
+		parts = parts[len(parts)-keep:]
+	}
+
+	return strings.Join(parts, "."), nil
+}
+
+/**
+Check if client IP is inside network of resolved address
+@params ip net.IP - resolved address
+@params prefix4 int
+@params prefix6 int
+@params client net.IP
+
+@return bool
+*/
+func containsIP(ip net.IP, prefix4 int, prefix6 int, client net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		return client.To4() != nil && (&net.IPNet{IP: ip4, Mask: net.CIDRMask(prefix4, 32)}).Contains(client)
+	}
+
+	return client.To4() == nil && (&net.IPNet{IP: ip, Mask: net.CIDRMask(prefix6, 128)}).Contains(client)
+}
+
+/**
+Split modifier term, e.g. redirect=_spf.some.domain
+@params term string
+
+@return string, string, bool - lowercase name, value and whether term is a modifier
+*/
+func modifier(term string) (string, string, bool) {
+	name, value, ok := strings.Cut(term, "=")
+	if !ok || name == "" || strings.ContainsAny(name, ":/") {
+		return "", "", false
+	}
+
+	return strings.ToLower(name), value, true
+}
+
+/**
+Create permerror of invalid mechanism
+@params invalid bool
+@params mechanism string
+
+@return error - nil if mechanism is valid
+*/
+func syntaxError(invalid bool, mechanism string) error {
+	if !invalid {
+		return nil
+	}
+
+	return &spfError{models.AuthPermError, "invalid mechanism " + mechanism}
+}
+
+/**
+Convert error of evaluation to result and reason
+@params err error
+
+@return string, string
+*/
+func spfFailure(err error) (string, string) {
+	if failure, ok := err.(*spfError); ok {
+		return failure.result, failure.reason
+	}
+
+	return models.AuthTempError, err.Error()
+}
+
+/**
+Check if DNS error means the name has no records
+@params err error
+
+@return bool
+*/
+func isNotFound(err error) bool {
+	var dnsError *net.DNSError
+	return errors.As(err, &dnsError) && dnsError.IsNotFound
+}
@@ -0,0 +1,234 @@
+package mail_parser
+
+import (
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Parts of message where link or code is found
+const (
+	SourceSubject = "subject"
+	SourceText    = "text"
+	SourceHTML    = "html"
+)
+
+// Link found in message
+type Link struct {
+	URL string `json:"url"`
+	// anchor text, empty for links from text part
+	Text   string `json:"text"`
+	Source string `json:"source"`
+}
+
+// Verification code found in message
+type Code struct {
+	Code string `json:"code"`
+	// name of built-in pattern or regex of caller
+	Pattern string `json:"pattern"`
+	Source  string `json:"source"`
+}
+
+// Pattern of verification codes, code is the first group or the whole match if the group is empty
+type CodePattern struct {
+	Name  string
+	Regex *regexp.Regexp
+	// hyphens and spaces are removed from codes, separators of split codes are not typed by user
+	StripSeparators bool
+}
+
+// Built-in patterns in order of priority
+var BuiltinCodePatterns = []CodePattern{
+	// "Your verification code is 123456", "PIN: AB12CD"
+	{"keyword", regexp.MustCompile(`(?i:\b(?:code|otp|pin|passcode|password|verification|token)\b)\W{1,20}(?:(?i:is)\W{1,5})?\b([0-9]{4,8}|[A-Z0-9]{6,8})\b`), false},
+	// "123456"
+	{"six_digits", regexp.MustCompile(`\b[0-9]{6}\b`), false},
+	// "123-456", "123 456"
+	{"split_digits", regexp.MustCompile(`\b[0-9]{3}[- ][0-9]{3}\b`), true},
+}
+
+// Urls inside text part, punctuation at the end is removed separately
+var textUrl = regexp.MustCompile(`(?i)\bhttps?://[^\s<>"]+`)
+
+// Url schemes which are not links to follow
+var ignoredSchemes = []string{"javascript:", "data:", "cid:", "#"}
+
+/**
+Find links of text and HTML parts, HTML links have anchor text
+
+@return []Link
+*/
+func (m *Message) Links() []Link {
+	links := []Link{}
+	seen := map[Link]bool{}
+	add := func(link Link) {
+		if link.URL == "" || seen[link] {
+			return
+		}
+		for _, scheme := range ignoredSchemes {
+			if strings.HasPrefix(strings.ToLower(link.URL), scheme) {
+				return
+			}
+		}
+		seen[link] = true
+		links = append(links, link)
+	}
+
+	if m.HTML != "" {
+		htmlLinks(m.HTML, func(url string, text string) {
+			add(Link{URL: url, Text: text, Source: SourceHTML})
+		})
+	}
+
+	for _, url := range textUrl.FindAllString(m.Text, -1) {
+		add(Link{URL: strings.TrimRight(url, ".,;:!?)]}'"), Source: SourceText})
+	}
+
+	return links
+}
+
+/**
+Find verification codes in subject and body, text of HTML part is used if there is no text part
+@params patterns []CodePattern - patterns are applied in order, every code is returned once
+
+@return []Code
+*/
+func (m *Message) Codes(patterns []CodePattern) []Code {
+	body := m.Text
+	if strings.TrimSpace(body) == "" && m.HTML != "" {
+		body = htmlText(m.HTML)
+	}
+	sources := []struct{ name, text string }{{SourceSubject, m.Subject}, {SourceText, body}}
+
+	codes := []Code{}
+	seen := map[string]bool{}
+	for _, pattern := range patterns {
+		for _, source := range sources {
+			for _, match := range pattern.Regex.FindAllStringSubmatch(source.text, -1) {
+				code := match[0]
+				if len(match) > 1 && match[1] != "" {
+					code = match[1]
+				}
+				if pattern.StripSeparators {
+					code = strings.NewReplacer("-", "", " ", "").Replace(code)
+				}
+
+				if code == "" || seen[code] || !strings.ContainsAny(code, "0123456789") {
+					continue
+				}
+				seen[code] = true
+				codes = append(codes, Code{Code: code, Pattern: pattern.Name, Source: source.name})
+			}
+		}
+	}
+
+	return codes
+}
+
+/**
+Walk through links of HTML
+@params body string
+@params handle func(url string, text string)
+
+@return void
+*/
+func htmlLinks(body string, handle func(string, string)) {
+	tokenizer := html.NewTokenizer(strings.NewReader(body))
+	href := ""
+	var text strings.Builder
+
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			if href != "" {
+				handle(href, normalizeSpace(text.String()))
+			}
+			return
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			switch token.Data {
+			case "a":
+				href = attribute(token, "href")
+				text.Reset()
+			case "area":
+				handle(strings.TrimSpace(attribute(token, "href")), attribute(token, "alt"))
+			case "img":
+				// image inside link is described by its alt text
+				if href != "" {
+					text.WriteString(" " + attribute(token, "alt"))
+				}
+			}
+		case html.EndTagToken:
+			if token := tokenizer.Token(); token.Data == "a" && href != "" {
+				handle(strings.TrimSpace(href), normalizeSpace(text.String()))
+				href = ""
+			}
+		case html.TextToken:
+			if href != "" {
+				text.Write(tokenizer.Text())
+			}
+		}
+	}
+}
+
+/**
+Get visible text of HTML, scripts and styles are skipped
+@params body string
+
+@return string
+*/
+func htmlText(body string) string {
+	tokenizer := html.NewTokenizer(strings.NewReader(body))
+	var text strings.Builder
+	skip := ""
+
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return text.String()
+		case html.StartTagToken:
+			if name, _ := tokenizer.TagName(); string(name) == "script" || string(name) == "style" {
+				skip = string(name)
+			}
+			// tags separate words, e.g. cells of table
+			text.WriteString(" ")
+		case html.EndTagToken:
+			if name, _ := tokenizer.TagName(); string(name) == skip {
+				skip = ""
+			}
+			text.WriteString(" ")
+		case html.TextToken:
+			if skip == "" {
+				text.Write(tokenizer.Text())
+			}
+		}
+	}
+}
+
+/**
+Get attribute of HTML tag
+@params token html.Token
+@params name string
+
+@return string - empty if absent
+*/
+func attribute(token html.Token, name string) string {
+	for _, attr := range token.Attr {
+		if attr.Key == name {
+			return attr.Val
+		}
+	}
+
+	return ""
+}
+
+/**
+Replace sequences of whitespace with single space
+@params text string
+
+@return string
+*/
+func normalizeSpace(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
@@ -0,0 +1,172 @@
+package mail_parser
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+)
+
+// Message parts which are shown to user
+type Message struct {
+	Header mail.Header
+	// decoded subject
+	Subject string
+	// the first text/plain part which is not attachment
+	Text string
+	// the first text/html part which is not attachment
+	HTML string
+	// attachments and inline parts like images
+	Attachments []*Part
+}
+
+// Single decoded part of message
+type Part struct {
+	ContentType string
+	Filename    string
+	// Content-ID without angle brackets, used by cid: urls
+	ContentId string
+	Inline    bool
+	Data      []byte
+}
+
+// Decoder of RFC 2047 encoded words in headers
+var wordDecoder = &mime.WordDecoder{}
+
+/**
+Parse RFC 5322 message with MIME parts
+Charsets are not converted, parts are expected to be in UTF-8
+@params raw []byte
+
+@return *Message, error
+*/
+func Parse(raw []byte) (*Message, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+
+	message := &Message{Header: msg.Header, Subject: DecodeHeader(msg.Header.Get("Subject"))}
+	err = message.walk(textproto.MIMEHeader(msg.Header), msg.Body)
+
+	return message, err
+}
+
+/**
+Decode RFC 2047 encoded words, value is returned as is if it can't be decoded
+@params value string
+
+@return string
+*/
+func DecodeHeader(value string) string {
+	decoded, err := wordDecoder.DecodeHeader(value)
+	if err != nil {
+		return value
+	}
+
+	return decoded
+}
+
+/**
+Find attachment by its Content-ID
+@params contentId string - with or without angle brackets
+
+@return *Part - nil if not found
+*/
+func (m *Message) Attachment(contentId string) *Part {
+	contentId = strings.Trim(contentId, "<>")
+	for _, part := range m.Attachments {
+		if part.ContentId != "" && part.ContentId == contentId {
+			return part
+		}
+	}
+
+	return nil
+}
+
+/**
+Walk through part and its nested parts
+@params header textproto.MIMEHeader - header of part
+@params body io.Reader - encoded body of part
+
+@return error
+*/
+func (m *Message) walk(header textproto.MIMEHeader, body io.Reader) error {
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		mediaType = "text/plain"
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		reader := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := reader.NextRawPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := m.walk(part.Header, part); err != nil {
+				return err
+			}
+		}
+	}
+
+	data, err := ioutil.ReadAll(decodeBody(header.Get("Content-Transfer-Encoding"), body))
+	if err != nil {
+		return err
+	}
+
+	disposition, dispositionParams, _ := mime.ParseMediaType(header.Get("Content-Disposition"))
+	filename := dispositionParams["filename"]
+	if filename == "" {
+		filename = params["name"]
+	}
+
+	// text parts which are not attached are body of message
+	if disposition != "attachment" && filename == "" {
+		if mediaType == "text/plain" && m.Text == "" {
+			m.Text = string(data)
+			return nil
+		}
+		if mediaType == "text/html" && m.HTML == "" {
+			m.HTML = string(data)
+			return nil
+		}
+	}
+
+	m.Attachments = append(m.Attachments, &Part{
+		ContentType: mediaType,
+		Filename:    DecodeHeader(filename),
+		ContentId:   strings.Trim(header.Get("Content-ID"), "<> "),
+		Inline:      disposition == "inline" || (disposition == "" && header.Get("Content-ID") != ""),
+		Data:        data,
+	})
+
+	return nil
+}
+
+/**
+Decode body by Content-Transfer-Encoding
+@params encoding string
+@params body io.Reader
+
+@return io.Reader
+*/
+func decodeBody(encoding string, body io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, body)
+	case "quoted-printable":
+		return quotedprintable.NewReader(body)
+	}
+
+	return body
+}
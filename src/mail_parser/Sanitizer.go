@@ -0,0 +1,101 @@
+package mail_parser
+
+import (
+	"encoding/base64"
+	"mime"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+)
+
+// Style properties which are kept in sanitized HTML, values with urls are removed
+var allowedStyles = []string{
+	"background-color", "border", "border-bottom", "border-collapse", "border-color", "border-left",
+	"border-radius", "border-right", "border-spacing", "border-style", "border-top", "border-width",
+	"color", "display", "font", "font-family", "font-size", "font-style", "font-weight", "height",
+	"letter-spacing", "line-height", "margin", "margin-bottom", "margin-left", "margin-right", "margin-top",
+	"max-width", "min-width", "padding", "padding-bottom", "padding-left", "padding-right", "padding-top",
+	"text-align", "text-decoration", "text-transform", "vertical-align", "white-space", "width",
+}
+
+// Style values which make browser fetch something
+var remoteStyle = regexp.MustCompile(`(?i)url\s*\(|expression\s*\(|image-set\s*\(|@import`)
+
+// Types of images which are embedded as data: urls, the same as data: images allowed in HTML
+var inlineImageTypes = map[string]bool{
+	"image/gif": true, "image/jpeg": true, "image/png": true, "image/svg+xml": true, "image/webp": true,
+}
+
+/**
+Sanitize HTML body: scripts, event handlers, forms, style sheets and remote images are removed,
+cid: images are embedded as data: urls, so they are shown without requests to API
+
+@return string - empty if message has no HTML body
+*/
+func (m *Message) SafeHTML() string {
+	if m.HTML == "" {
+		return ""
+	}
+
+	return htmlPolicy(func(source *url.URL) {
+		if strings.EqualFold(source.Scheme, "cid") {
+			contentId, _ := url.PathUnescape(source.Opaque)
+			for _, part := range m.Attachments {
+				if part.ContentId != "" && part.ContentId == contentId {
+					*source = part.dataUrl()
+					return
+				}
+			}
+		}
+
+		// data: images are embedded, everything else is fetched from remote server
+		if !strings.EqualFold(source.Scheme, "data") {
+			*source = url.URL{Scheme: "about", Opaque: "blank"}
+		}
+	}).Sanitize(m.HTML)
+}
+
+/**
+Embed image part as data: url, parts which are not images are replaced with blank page
+
+@return url.URL
+*/
+func (p *Part) dataUrl() url.URL {
+	mediaType, _, _ := mime.ParseMediaType(p.ContentType)
+	if !inlineImageTypes[mediaType] {
+		return url.URL{Scheme: "about", Opaque: "blank"}
+	}
+
+	return url.URL{Scheme: "data", Opaque: mediaType + ";base64," + base64.StdEncoding.EncodeToString(p.Data)}
+}
+
+/**
+Create policy of sanitizer, it is created for every message because sources are rewritten by message attachments
+@params rewriteSource func(*url.URL) - rewrites src of images and other embedded content
+
+@return *bluemonday.Policy
+*/
+func htmlPolicy(rewriteSource func(*url.URL)) *bluemonday.Policy {
+	policy := bluemonday.UGCPolicy()
+
+	// layout of messages is built with tables and inline styles
+	policy.AllowAttrs("align", "valign", "bgcolor", "width", "height", "border", "cellpadding", "cellspacing").Globally()
+	policy.AllowElements("center", "font", "span", "div")
+	policy.AllowAttrs("color", "face", "size").OnElements("font")
+	policy.AllowStyles(allowedStyles...).MatchingHandler(func(value string) bool {
+		return !remoteStyle.MatchString(value)
+	}).Globally()
+
+	// cid: is allowed only to be rewritten to data: url
+	policy.AllowURLSchemes("http", "https", "mailto", "cid")
+	policy.AllowDataURIImages()
+	policy.RewriteSrc(rewriteSource)
+
+	// links are opened outside of message without referrer
+	policy.RequireNoReferrerOnLinks(true)
+	policy.AddTargetBlankToFullyQualifiedLinks(true)
+
+	return policy
+}
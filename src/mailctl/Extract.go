@@ -0,0 +1,74 @@
+package main
+
+import (
+	"client"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+)
+
+/**
+Print links of message: url and anchor text
+
+@return error
+*/
+func listLinks(ctx context.Context, api *client.Client, args []string) error {
+	flags := flag.NewFlagSet("links", flag.ExitOnError)
+	contains := flags.String("contains", "", "only links which url or text contains this value")
+	if err := parseArgs(flags, args, 2); err != nil {
+		return err
+	}
+	id, err := parseId(flags.Arg(1))
+	if err != nil {
+		return err
+	}
+
+	links, err := api.GetLinks(ctx, flags.Arg(0), id, *contains)
+	if err != nil {
+		return err
+	}
+
+	table := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for _, link := range links {
+		fmt.Fprintf(table, "%s\t%s\n", link.URL, link.Text)
+	}
+
+	return table.Flush()
+}
+
+/**
+Print verification codes of message, the most probable code is the first one
+
+@return error
+*/
+func listCodes(ctx context.Context, api *client.Client, args []string) error {
+	flags := flag.NewFlagSet("codes", flag.ExitOnError)
+	patterns := []string{}
+	flags.Func("pattern", "regular expression of code, may be repeated", func(value string) error {
+		patterns = append(patterns, value)
+		return nil
+	})
+	if err := parseArgs(flags, args, 2); err != nil {
+		return err
+	}
+	id, err := parseId(flags.Arg(1))
+	if err != nil {
+		return err
+	}
+
+	codes, err := api.GetCodes(ctx, flags.Arg(0), id, patterns...)
+	if err != nil {
+		return err
+	}
+	if len(codes) == 0 {
+		return fmt.Errorf("Message %d has no verification codes", id)
+	}
+
+	for _, code := range codes {
+		fmt.Println(code.Code)
+	}
+
+	return nil
+}
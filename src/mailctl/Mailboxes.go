@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"client"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"text/tabwriter"
+	"time"
+)
+
+// Lines which have to be escaped in mbox (mboxrd format)
+var mboxFromLine = regexp.MustCompile(`(?m)^(>*From )`)
+
+/**
+Create mailbox and print its address and token
+
+@return error
+*/
+func createMailbox(ctx context.Context, api *client.Client, args []string) error {
+	flags := flag.NewFlagSet("create", flag.ExitOnError)
+	if err := parseArgs(flags, args, 0); err != nil {
+		return err
+	}
+
+	mailbox, err := api.CreateMailbox(ctx)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s\ntoken: %s\n", mailbox.Address, mailbox.Token)
+	return nil
+}
+
+/**
+Print every mailbox with statistics
+
+@return error
+*/
+func listMailboxes(ctx context.Context, api *client.Client, args []string) error {
+	flags := flag.NewFlagSet("list", flag.ExitOnError)
+	prefix := flags.String("prefix", "", "address prefix")
+	domain := flags.String("domain", "", "address domain")
+	if err := parseArgs(flags, args, 0); err != nil {
+		return err
+	}
+
+	table := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(table, "ADDRESS\tMESSAGES\tUNREAD\tSIZE\tLAST RECEIVED\tCREATED")
+
+	options := &client.ListOptions{Limit: 100, Ascending: true}
+	for {
+		page, err := api.ListMailboxes(ctx, *prefix, *domain, options)
+		if err != nil {
+			return err
+		}
+
+		for _, mailbox := range page.Mailboxes {
+			lastReceived := "-"
+			if mailbox.LastReceived != nil {
+				lastReceived = mailbox.LastReceived.Format(time.RFC3339)
+			}
+			fmt.Fprintf(table, "%s\t%d\t%d\t%d\t%s\t%s\n", mailbox.Address, mailbox.Messages, mailbox.Unread,
+				mailbox.Size, lastReceived, mailbox.Created.Format(time.RFC3339))
+		}
+
+		if !page.HasMore {
+			break
+		}
+		options.SinceId = page.SinceId
+	}
+
+	return table.Flush()
+}
+
+/**
+Remove mailbox
+
+@return error
+*/
+func deleteMailbox(ctx context.Context, api *client.Client, args []string) error {
+	flags := flag.NewFlagSet("delete", flag.ExitOnError)
+	if err := parseArgs(flags, args, 1); err != nil {
+		return err
+	}
+
+	if err := api.DeleteMailbox(ctx, flags.Arg(0)); err != nil {
+		return err
+	}
+
+	fmt.Printf("%s removed\n", flags.Arg(0))
+	return nil
+}
+
+/**
+Write every message of mailbox to file or stdout in mbox format
+
+@return error
+*/
+func exportMailbox(ctx context.Context, api *client.Client, args []string) error {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	output := flags.String("o", "", "file, stdout by default")
+	if err := parseArgs(flags, args, 1); err != nil {
+		return err
+	}
+	address := flags.Arg(0)
+
+	messages, err := api.Search(ctx, address, client.SearchQuery{})
+	if err != nil {
+		return err
+	}
+
+	var writer io.Writer = os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		writer = file
+	}
+	mbox := bufio.NewWriter(writer)
+
+	for _, message := range messages {
+		raw, err := api.GetRawMessage(ctx, address, message.Id)
+		if err != nil {
+			return err
+		}
+
+		sender := message.From
+		if sender == "" {
+			sender = "MAILER-DAEMON"
+		}
+		raw = bytes.Replace(raw, []byte("\r\n"), []byte("\n"), -1)
+		raw = mboxFromLine.ReplaceAll(raw, []byte(">$1"))
+
+		fmt.Fprintf(mbox, "From %s %s\n", sender, message.ReceivedDate.UTC().Format(time.ANSIC))
+		mbox.Write(raw)
+		if !bytes.HasSuffix(raw, []byte("\n")) {
+			mbox.WriteString("\n")
+		}
+		mbox.WriteString("\n")
+	}
+
+	if err := mbox.Flush(); err != nil {
+		return err
+	}
+	if *output != "" {
+		fmt.Fprintf(os.Stderr, "%d message(s) exported to %s\n", len(messages), *output)
+	}
+
+	return nil
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"client"
+	"context"
+	"flag"
+	"fmt"
+	"mail_parser"
+	"models"
+	"net/smtp"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+/**
+Print messages of mailbox, newest first
+
+@return error
+*/
+func listMessages(ctx context.Context, api *client.Client, args []string) error {
+	flags := flag.NewFlagSet("messages", flag.ExitOnError)
+	unread := flags.Bool("unread", false, "only unread messages")
+	if err := parseArgs(flags, args, 1); err != nil {
+		return err
+	}
+
+	filter := &client.MessageFilter{}
+	if *unread {
+		filter.Unread = unread
+	}
+
+	table := newMessageTable()
+	options := &client.ListOptions{Limit: 100}
+	for {
+		page, err := api.ListMessages(ctx, flags.Arg(0), filter, options)
+		if err != nil {
+			return err
+		}
+
+		for _, message := range page.Messages {
+			printMessage(table, message)
+		}
+
+		if !page.HasMore {
+			break
+		}
+		options.MaxId = page.MaxId
+	}
+
+	return table.Flush()
+}
+
+/**
+Print single message in one of formats: text, html, raw, headers
+
+@return error
+*/
+func showMessage(ctx context.Context, api *client.Client, args []string) error {
+	flags := flag.NewFlagSet("show", flag.ExitOnError)
+	format := flags.String("format", "text", "text, html, raw or headers")
+	if err := parseArgs(flags, args, 2); err != nil {
+		return err
+	}
+	address := flags.Arg(0)
+	id, err := parseId(flags.Arg(1))
+	if err != nil {
+		return err
+	}
+
+	raw, err := api.GetRawMessage(ctx, address, id)
+	if err != nil {
+		return err
+	}
+	if *format == "raw" {
+		_, err = os.Stdout.Write(raw)
+		return err
+	}
+
+	message, err := mail_parser.Parse(raw)
+	if err != nil {
+		return err
+	}
+
+	switch *format {
+	case "headers":
+		names := make([]string, 0, len(message.Header))
+		for name := range message.Header {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			for _, value := range message.Header[name] {
+				fmt.Printf("%s: %s\n", name, value)
+			}
+		}
+	case "html":
+		if message.HTML == "" {
+			return fmt.Errorf("Message %d has no HTML part", id)
+		}
+		fmt.Println(message.HTML)
+	case "text":
+		for _, name := range []string{"From", "To", "Date"} {
+			fmt.Printf("%s: %s\n", name, mail_parser.DecodeHeader(message.Header.Get(name)))
+		}
+		fmt.Printf("Subject: %s\n\n%s\n", message.Subject, message.Text)
+		for _, attachment := range message.Attachments {
+			fmt.Printf("[attachment: %s %s, %d bytes]\n", attachment.Filename, attachment.ContentType, len(attachment.Data))
+		}
+	default:
+		return fmt.Errorf("Unknown format %q", *format)
+	}
+
+	return nil
+}
+
+/**
+Print messages which match every given value
+
+@return error
+*/
+func searchMessages(ctx context.Context, api *client.Client, args []string) error {
+	flags := flag.NewFlagSet("search", flag.ExitOnError)
+	query := client.SearchQuery{}
+	flags.StringVar(&query.From, "from", "", "sender contains")
+	flags.StringVar(&query.To, "to", "", "recipient contains")
+	flags.StringVar(&query.Subject, "subject", "", "subject contains")
+	flags.StringVar(&query.Body, "body", "", "body contains")
+	if err := parseArgs(flags, args, 1); err != nil {
+		return err
+	}
+
+	messages, err := api.Search(ctx, flags.Arg(0), query)
+	if err != nil {
+		return err
+	}
+
+	table := newMessageTable()
+	for _, message := range messages {
+		printMessage(table, message)
+	}
+
+	return table.Flush()
+}
+
+/**
+Print messages which arrive to mailbox until command is interrupted
+
+@return error
+*/
+func tailMailbox(ctx context.Context, api *client.Client, args []string) error {
+	flags := flag.NewFlagSet("tail", flag.ExitOnError)
+	interval := flags.Duration("interval", time.Second, "delay between checks")
+	if err := parseArgs(flags, args, 1); err != nil {
+		return err
+	}
+	address := flags.Arg(0)
+
+	// only messages newer than the last existing one are printed
+	latest, err := api.ListMessages(ctx, address, nil, &client.ListOptions{Limit: 1})
+	if err != nil {
+		return err
+	}
+	sinceId := 0
+	if len(latest.Messages) > 0 {
+		sinceId = latest.Messages[0].Id
+	}
+
+	fmt.Fprintf(os.Stderr, "Waiting for messages to %s, press Ctrl+C to stop\n", address)
+	for {
+		page, err := api.ListMessages(ctx, address, nil, &client.ListOptions{Limit: 100, SinceId: sinceId, Ascending: true})
+		if err != nil {
+			return err
+		}
+
+		for _, message := range page.Messages {
+			fmt.Printf("%d\t%s\t%s\t%s\n", message.Id, message.ReceivedDate.Format(time.RFC3339), message.From, message.Subject)
+			sinceId = message.Id
+		}
+		if page.HasMore {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(*interval):
+		}
+	}
+}
+
+/**
+Send test message to SMTP listener
+
+@return error
+*/
+func sendMessage(ctx context.Context, api *client.Client, args []string) error {
+	flags := flag.NewFlagSet("send", flag.ExitOnError)
+	server := flags.String("smtp", env("MAILCTL_SMTP", "127.0.0.1:2525"), "address of SMTP listener")
+	from := flags.String("from", "mailctl@localhost", "sender")
+	to := flags.String("to", "", "recipient, required")
+	subject := flags.String("subject", "Test message", "subject")
+	body := flags.String("body", "Test message sent by mailctl", "body")
+	if err := parseArgs(flags, args, 0); err != nil {
+		return err
+	}
+	if *to == "" {
+		return fmt.Errorf("Recipient is required")
+	}
+
+	now := time.Now()
+	data := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nDate: %s\r\nMessage-ID: <%d.mailctl@localhost>\r\n"+
+		"MIME-Version: 1.0\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n%s\r\n",
+		*from, *to, *subject, now.Format(time.RFC1123Z), now.UnixNano(),
+		strings.Replace(*body, "\n", "\r\n", -1))
+
+	if err := smtp.SendMail(*server, nil, *from, []string{*to}, []byte(data)); err != nil {
+		return err
+	}
+
+	fmt.Printf("Message sent to %s\n", *to)
+	return nil
+}
+
+/**
+Create table for list of messages
+
+@return *tabwriter.Writer
+*/
+func newMessageTable() *tabwriter.Writer {
+	table := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(table, "ID\tRECEIVED\tFLAGS\tFROM\tSUBJECT")
+	return table
+}
+
+/**
+Print message as row of table
+
+@return void
+*/
+func printMessage(table *tabwriter.Writer, message *models.Message) {
+	flags := ""
+	if !message.Seen {
+		flags += "N"
+	}
+	if message.Flagged {
+		flags += "F"
+	}
+	if message.Answered {
+		flags += "A"
+	}
+
+	fmt.Fprintf(table, "%d\t%s\t%s\t%s\t%s\n", message.Id, message.ReceivedDate.Format(time.RFC3339), flags, message.From, message.Subject)
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"client"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+)
+
+// Usage of every command
+const usage = `Usage: mailctl [-url URL] [-key KEY] [-tenant TENANT] COMMAND [ARGS]
+
+Commands:
+  create                            create mailbox with random address
+  list [-prefix P] [-domain D]      list mailboxes with statistics
+  delete ADDRESS                    remove mailbox with its messages
+  messages [-unread] ADDRESS        list messages of mailbox
+  show [-format F] ADDRESS ID       show message, format: text (default), html, raw, headers
+  links [-contains S] ADDRESS ID    print links of message with anchor text
+  codes [-pattern R]... ADDRESS ID  print verification codes of message, the most probable first
+  search [-from F] [-to T] [-subject S] [-body B] ADDRESS
+                                    find messages which contain every given value
+  tail [-interval D] ADDRESS        print new messages of mailbox until interrupted
+  send [-smtp HOST:PORT] -from F -to T [-subject S] [-body B]
+                                    send test message over SMTP
+  export [-o FILE] ADDRESS          write every message of mailbox in mbox format
+
+Environment:
+  MAILCTL_URL, MAILCTL_API_KEY, MAILCTL_TENANT are used as defaults of global flags
+`
+
+// Command receives client and its own arguments
+type command func(ctx context.Context, api *client.Client, args []string) error
+
+var commands = map[string]command{
+	"create":   createMailbox,
+	"list":     listMailboxes,
+	"delete":   deleteMailbox,
+	"messages": listMessages,
+	"show":     showMessage,
+	"links":    listLinks,
+	"codes":    listCodes,
+	"search":   searchMessages,
+	"tail":     tailMailbox,
+	"send":     sendMessage,
+	"export":   exportMailbox,
+}
+
+func main() {
+	global := flag.NewFlagSet("mailctl", flag.ExitOnError)
+	global.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	baseURL := global.String("url", env("MAILCTL_URL", "http://127.0.0.1:8080"), "address of API")
+	apiKey := global.String("key", os.Getenv("MAILCTL_API_KEY"), "API key")
+	tenant := global.String("tenant", os.Getenv("MAILCTL_TENANT"), "tenant of mailboxes")
+	global.Parse(os.Args[1:])
+
+	if global.NArg() == 0 {
+		global.Usage()
+		os.Exit(2)
+	}
+
+	run, ok := commands[global.Arg(0)]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n\n", global.Arg(0))
+		global.Usage()
+		os.Exit(2)
+	}
+
+	api := client.New(*baseURL, *apiKey)
+	api.Tenant = *tenant
+
+	// interrupt stops long running commands like tail
+	ctx, cancel := context.WithCancel(context.Background())
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	go func() {
+		<-interrupt
+		cancel()
+	}()
+
+	if err := run(ctx, api, global.Args()[1:]); err != nil && ctx.Err() == nil {
+		fmt.Fprintf(os.Stderr, "mailctl: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+/**
+Get environment variable or default value
+@params name string
+@params value string - default value
+
+@return string
+*/
+func env(name string, value string) string {
+	if envValue := os.Getenv(name); envValue != "" {
+		return envValue
+	}
+
+	return value
+}
+
+/**
+Parse flags of command and check count of positional arguments
+@params flags *flag.FlagSet
+@params args []string
+@params count int - expected count of positional arguments
+
+@return error
+*/
+func parseArgs(flags *flag.FlagSet, args []string, count int) error {
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != count {
+		return fmt.Errorf("%s expects %d argument(s), see mailctl -h", flags.Name(), count)
+	}
+
+	return nil
+}
+
+/**
+Parse message id argument
+@params value string
+
+@return int, error
+*/
+func parseId(value string) (int, error) {
+	id, err := strconv.Atoi(value)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("Message id must be positive number: %s", value)
+	}
+
+	return id, nil
+}
@@ -0,0 +1,79 @@
+package memdb
+
+import (
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+//Normalization of addresses
+type addressFolding struct {
+	//Local part of address is case-insensitive
+	foldLocal bool
+	//Subaddress after separator is delivered to base mailbox, empty separator disables subaddressing
+	subaddressSeparator string
+}
+
+//Normalization is replaced at runtime while addresses are resolved
+var folding atomic.Pointer[addressFolding]
+
+//ADDRESS_FOLD_LOCAL=false keeps letter case of local part, SUBADDRESS_SEPARATOR="" disables subaddressing
+func init() {
+	SetAddressFolding(os.Getenv("ADDRESS_FOLD_LOCAL") != "false", envDefault("SUBADDRESS_SEPARATOR", "+"))
+}
+
+//Entities which are found by mailbox address
+var addressedEntities = map[entity]bool{
+	entity_mailbox: true,
+	entity_message: true,
+	entity_thread:  true,
+	entity_alias:   true,
+	entity_forward: true,
+}
+
+//Change address normalization, empty separator disables subaddressing
+func SetAddressFolding(fold bool, separator string) {
+	folding.Store(&addressFolding{foldLocal: fold, subaddressSeparator: separator})
+}
+
+//Returns canonical address of mailbox and subaddress tag
+//Domain is always lowercase, local part is lowercase if folding is enabled
+//email_1+signup@some.domain is email_1@some.domain with signup tag
+func CanonicalAddress(address string) (string, string) {
+	at := strings.LastIndex(address, "@")
+	if at < 0 {
+		return address, ""
+	}
+	local, domain := address[:at], strings.ToLower(address[at+1:])
+
+	//Quoted local part is kept as is, except letter case
+	tag := ""
+	settings := folding.Load()
+	if separator := settings.subaddressSeparator; separator != "" && !strings.HasPrefix(local, `"`) {
+		if index := strings.Index(local, separator); index > 0 {
+			local, tag = local[:index], local[index+len(separator):]
+		}
+	}
+	if settings.foldLocal {
+		local = strings.ToLower(local)
+	}
+
+	return local + "@" + domain, tag
+}
+
+//Check if addresses are delivered to the same mailbox
+func SameMailbox(first string, second string) bool {
+	first, _ = CanonicalAddress(first)
+	second, _ = CanonicalAddress(second)
+
+	return first == second
+}
+
+//Returns value of environment variable, fallback is used if it is not set
+func envDefault(name string, fallback string) string {
+	if value, ok := os.LookupEnv(name); ok {
+		return value
+	}
+
+	return fallback
+}
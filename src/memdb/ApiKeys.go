@@ -0,0 +1,96 @@
+package memdb
+
+import (
+	"models"
+	"sort"
+)
+
+//Save new API key
+func (e engine) insertKey(command *command) {
+	//If key is already used
+	if e.keys[command.key] != nil {
+		command.result <- CommandResult{
+			Success: false,
+			Rows:    0,
+			Error:   "API key already exists",
+			Code:    ErrorConflict}
+		return
+	}
+
+	//Scoped key needs existing mailbox
+	apiKey := command.value.(*models.ApiKey)
+	if !apiKey.Admin && e.store[apiKey.Mailbox] == nil {
+		command.result <- CommandResult{
+			Success: false,
+			Rows:    0,
+			Error:   "There is not mailbox for the key",
+			Code:    ErrorNotFound}
+		return
+	}
+
+	//Save key
+	apiKey.Tenant = e.tenant.Name
+	e.keys[command.key] = apiKey
+
+	//Send result of saving
+	command.result <- CommandResult{Success: true, Rows: 1, Value: apiKey}
+}
+
+//Select single API key
+//Keys are unique across all tenants, so key of any tenant is returned
+func (e engine) selectKey(command *command) {
+	//If key is inexisting
+	if e.keys[command.key] == nil {
+		command.result <- CommandResult{
+			Success: false,
+			Rows:    0,
+			Error:   "API key is not found",
+			Code:    ErrorNotFound}
+		return
+	}
+
+	//Send key
+	command.result <- CommandResult{
+		Success: true,
+		Rows:    1,
+		Value:   e.keys[command.key]}
+}
+
+//Select all API keys of tenant ordered by creation time
+func (e engine) selectKeys(command *command) {
+	keys := make([]*models.ApiKey, 0, len(e.keys))
+	for _, apiKey := range e.keys {
+		if apiKey.Tenant == e.tenant.Name {
+			keys = append(keys, apiKey)
+		}
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].Created.Before(keys[j].Created)
+	})
+
+	//Send keys
+	command.result <- CommandResult{
+		Success: true,
+		Rows:    len(keys),
+		Value:   keys}
+}
+
+//Delete API key
+func (e engine) deleteKey(command *command) {
+	//If key is inexisting in tenant
+	if e.keys[command.key] == nil || e.keys[command.key].Tenant != e.tenant.Name {
+		command.result <- CommandResult{
+			Success: false,
+			Rows:    0,
+			Error:   "There is not API key to delete",
+			Code:    ErrorNotFound}
+		return
+	}
+
+	//Delete key
+	delete(e.keys, command.key)
+
+	//Send result of deleting
+	command.result <- CommandResult{Success: true, Rows: 1}
+}
@@ -2,6 +2,7 @@ package memdb
 
 import (
 	"fmt"
+	"logger"
 	"models"
 	"time"
 
@@ -9,39 +10,88 @@ import (
 )
 
 //Engine structure which contains data
+//Every tenant has its own engine with own store and sequences,
+//chanel, listeners, keys and list of tenants are shared
 type engine struct {
+	tenant           *models.Tenant
 	store            map[string]*models.MailBox
+	keys             map[string]*models.ApiKey
+	//Mailbox address of every alias
+	aliases          map[string]string
 	mailbox_sequance *int
 	message_sequance *int
+	thread_sequance  *int
+	forward_sequance *int
+	//Count and total size of messages of tenant, quotas are checked against them
+	message_count    *int
+	message_size     *int
 	chanel           chan command
+	//Chanel of every subscriber by its receive-only side, which is known to subscriber
+	listeners        map[<-chan Event]chan Event
+	tenants          map[string]*engine
 }
 
 //Save new mailbox
 func (e engine) insertMailbox(command *command) {
+	//If tenant has enough mailboxes
+	if e.tenant.MaxMailboxes > 0 && len(e.store) >= e.tenant.MaxMailboxes {
+		command.result <- CommandResult{
+			Success: false,
+			Rows:    0,
+			Error:   "Mailboxes quota is exceeded",
+			Code:    ErrorQuotaExceeded}
+		return
+	}
+
 	//Increase sequance
 	*e.mailbox_sequance++
 
 	//Create mailbox
 	mailBox := &models.MailBox{
-		Id:       *e.mailbox_sequance,
-		Address:  fmt.Sprintf(command.key, *e.mailbox_sequance),
-		Messages: make([]*models.Message, 0)}
+		Id:          *e.mailbox_sequance,
+		Address:     fmt.Sprintf(command.key, *e.mailbox_sequance),
+		Messages:    make([]*models.Message, 0),
+		CreatedDate: time.Now()}
 
 	//Save mailbox
 	e.store[mailBox.Address] = mailBox
 
-	//Send result of saving
-	command.result <- CommandResult{Success: true, Rows: 1, Value: mailBox}
+	//Send copy of saved mailbox
+	command.result <- CommandResult{Success: true, Rows: 1, Value: mailBox.Copy()}
 }
 
 //Save new message
 func (e engine) insertMessage(command *command) {
+	//If message is absent
+	if message, ok := command.value.(*models.Message); !ok || message == nil {
+		command.result <- CommandResult{
+			Success: false,
+			Rows:    0,
+			Error:   "Message is empty",
+			Code:    ErrorInvalid}
+		return
+	}
+
+	//Message to alias is saved to its mailbox
+	command.key = e.resolveAddress(command.key)
+
 	//If mailbox is inexisting
 	if e.store[command.key] == nil {
 		command.result <- CommandResult{
 			Success: false,
 			Rows:    0,
-			Error:   "There is not mailbox for the message"}
+			Error:   "There is not mailbox for the message",
+			Code:    ErrorNotFound}
+		return
+	}
+
+	//If tenant has not enough space for the message
+	if e.messageQuotaExceeded(command.value.(*models.Message)) {
+		command.result <- CommandResult{
+			Success: false,
+			Rows:    0,
+			Error:   "Messages quota is exceeded",
+			Code:    ErrorQuotaExceeded}
 		return
 	}
 
@@ -52,11 +102,24 @@ func (e engine) insertMessage(command *command) {
 	message := command.value.(*models.Message)
 	message.Id = *e.message_sequance
 
+	//Link message to conversation
+	e.assignThread(e.store[command.key], message)
+
 	//Save message to store
 	e.store[command.key].Messages = append(e.store[command.key].Messages, message)
+	e.countMessage(message, 1)
+
+	logger.From(command.ctx).Info("Message saved",
+		"tenant", command.tenant, "mailbox", command.key, "message_id", message.Id, "size", len(message.Raw))
 
-	//Send result of saving
-	command.result <- CommandResult{Success: true, Rows: 1, Value: message}
+	//Send copy of saved message, engine keeps changing the original
+	command.result <- CommandResult{Success: true, Rows: 1, Value: message.Copy()}
+
+	//Notify subscribers
+	e.notify(Event{Type: MessageInserted, Address: command.key, Index: len(e.store[command.key].Messages) - 1, Message: message})
+
+	//Copy message by forward rules
+	e.forwardMessage(command, command.key, message)
 }
 
 //Delete message
@@ -66,7 +129,8 @@ func (e engine) deleteMessage(command *command) {
 		command.result <- CommandResult{
 			Success: false,
 			Rows:    0,
-			Error:   "There is not mailbox for the message"}
+			Error:   "There is not mailbox for the message",
+			Code:    ErrorNotFound}
 		return
 	}
 
@@ -84,15 +148,21 @@ func (e engine) deleteMessage(command *command) {
 		command.result <- CommandResult{
 			Success: false,
 			Rows:    0,
-			Error:   "There is not message"}
+			Error:   "There is not message",
+			Code:    ErrorNotFound}
 		return
 	}
 
 	//Delete message
+	message := e.store[command.key].Messages[elementIndex]
 	e.store[command.key].Messages = append(e.store[command.key].Messages[:elementIndex], e.store[command.key].Messages[elementIndex+1:]...)
+	e.countMessage(message, -1)
 
 	//Send result of deleting
 	command.result <- CommandResult{Success: true, Rows: 1}
+
+	//Notify subscribers
+	e.notify(Event{Type: MessageDeleted, Address: command.key, Index: elementIndex, Message: message})
 }
 
 //Delete mailbox
@@ -102,16 +172,177 @@ func (e engine) deleteMailbox(command *command) {
 		command.result <- CommandResult{
 			Success: false,
 			Rows:    0,
-			Error:   "There is not mailbox to delete"}
+			Error:   "There is not mailbox to delete",
+			Code:    ErrorNotFound}
 
 		return
 	}
 
-	//Delete mailbox
+	//Delete mailbox, its aliases and access tokens
+	for _, message := range e.store[command.key].Messages {
+		e.countMessage(message, -1)
+	}
+	for _, alias := range e.store[command.key].Aliases {
+		delete(e.aliases, alias)
+	}
 	delete(e.store, command.key)
+	for key, apiKey := range e.keys {
+		if apiKey.Tenant == e.tenant.Name && apiKey.Mailbox == command.key {
+			delete(e.keys, key)
+		}
+	}
 
 	//Send result of deleting
 	command.result <- CommandResult{Success: true, Rows: 1}
+
+	//Notify subscribers
+	e.notify(Event{Type: MailboxDeleted, Address: command.key})
+}
+
+//Select copy of mailbox
+func (e engine) selectMailbox(command *command) {
+	//If mailbox is inexisting
+	if e.store[command.key] == nil {
+		command.result <- CommandResult{
+			Success: false,
+			Rows:    0,
+			Error:   "There is not such mailbox",
+			Code:    ErrorNotFound}
+		return
+	}
+
+	//Send deep copy of mailbox, so caller can read messages and their flags without races
+	command.result <- CommandResult{
+		Success: true,
+		Rows:    1,
+		Value:   e.store[command.key].Copy()}
+}
+
+//Select statistics of mailboxes using cursor-pagination
+func (e engine) selectMailboxes(command *command) {
+	boxes := make([]*models.MailBox, 0, len(e.store))
+	for _, box := range e.store {
+		boxes = append(boxes, box)
+	}
+
+	//Mailboxes which satisfy filter
+	filtered := From(boxes).Where(func(o T) (bool, error) {
+		return command.value.(*MailBoxFilter).match(o.(*models.MailBox)), nil
+	})
+	command.page.Total, _ = filtered.Count()
+
+	//One extra mailbox is taken to know if there is next page
+	boxesT, _ := filtered.Where(func(o T) (bool, error) {
+		return command.page.contains(o.(*models.MailBox).Id), nil
+	}).OrderBy(func(o1, o2 T) bool {
+		if command.page.Ascending {
+			return o1.(*models.MailBox).Id < o2.(*models.MailBox).Id
+		}
+
+		return o1.(*models.MailBox).Id > o2.(*models.MailBox).Id
+	}).Take(command.page.Count + 1).Results()
+
+	command.page.HasMore = len(boxesT) > command.page.Count
+	if command.page.HasMore {
+		boxesT = boxesT[:command.page.Count]
+	}
+
+	//Collect statistics
+	stats := make([]*models.MailBoxStats, len(boxesT))
+	for index, boxT := range boxesT {
+		stats[index] = boxT.(*models.MailBox).Stats()
+	}
+
+	//Send statistics
+	command.result <- CommandResult{
+		Success: true,
+		Rows:    len(stats),
+		Value:   stats}
+}
+
+//Update message
+//Command value is a function which changes message inside engine loop
+func (e engine) updateMessage(command *command) {
+	//If mailbox is inexisting
+	if e.store[command.key] == nil {
+		command.result <- CommandResult{
+			Success: false,
+			Rows:    0,
+			Error:   "There is not such mailbox",
+			Code:    ErrorNotFound}
+		return
+	}
+
+	//Find message`s index
+	elementIndex := -1
+	for index, element := range e.store[command.key].Messages {
+		if element.Id == command.id {
+			elementIndex = index
+			break
+		}
+	}
+
+	//if index is not found
+	if elementIndex == -1 {
+		command.result <- CommandResult{
+			Success: false,
+			Rows:    0,
+			Error:   "Message is not found",
+			Code:    ErrorNotFound}
+		return
+	}
+
+	//Apply changes
+	message := e.store[command.key].Messages[elementIndex]
+	command.value.(func(*models.Message))(message)
+
+	//Send copy of updated message
+	command.result <- CommandResult{Success: true, Rows: 1, Value: message.Copy()}
+
+	//Notify subscribers
+	e.notify(Event{Type: MessageUpdated, Address: command.key, Index: elementIndex, Message: message})
+}
+
+//Register new subscriber
+func (e engine) subscribe(command *command) {
+	listener := make(chan Event, eventBufferSize)
+	e.listeners[listener] = listener
+
+	command.result <- CommandResult{Success: true, Rows: 1, Value: listener}
+}
+
+//Remove subscriber and close its chanel
+func (e engine) unsubscribe(command *command) {
+	events := command.value.(<-chan Event)
+
+	//Slow subscriber is already removed
+	if listener, ok := e.listeners[events]; ok {
+		delete(e.listeners, events)
+		close(listener)
+	}
+
+	command.result <- CommandResult{Success: true, Rows: 1}
+}
+
+//Send event to every subscriber
+//Engine is never blocked by slow subscriber, subscriber is removed instead,
+//its chanel is closed, so it knows that events are lost and subscribes again
+//Subscribers receive copy of message, which is not changed by engine
+func (e engine) notify(event Event) {
+	event.Tenant = e.tenant.Name
+	if event.Message != nil {
+		event.Message = event.Message.Copy()
+	}
+	for events, listener := range e.listeners {
+		select {
+		case listener <- event:
+		default:
+			logger.Default().Warn("Subscriber is too slow, it is unsubscribed",
+				"tenant", event.Tenant, "mailbox", event.Address, "event", int(event.Type))
+			delete(e.listeners, events)
+			close(listener)
+		}
+	}
 }
 
 //Select single message
@@ -121,7 +352,8 @@ func (e engine) selectMessage(command *command) {
 		command.result <- CommandResult{
 			Success: false,
 			Rows:    0,
-			Error:   "There is not such mailbox"}
+			Error:   "There is not such mailbox",
+			Code:    ErrorNotFound}
 		return
 	}
 
@@ -135,15 +367,16 @@ func (e engine) selectMessage(command *command) {
 		command.result <- CommandResult{
 			Success: false,
 			Rows:    0,
-			Error:   "Message is not found"}
+			Error:   "Message is not found",
+			Code:    ErrorNotFound}
 		return
 	}
 
-	//Send message
+	//Send copy of message
 	command.result <- CommandResult{
 		Success: true,
 		Rows:    1,
-		Value:   message}
+		Value:   message.(*models.Message).Copy()}
 }
 
 //Select list of messages
@@ -153,26 +386,39 @@ func (e engine) selectMessages(command *command) {
 		command.result <- CommandResult{
 			Success: false,
 			Rows:    0,
-			Error:   "There is not such mailbox"}
+			Error:   "There is not such mailbox",
+			Code:    ErrorNotFound}
 		return
 	}
 
+	//Messages which satisfy filter
+	filtered := From(e.store[command.key].Messages).Where(func(o T) (bool, error) {
+		return command.filter.match(o.(*models.Message)), nil
+	})
+	command.page.Total, _ = filtered.Count()
+	command.page.Unread = e.store[command.key].UnreadCount()
+
 	//Select messages using cursor-pagination
-	messagesT, _ := From(e.store[command.key].Messages).OrderBy(func(o1, o2 T) bool {
-		return o1.(*models.Message).Id > o2.(*models.Message).Id
-	}).SkipWhile(func(o T) (bool, error) {
-		//Cursor pagination
-		if command.page.MaxId == nil {
-			return false, nil
+	//One extra message is taken to know if there is next page
+	messagesT, _ := filtered.Where(func(o T) (bool, error) {
+		return command.page.contains(o.(*models.Message).Id), nil
+	}).OrderBy(func(o1, o2 T) bool {
+		if command.page.Ascending {
+			return o1.(*models.Message).Id < o2.(*models.Message).Id
 		}
 
-		return o.(*models.Message).Id >= *command.page.MaxId, nil
-	}).Take(command.page.Count).Results()
+		return o1.(*models.Message).Id > o2.(*models.Message).Id
+	}).Take(command.page.Count + 1).Results()
+
+	command.page.HasMore = len(messagesT) > command.page.Count
+	if command.page.HasMore {
+		messagesT = messagesT[:command.page.Count]
+	}
 
-	//Convert Linq.T to *models.Mesage
+	//Convert Linq.T to copies of *models.Mesage
 	messages := make([]*models.Message, len(messagesT))
 	for index, messageT := range messagesT {
-		messages[index] = messageT.(*models.Message)
+		messages[index] = messageT.(*models.Message).Copy()
 	}
 
 	//Send messages
@@ -199,8 +445,13 @@ func (e engine) clearNotRelevantMessages(command *command) {
 			}
 
 			//Delete message
+			message := box.Messages[i]
 			box.Messages = append(box.Messages[:i], box.Messages[i+1:]...)
+			e.countMessage(message, -1)
 			deletedMessages++
+
+			//Notify subscribers
+			e.notify(Event{Type: MessageDeleted, Address: box.Address, Index: i, Message: message})
 		}
 	}
 
@@ -217,41 +468,133 @@ func (e engine) Run() {
 
 	//Loop through messages
 	for command := range e.chanel {
+		//Subscribers and tenants are shared by all tenants
+		if command.action == action_subscribe {
+			e.subscribe(&command)
+			continue
+		}
+		if command.action == action_unsubscribe {
+			e.unsubscribe(&command)
+			continue
+		}
+		if command.entity == entity_tenant {
+			e.runTenantCommand(&command)
+			continue
+		}
+
+		//Find tenant of command
+		tenant := e.tenants[command.tenant]
+		if tenant == nil {
+			command.result <- CommandResult{
+				Success: false,
+				Rows:    0,
+				Error:   "There is not such tenant",
+				Code:    ErrorNotFound}
+			continue
+		}
+
 		//Choose needed action
 		switch command.action {
 		case action_insert:
 			//Choose needed entity
 			switch command.entity {
 			case entity_mailbox:
-				e.insertMailbox(&command)
+				tenant.insertMailbox(&command)
 			case entity_message:
-				e.insertMessage(&command)
+				tenant.insertMessage(&command)
+			case entity_key:
+				tenant.insertKey(&command)
+			case entity_alias:
+				tenant.insertAlias(&command)
+			case entity_forward:
+				tenant.insertForward(&command)
 			}
 		case action_delete:
 			//Choose needed entity
 			switch command.entity {
 			case entity_mailbox:
-				e.deleteMailbox(&command)
+				tenant.deleteMailbox(&command)
 			case entity_message:
-				e.deleteMessage(&command)
+				tenant.deleteMessage(&command)
+			case entity_key:
+				tenant.deleteKey(&command)
+			case entity_alias:
+				tenant.deleteAlias(&command)
+			case entity_forward:
+				tenant.deleteForward(&command)
 			}
 		case action_get:
-			e.selectMessage(&command)
+			//Choose needed entity
+			switch command.entity {
+			case entity_mailbox:
+				tenant.selectMailbox(&command)
+			case entity_message:
+				tenant.selectMessage(&command)
+			case entity_thread:
+				tenant.selectThread(&command)
+			case entity_key:
+				tenant.selectKey(&command)
+			case entity_alias:
+				tenant.selectAlias(&command)
+			}
 		case action_filter:
-			e.selectMessages(&command)
+			//Choose needed entity
+			switch command.entity {
+			case entity_mailbox:
+				tenant.selectMailboxes(&command)
+			case entity_message:
+				tenant.selectMessages(&command)
+			case entity_thread:
+				tenant.selectThreads(&command)
+			case entity_key:
+				tenant.selectKeys(&command)
+			}
 		case action_clearnotrelevant:
-			e.clearNotRelevantMessages(&command)
+			tenant.clearNotRelevantMessages(&command)
+		case action_update:
+			tenant.updateMessage(&command)
 		}
 	}
 }
 
 //Constructor of database engine
+//Created engine serves default tenant
 func newEngine(chanel chan command) *engine {
 	//Initialize data
+	tenants := make(map[string]*engine)
 	engine := new(engine)
+	engine.tenant = &models.Tenant{Name: DefaultTenant, Created: time.Now()}
 	engine.store = make(map[string]*models.MailBox)
+	engine.keys = make(map[string]*models.ApiKey)
+	engine.aliases = make(map[string]string)
 	engine.mailbox_sequance = new(int)
 	engine.message_sequance = new(int)
+	engine.thread_sequance = new(int)
+	engine.forward_sequance = new(int)
+	engine.message_count = new(int)
+	engine.message_size = new(int)
+	engine.listeners = make(map[<-chan Event]chan Event)
 	engine.chanel = chanel
+	engine.tenants = tenants
+	engine.tenants[DefaultTenant] = engine
+	return engine
+}
+
+//Constructor of tenant engine, shared data is taken from root engine
+func newTenantEngine(root *engine, tenant *models.Tenant) *engine {
+	engine := new(engine)
+	engine.tenant = tenant
+	engine.store = make(map[string]*models.MailBox)
+	engine.keys = root.keys
+	engine.aliases = make(map[string]string)
+	engine.mailbox_sequance = new(int)
+	engine.message_sequance = new(int)
+	engine.thread_sequance = new(int)
+	engine.forward_sequance = new(int)
+	engine.message_count = new(int)
+	engine.message_size = new(int)
+	engine.listeners = root.listeners
+	engine.chanel = root.chanel
+	engine.tenants = root.tenants
 	return engine
 }
@@ -0,0 +1,234 @@
+package memdb
+
+import (
+	"logger"
+	"models"
+)
+
+//Forwarded copy is not forwarded further after this count of mailboxes
+const maxForwardHops = 10
+
+//Returns address of mailbox which receives messages of address or alias
+func (e engine) resolveAddress(address string) string {
+	if e.store[address] == nil && e.aliases[address] != "" {
+		return e.aliases[address]
+	}
+
+	return address
+}
+
+//Select address of mailbox which receives messages of address or alias
+func (e engine) selectAlias(command *command) {
+	address := e.resolveAddress(command.key)
+
+	//If mailbox is inexisting
+	if e.store[address] == nil {
+		command.result <- CommandResult{
+			Success: false,
+			Rows:    0,
+			Error:   "There is not mailbox for the address",
+			Code:    ErrorNotFound}
+		return
+	}
+
+	command.result <- CommandResult{Success: true, Rows: 1, Value: address}
+}
+
+//Save new alias of mailbox
+func (e engine) insertAlias(command *command) {
+	alias := command.value.(string)
+
+	//If mailbox is inexisting
+	if e.store[command.key] == nil {
+		command.result <- CommandResult{
+			Success: false,
+			Rows:    0,
+			Error:   "There is not such mailbox",
+			Code:    ErrorNotFound}
+		return
+	}
+
+	//If address is taken by mailbox or another alias
+	if e.store[alias] != nil || e.aliases[alias] != "" {
+		command.result <- CommandResult{
+			Success: false,
+			Rows:    0,
+			Error:   "Address is already used",
+			Code:    ErrorConflict}
+		return
+	}
+
+	//If alias is not delivered to tenant of mailbox
+	if AddressTenant(alias) != e.tenant.Name {
+		command.result <- CommandResult{
+			Success: false,
+			Rows:    0,
+			Error:   "Alias has to be in domain of tenant",
+			Code:    ErrorInvalid}
+		return
+	}
+
+	e.aliases[alias] = command.key
+	e.store[command.key].Aliases = append(e.store[command.key].Aliases, alias)
+
+	command.result <- CommandResult{Success: true, Rows: 1, Value: alias}
+}
+
+//Delete alias of mailbox
+func (e engine) deleteAlias(command *command) {
+	alias := command.value.(string)
+
+	//If alias belongs to another mailbox
+	if e.store[command.key] == nil || e.aliases[alias] != command.key {
+		command.result <- CommandResult{
+			Success: false,
+			Rows:    0,
+			Error:   "There is not such alias",
+			Code:    ErrorNotFound}
+		return
+	}
+
+	delete(e.aliases, alias)
+	box := e.store[command.key]
+	aliases := []string{}
+	for _, existing := range box.Aliases {
+		if existing != alias {
+			aliases = append(aliases, existing)
+		}
+	}
+	box.Aliases = aliases
+
+	command.result <- CommandResult{Success: true, Rows: 1}
+}
+
+//Save new forward rule of mailbox
+func (e engine) insertForward(command *command) {
+	rule := command.value.(*models.ForwardRule)
+
+	//If mailbox is inexisting
+	if e.store[command.key] == nil {
+		command.result <- CommandResult{
+			Success: false,
+			Rows:    0,
+			Error:   "There is not such mailbox",
+			Code:    ErrorNotFound}
+		return
+	}
+
+	//If target is inexisting
+	target := e.resolveAddress(rule.To)
+	if e.store[target] == nil {
+		command.result <- CommandResult{
+			Success: false,
+			Rows:    0,
+			Error:   "There is not mailbox to forward to",
+			Code:    ErrorNotFound}
+		return
+	}
+
+	//If mailbox forwards to itself
+	if target == command.key {
+		command.result <- CommandResult{
+			Success: false,
+			Rows:    0,
+			Error:   "Mailbox can not forward to itself",
+			Code:    ErrorInvalid}
+		return
+	}
+
+	//Increase sequance
+	*e.forward_sequance++
+	rule.Id = *e.forward_sequance
+	e.store[command.key].Forwards = append(e.store[command.key].Forwards, rule)
+
+	//Send copy of saved rule
+	saved := *rule
+	command.result <- CommandResult{Success: true, Rows: 1, Value: &saved}
+}
+
+//Delete forward rule of mailbox
+func (e engine) deleteForward(command *command) {
+	//If mailbox is inexisting
+	if e.store[command.key] == nil {
+		command.result <- CommandResult{
+			Success: false,
+			Rows:    0,
+			Error:   "There is not such mailbox",
+			Code:    ErrorNotFound}
+		return
+	}
+
+	box := e.store[command.key]
+	for index, rule := range box.Forwards {
+		if rule.Id == command.id {
+			box.Forwards = append(box.Forwards[:index:index], box.Forwards[index+1:]...)
+			command.result <- CommandResult{Success: true, Rows: 1}
+			return
+		}
+	}
+
+	command.result <- CommandResult{
+		Success: false,
+		Rows:    0,
+		Error:   "There is not such forward rule",
+		Code:    ErrorNotFound}
+}
+
+//Copy saved message to targets of matching forward rules of its mailbox
+//Mailboxes which already have the copy are skipped, so rules can not loop
+func (e engine) forwardMessage(command *command, address string, message *models.Message) {
+	//Mailboxes on the way of message
+	visited := map[string]bool{address: true}
+	for _, mailbox := range message.ForwardedFrom {
+		visited[mailbox] = true
+	}
+
+	e.forwardCopies(command, address, message, visited)
+}
+
+//Copy message by forward rules of mailbox and rules of targets
+//Visited mailboxes are shared by the whole forwarding, so every mailbox gets one copy even if rules converge
+func (e engine) forwardCopies(command *command, address string, message *models.Message, visited map[string]bool) {
+	for _, rule := range e.store[address].Forwards {
+		if !rule.Match(message) {
+			continue
+		}
+
+		target := e.resolveAddress(rule.To)
+		switch {
+		case visited[target]:
+			logger.From(command.ctx).Warn("Mailbox already has forwarded message",
+				"tenant", command.tenant, "mailbox", address, "target", target, "rule_id", rule.Id)
+			continue
+		case len(message.ForwardedFrom) >= maxForwardHops:
+			logger.From(command.ctx).Warn("Message is forwarded too many times",
+				"tenant", command.tenant, "mailbox", address, "target", target, "rule_id", rule.Id)
+			continue
+		case e.store[target] == nil:
+			logger.From(command.ctx).Warn("Forward target is inexisting",
+				"tenant", command.tenant, "mailbox", address, "target", target, "rule_id", rule.Id)
+			continue
+		}
+
+		forwarded := message.Forward(address)
+		if e.messageQuotaExceeded(forwarded) {
+			logger.From(command.ctx).Warn("Forwarded message exceeds quota",
+				"tenant", command.tenant, "mailbox", address, "target", target, "rule_id", rule.Id)
+			continue
+		}
+
+		//Save copy as a new message of target mailbox
+		*e.message_sequance++
+		forwarded.Id = *e.message_sequance
+		e.assignThread(e.store[target], forwarded)
+		e.store[target].Messages = append(e.store[target].Messages, forwarded)
+		e.countMessage(forwarded, 1)
+		visited[target] = true
+
+		logger.From(command.ctx).Info("Message forwarded",
+			"tenant", command.tenant, "mailbox", address, "target", target, "rule_id", rule.Id, "message_id", forwarded.Id)
+		e.notify(Event{Type: MessageInserted, Address: target, Index: len(e.store[target].Messages) - 1, Message: forwarded})
+
+		e.forwardCopies(command, target, forwarded, visited)
+	}
+}
@@ -1,9 +1,18 @@
 package memdb
 
 import (
+	"context"
+	"errors"
+	"logger"
+	"metrics"
 	"models"
+	"strings"
 	"sync"
 	"time"
+	"tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 //Variables to support singleton
@@ -13,13 +22,22 @@ var (
 )
 
 //All commands go through this chanel and after it listener processes them
+//Every command is executed inside tenant of database instance
 type database struct {
 	commands chan command
 	engine   *engine
+	tenant   string
+	//Correlation ids of request which executes commands
+	ctx context.Context
 }
 
+//Domain of generated mailboxes, tenant mailboxes use subdomain with tenant name
+const mailboxDomain = "some.domain"
+
 //The struct is used to compose command for database engine
 type command struct {
+	ctx    context.Context
+	tenant string
 	action action
 	entity entity
 	id     int
@@ -27,6 +45,7 @@ type command struct {
 	value  interface{}
 	result chan<- interface{}
 	page   *PageCursor
+	filter *MessageFilter
 }
 
 //List of possible actions
@@ -38,28 +57,196 @@ const (
 	action_filter
 	action_delete
 	action_clearnotrelevant
+	action_update
+	action_subscribe
+	action_unsubscribe
+	action_stats
 )
 
+//Names of actions, used in metrics
+var actionNames = map[action]string{
+	action_insert:           "insert",
+	action_get:              "get",
+	action_filter:           "filter",
+	action_delete:           "delete",
+	action_clearnotrelevant: "clearnotrelevant",
+	action_update:           "update",
+	action_subscribe:        "subscribe",
+	action_unsubscribe:      "unsubscribe",
+	action_stats:            "stats",
+}
+
+func (a action) String() string {
+	return actionNames[a]
+}
+
 //List of possible entities in database
 type entity int
 
 const (
 	entity_mailbox entity = iota
 	entity_message
+	entity_thread
+	entity_key
+	entity_tenant
+	entity_alias
+	entity_forward
 )
 
+//Names of entities, used in metrics
+var entityNames = map[entity]string{
+	entity_mailbox: "mailbox",
+	entity_message: "message",
+	entity_thread:  "thread",
+	entity_key:     "key",
+	entity_tenant:  "tenant",
+	entity_alias:   "alias",
+	entity_forward: "forward",
+}
+
+func (e entity) String() string {
+	return entityNames[e]
+}
+
+//List of possible errors of database command
+type ErrorCode int
+
+const (
+	ErrorNone ErrorCode = iota
+	ErrorNotFound
+	ErrorConflict
+	ErrorInvalid
+	ErrorQuotaExceeded
+	ErrorInternal
+)
+
+//Name of error code, used in api responses
+func (c ErrorCode) String() string {
+	switch c {
+	case ErrorNone:
+		return ""
+	case ErrorNotFound:
+		return "not_found"
+	case ErrorConflict:
+		return "conflict"
+	case ErrorInvalid:
+		return "invalid"
+	case ErrorQuotaExceeded:
+		return "quota_exceeded"
+	}
+
+	return "internal"
+}
+
 //Response from database command
 type CommandResult struct {
 	Success bool
 	Rows    int
 	Error   string
+	Code    ErrorCode
 	Value   interface{}
 }
 
 //The struct is used for pagination purpose
+//Total and HasMore are filled by database engine
 type PageCursor struct {
+	//Only messages with smaller id are selected
 	MaxId *int
+	//Only messages with bigger id are selected
+	SinceId *int
+	//Page size
 	Count int
+	//Oldest messages go first
+	Ascending bool
+	//Count of messages which satisfy filter in whole mailbox
+	Total int
+	//Count of unread messages in whole mailbox, filter is not applied
+	Unread int
+	//There are messages after the last one on the page
+	HasMore bool
+}
+
+//Check if message id is inside cursor bounds
+func (p *PageCursor) contains(id int) bool {
+	if p.MaxId != nil && id >= *p.MaxId {
+		return false
+	}
+	if p.SinceId != nil && id <= *p.SinceId {
+		return false
+	}
+
+	return true
+}
+
+//The struct is used to filter list of messages, nil fields are not checked
+type MessageFilter struct {
+	Seen    *bool
+	Flagged *bool
+	//Subaddress tag, empty tag selects messages to base address
+	Tag *string
+}
+
+//Check if message satisfies filter
+func (f *MessageFilter) match(message *models.Message) bool {
+	if f == nil {
+		return true
+	}
+	if f.Seen != nil && message.Seen != *f.Seen {
+		return false
+	}
+	if f.Flagged != nil && message.Flagged != *f.Flagged {
+		return false
+	}
+	if f.Tag != nil && !strings.EqualFold(message.Tag, *f.Tag) {
+		return false
+	}
+
+	return true
+}
+
+//The struct is used to filter list of mailboxes, empty fields are not checked
+type MailBoxFilter struct {
+	Prefix string
+	Domain string
+}
+
+//Check if mailbox satisfies filter
+func (f *MailBoxFilter) match(box *models.MailBox) bool {
+	if f == nil {
+		return true
+	}
+	if f.Prefix != "" && !strings.HasPrefix(strings.ToLower(box.Address), strings.ToLower(f.Prefix)) {
+		return false
+	}
+	if f.Domain != "" && !strings.HasSuffix(strings.ToLower(box.Address), "@"+strings.ToLower(f.Domain)) {
+		return false
+	}
+
+	return true
+}
+
+//Size of subscriber chanel buffer
+//If subscriber is too slow, it is unsubscribed and its chanel is closed, so it knows that events are lost
+const eventBufferSize = 100
+
+//List of possible changes in database
+type EventType int
+
+const (
+	MessageInserted EventType = iota
+	MessageUpdated
+	MessageDeleted
+	MailboxDeleted
+)
+
+//Notification about change in database, sent to subscribers
+type Event struct {
+	Type    EventType
+	Tenant  string
+	Address string
+	//Position of the message inside mailbox at the moment of change
+	Index   int
+	Message *models.Message
 }
 
 //Base executing command
@@ -69,25 +256,126 @@ func (db database) executeCommand(executingCommand *command) CommandResult {
 	reply := make(chan interface{})
 	defer close(reply)
 
-	//Put chanel to command
+	action := executingCommand.action.String()
+	entity := executingCommand.entity.String()
+
+	//Span of command is a child of request or session span
+	started := time.Now()
+	ctx, span := tracing.Start(db.ctx, "memdb "+action+" "+entity, trace.WithTimestamp(started), trace.WithAttributes(
+		attribute.String("memdb.action", action),
+		attribute.String("memdb.entity", entity),
+		attribute.String("memdb.tenant", db.tenant)))
+
+	//Put chanel, tenant and request context to command
 	executingCommand.result = reply
+	executingCommand.tenant = db.tenant
+	executingCommand.ctx = ctx
+
+	//Mailboxes are found by canonical address, e.g. Email_1+tag@Some.Domain is email_1@some.domain
+	if addressedEntities[executingCommand.entity] && executingCommand.key != "" {
+		executingCommand.key, _ = CanonicalAddress(executingCommand.key)
+	}
 
 	//Send command to database chanel
+	//Chanel is not buffered, so sending finishes when engine takes the command
 	db.commands <- *executingCommand
+	dequeued := time.Now()
+	result := (<-reply).(CommandResult)
+	finished := time.Now()
+
+	//Time in queue and execution time are separate child spans
+	tracing.Interval(ctx, "memdb.queue", started, dequeued)
+	tracing.Interval(ctx, "memdb.execute", dequeued, finished)
+	span.SetAttributes(attribute.Bool("memdb.success", result.Success), attribute.Int("memdb.rows", result.Rows))
+	if result.Code != ErrorNone {
+		span.SetAttributes(attribute.String("memdb.error", result.Code.String()))
+	}
+	if result.Code == ErrorInternal {
+		tracing.Fail(span, errors.New(result.Error))
+	}
+	span.End(trace.WithTimestamp(finished))
+
+	//Time in queue and execution time are measured together
+	duration := finished.Sub(started)
+	metrics.CommandDuration.WithLabelValues(action, entity).Observe(duration.Seconds())
+	logger.From(ctx).Debug("Database command executed",
+		"action", action,
+		"entity", entity,
+		"tenant", db.tenant,
+		"success", result.Success,
+		"error", result.Code.String(),
+		"queue", dequeued.Sub(started),
+		"duration", duration)
 
 	//Return database response
-	return (<-reply).(CommandResult)
+	return result
+}
+
+//Returns database instance which executes commands inside tenant
+func (db database) Tenant(name string) *database {
+	if name == "" {
+		name = DefaultTenant
+	}
+
+	return &database{commands: db.commands, engine: db.engine, tenant: name, ctx: db.ctx}
+}
+
+//Returns database instance which logs commands with correlation ids of context
+func (db database) WithContext(ctx context.Context) *database {
+	return &database{commands: db.commands, engine: db.engine, tenant: db.tenant, ctx: ctx}
+}
+
+//Returns tenant of mailbox address, tenant is a subdomain of mailboxes domain
+func AddressTenant(address string) string {
+	domain := strings.ToLower(address[strings.LastIndex(address, "@")+1:])
+	if strings.HasSuffix(domain, "."+mailboxDomain) {
+		return strings.TrimSuffix(domain, "."+mailboxDomain)
+	}
+
+	return DefaultTenant
+}
+
+//Create new tenant
+func (db database) InsertTenant(tenant *models.Tenant) CommandResult {
+	command := &command{action: action_insert, entity: entity_tenant, value: tenant}
+	return db.executeCommand(command)
+}
+
+//Delete tenant with all its data
+func (db database) DeleteTenant(name string) CommandResult {
+	command := &command{action: action_delete, entity: entity_tenant, key: name}
+	return db.executeCommand(command)
+}
+
+//Returns some concreate tenant
+func (db database) GetTenant(name string) CommandResult {
+	command := &command{action: action_get, entity: entity_tenant, key: name}
+	return db.executeCommand(command)
+}
+
+//Returns all tenants
+func (db database) GetTenants() CommandResult {
+	command := &command{action: action_filter, entity: entity_tenant}
+	return db.executeCommand(command)
 }
 
 //Create new mailbox
 //There are not input parameters because email address is auto generated
 func (db database) InsertMailBox() CommandResult {
-	command := &command{action: action_insert, entity: entity_mailbox, key: "email_%d@some.domain"}
+	domain := mailboxDomain
+	if db.tenant != DefaultTenant {
+		domain = db.tenant + "." + mailboxDomain
+	}
+
+	command := &command{action: action_insert, entity: entity_mailbox, key: "email_%d@" + domain}
 	return db.executeCommand(command)
 }
 
-//Save new message
+//Save new message, subaddress tag of address is saved to message
+//Engine saves a copy, message of caller is not changed, saved message with its id and thread is returned
 func (db database) InsertMessage(address string, message *models.Message) CommandResult {
+	message = message.Copy()
+	_, message.Tag = CanonicalAddress(address)
 	command := &command{action: action_insert, entity: entity_message, key: address, value: message}
 	return db.executeCommand(command)
 }
@@ -112,7 +400,131 @@ func (db database) GetMessage(address string, id int) CommandResult {
 
 //Returns a list of messages for some mailbox
 func (db database) GetMailBoxMessages(address string, page *PageCursor) CommandResult {
-	command := &command{action: action_filter, entity: entity_message, key: address, page: page}
+	return db.FilterMailBoxMessages(address, page, nil)
+}
+
+//Returns a list of messages for some mailbox which satisfy filter
+func (db database) FilterMailBoxMessages(address string, page *PageCursor, filter *MessageFilter) CommandResult {
+	command := &command{action: action_filter, entity: entity_message, key: address, page: page, filter: filter}
+	return db.executeCommand(command)
+}
+
+//Returns conversations of some mailbox, newest goes first
+func (db database) GetMailBoxThreads(address string) CommandResult {
+	command := &command{action: action_filter, entity: entity_thread, key: address}
+	return db.executeCommand(command)
+}
+
+//Returns some concreate conversation with its messages
+func (db database) GetThread(address string, id int) CommandResult {
+	command := &command{action: action_get, entity: entity_thread, key: address, id: id}
+	return db.executeCommand(command)
+}
+
+//Returns statistics of mailboxes which satisfy filter
+func (db database) GetMailBoxes(page *PageCursor, filter *MailBoxFilter) CommandResult {
+	command := &command{action: action_filter, entity: entity_mailbox, page: page, value: filter}
+	return db.executeCommand(command)
+}
+
+//Returns a copy of mailbox with all its messages in order of receiving
+func (db database) GetMailBox(address string) CommandResult {
+	command := &command{action: action_get, entity: entity_mailbox, key: address}
+	return db.executeCommand(command)
+}
+
+//Replace flags of some concreate message
+func (db database) SetMessageFlags(address string, id int, flags []string) CommandResult {
+	command := &command{action: action_update, entity: entity_message, key: address, id: id, value: func(message *models.Message) {
+		message.SetFlags(flags)
+	}}
+	return db.executeCommand(command)
+}
+
+//Change seen, flagged and answered state of some concreate message
+func (db database) UpdateMessageFlags(address string, id int, flags models.MessageFlags) CommandResult {
+	command := &command{action: action_update, entity: entity_message, key: address, id: id, value: flags.Apply}
+	return db.executeCommand(command)
+}
+
+//Save result of releasing message to upstream server
+func (db database) AddMessageRelease(address string, id int, release models.Release) CommandResult {
+	command := &command{action: action_update, entity: entity_message, key: address, id: id, value: func(message *models.Message) {
+		message.Releases = append(message.Releases, release)
+	}}
+	return db.executeCommand(command)
+}
+
+//Add alias address to mailbox
+func (db database) InsertAlias(address string, alias string) CommandResult {
+	alias, _ = CanonicalAddress(alias)
+	command := &command{action: action_insert, entity: entity_alias, key: address, value: alias}
+	return db.executeCommand(command)
+}
+
+//Returns address of mailbox which receives messages of address or its alias
+func (db database) ResolveAddress(address string) CommandResult {
+	command := &command{action: action_get, entity: entity_alias, key: address}
+	return db.executeCommand(command)
+}
+
+//Delete alias address of mailbox
+func (db database) DeleteAlias(address string, alias string) CommandResult {
+	alias, _ = CanonicalAddress(alias)
+	command := &command{action: action_delete, entity: entity_alias, key: address, value: alias}
+	return db.executeCommand(command)
+}
+
+//Add rule which copies new messages of mailbox to another mailbox
+//Engine saves a copy, rule of caller is not changed, saved rule with its id is returned
+func (db database) InsertForward(address string, rule *models.ForwardRule) CommandResult {
+	saved := *rule
+	saved.To, _ = CanonicalAddress(rule.To)
+	command := &command{action: action_insert, entity: entity_forward, key: address, value: &saved}
+	return db.executeCommand(command)
+}
+
+//Delete forward rule of mailbox
+func (db database) DeleteForward(address string, id int) CommandResult {
+	command := &command{action: action_delete, entity: entity_forward, key: address, id: id}
+	return db.executeCommand(command)
+}
+
+//Save new API key
+func (db database) InsertApiKey(apiKey *models.ApiKey) CommandResult {
+	command := &command{action: action_insert, entity: entity_key, key: apiKey.Key, value: apiKey}
+	return db.executeCommand(command)
+}
+
+//Returns API key by its value
+func (db database) GetApiKey(key string) CommandResult {
+	command := &command{action: action_get, entity: entity_key, key: key}
+	return db.executeCommand(command)
+}
+
+//Returns all API keys
+func (db database) GetApiKeys() CommandResult {
+	command := &command{action: action_filter, entity: entity_key}
+	return db.executeCommand(command)
+}
+
+//Delete API key
+func (db database) DeleteApiKey(key string) CommandResult {
+	command := &command{action: action_delete, entity: entity_key, key: key}
+	return db.executeCommand(command)
+}
+
+//Subscribe to changes in database
+//Returned chanel receives an event after every insert, update or delete
+//Chanel is closed when subscriber is too slow and events are lost, then it has to subscribe again
+func (db database) Subscribe() <-chan Event {
+	command := &command{action: action_subscribe}
+	return db.executeCommand(command).Value.(chan Event)
+}
+
+//Stop sending events to subscriber, its chanel is closed
+func (db database) Unsubscribe(events <-chan Event) CommandResult {
+	command := &command{action: action_unsubscribe, value: events}
 	return db.executeCommand(command)
 }
 
@@ -122,18 +534,60 @@ func (db database) ClearNotRelevantMessages(duration time.Duration) CommandResul
 	return db.executeCommand(command)
 }
 
+//Returns count of mailboxes, messages and their size in every tenant
+func (db database) GetStats() CommandResult {
+	command := &command{action: action_stats, entity: entity_tenant}
+	return db.executeCommand(command)
+}
+
+//Check if engine loop handles commands in time
+func (db database) Ping(timeout time.Duration) error {
+	//Reply chanel is buffered and not closed, so late response of engine doesn't block it
+	reply := make(chan interface{}, 1)
+	ping := command{ctx: db.ctx, tenant: db.tenant, action: action_get, entity: entity_tenant, key: db.tenant, result: reply}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case db.commands <- ping:
+	case <-timer.C:
+		return errors.New("Database engine doesn't accept commands")
+	}
+
+	select {
+	case <-reply:
+		return nil
+	case <-timer.C:
+		return errors.New("Database engine doesn't respond")
+	}
+}
+
 //Construct and get instance of database
 //Only one instance can be constructed(Singleton)
 func GetInstance() *database {
 	once.Do(func() {
 		//Code inside this block is executed only once
 		chanel := make(chan command)
-		instance = &database{commands: chanel, engine: newEngine(chanel)}
+		instance = &database{commands: chanel, engine: newEngine(chanel), tenant: DefaultTenant}
 
 		//Database engine start
 		go instance.engine.Run()
+
+		//Stored data is read on every metrics scrape
+		metrics.SetStorageSource(storageStats)
 	})
 
 	//Return singleton instance
 	return instance
 }
+
+//Convert stats of tenants to metrics
+func storageStats() []metrics.Storage {
+	response := GetInstance().GetStats()
+	if !response.Success {
+		return nil
+	}
+
+	return response.Value.([]metrics.Storage)
+}
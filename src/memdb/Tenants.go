@@ -0,0 +1,177 @@
+package memdb
+
+import (
+	"metrics"
+	"models"
+	"regexp"
+	"sort"
+	"time"
+)
+
+//Tenant which is used when no tenant is specified
+const DefaultTenant = "default"
+
+//Tenant name is a part of mailbox domain, so it has to be valid domain label
+var tenantName = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+//Handle commands which manage tenants
+func (e engine) runTenantCommand(command *command) {
+	//Choose needed action
+	switch command.action {
+	case action_insert:
+		e.insertTenant(command)
+	case action_delete:
+		e.deleteTenant(command)
+	case action_get:
+		e.selectTenant(command)
+	case action_filter:
+		e.selectTenants(command)
+	case action_stats:
+		e.selectStats(command)
+	}
+}
+
+//Save new tenant
+func (e engine) insertTenant(command *command) {
+	tenant := command.value.(*models.Tenant)
+
+	//If name can not be used in domain
+	if !tenantName.MatchString(tenant.Name) {
+		command.result <- CommandResult{
+			Success: false,
+			Rows:    0,
+			Error:   "Tenant name must contain only lowercase letters, digits and hyphens",
+			Code:    ErrorInvalid}
+		return
+	}
+
+	//If tenant is existing
+	if e.tenants[tenant.Name] != nil {
+		command.result <- CommandResult{
+			Success: false,
+			Rows:    0,
+			Error:   "Tenant already exists",
+			Code:    ErrorConflict}
+		return
+	}
+
+	//Save tenant
+	tenant.Created = time.Now()
+	e.tenants[tenant.Name] = newTenantEngine(&e, tenant)
+
+	//Send result of saving
+	command.result <- CommandResult{Success: true, Rows: 1, Value: tenant}
+}
+
+//Delete tenant with all its mailboxes and keys
+func (e engine) deleteTenant(command *command) {
+	tenant := e.tenants[command.key]
+
+	//If tenant is inexisting
+	if tenant == nil {
+		command.result <- CommandResult{
+			Success: false,
+			Rows:    0,
+			Error:   "There is not tenant to delete",
+			Code:    ErrorNotFound}
+		return
+	}
+
+	//Default tenant is always present
+	if command.key == DefaultTenant {
+		command.result <- CommandResult{
+			Success: false,
+			Rows:    0,
+			Error:   "Default tenant can not be deleted",
+			Code:    ErrorInvalid}
+		return
+	}
+
+	//Delete tenant and its keys
+	delete(e.tenants, command.key)
+	for key, apiKey := range e.keys {
+		if apiKey.Tenant == command.key {
+			delete(e.keys, key)
+		}
+	}
+
+	//Send result of deleting
+	command.result <- CommandResult{Success: true, Rows: 1}
+
+	//Notify subscribers
+	for address := range tenant.store {
+		tenant.notify(Event{Type: MailboxDeleted, Address: address})
+	}
+}
+
+//Select single tenant
+func (e engine) selectTenant(command *command) {
+	//If tenant is inexisting
+	if e.tenants[command.key] == nil {
+		command.result <- CommandResult{
+			Success: false,
+			Rows:    0,
+			Error:   "There is not such tenant",
+			Code:    ErrorNotFound}
+		return
+	}
+
+	//Send tenant
+	command.result <- CommandResult{
+		Success: true,
+		Rows:    1,
+		Value:   e.tenants[command.key].tenant}
+}
+
+//Select all tenants ordered by name
+func (e engine) selectTenants(command *command) {
+	tenants := make([]*models.Tenant, 0, len(e.tenants))
+	for _, tenant := range e.tenants {
+		tenants = append(tenants, tenant.tenant)
+	}
+
+	sort.Slice(tenants, func(i, j int) bool {
+		return tenants[i].Name < tenants[j].Name
+	})
+
+	//Send tenants
+	command.result <- CommandResult{
+		Success: true,
+		Rows:    len(tenants),
+		Value:   tenants}
+}
+
+//Check if new message exceeds messages count or size quota of tenant
+func (e engine) messageQuotaExceeded(message *models.Message) bool {
+	if e.tenant.MaxMessages <= 0 && e.tenant.MaxSize <= 0 {
+		return false
+	}
+
+	return (e.tenant.MaxMessages > 0 && *e.message_count+1 > e.tenant.MaxMessages) ||
+		(e.tenant.MaxSize > 0 && *e.message_size+message.Size() > e.tenant.MaxSize)
+}
+
+//Change count and size of messages of tenant by saved (direction 1) or deleted (direction -1) message
+//Size of message never changes after saving, so the same size is subtracted on deleting
+func (e engine) countMessage(message *models.Message, direction int) {
+	*e.message_count += direction
+	*e.message_size += direction * message.Size()
+}
+
+//Count mailboxes, messages and their size in every tenant
+func (e engine) selectStats(command *command) {
+	stats := make([]metrics.Storage, 0, len(e.tenants))
+	for name, tenant := range e.tenants {
+		stats = append(stats, metrics.Storage{
+			Tenant:    name,
+			Mailboxes: len(tenant.store),
+			Messages:  *tenant.message_count,
+			Bytes:     *tenant.message_size})
+	}
+
+	//Send stats
+	command.result <- CommandResult{
+		Success: true,
+		Rows:    len(stats),
+		Value:   stats}
+}
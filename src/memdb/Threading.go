@@ -0,0 +1,174 @@
+package memdb
+
+import (
+	"fmt"
+	"models"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+//Matches reply and forward prefixes of subject like "Re: ", "Fwd: ", "RE[2]: "
+var subjectPrefix = regexp.MustCompile(`(?i)^\s*(re|fwd?|aw|wg)(\[\d+\])?\s*:\s*`)
+
+//Remove reply and forward prefixes from subject
+func normalizeSubject(subject string) string {
+	for subjectPrefix.MatchString(subject) {
+		subject = subjectPrefix.ReplaceAllString(subject, "")
+	}
+
+	return strings.ToLower(strings.TrimSpace(subject))
+}
+
+//Assign thread to new message
+//Thread is found by In-Reply-To and References headers,
+//then by replies which were received before the message,
+//and at last by normalized subject
+func (e engine) assignThread(box *models.MailBox, message *models.Message) {
+	//Message-ID is needed to link future replies
+	if message.MessageID == "" {
+		message.MessageID = fmt.Sprintf("<%d@mail_service>", message.Id)
+	}
+
+	//Parents of the message, the closest one goes first
+	parents := []string{}
+	if message.InReplyTo != "" {
+		parents = append(parents, message.InReplyTo)
+	}
+	for i := len(message.References) - 1; i >= 0; i-- {
+		parents = append(parents, message.References[i])
+	}
+
+	for _, parent := range parents {
+		for _, existing := range box.Messages {
+			if existing.MessageID == parent {
+				message.ThreadId = existing.ThreadId
+				return
+			}
+		}
+	}
+
+	//Replies which came earlier than the message itself
+	for _, existing := range box.Messages {
+		if existing.InReplyTo == message.MessageID || containsString(existing.References, message.MessageID) {
+			message.ThreadId = existing.ThreadId
+			return
+		}
+	}
+
+	//Fallback to subject
+	subject := normalizeSubject(message.Subject)
+	if subject != "" {
+		for _, existing := range box.Messages {
+			if normalizeSubject(existing.Subject) == subject {
+				message.ThreadId = existing.ThreadId
+				return
+			}
+		}
+	}
+
+	//Start new thread
+	*e.thread_sequance++
+	message.ThreadId = *e.thread_sequance
+}
+
+//Group messages of mailbox to threads
+//Threads are ordered by last received message, messages inside thread - by id
+func groupThreads(box *models.MailBox) []*models.Thread {
+	threads := []*models.Thread{}
+	byId := make(map[int]*models.Thread)
+
+	for _, message := range box.Messages {
+		thread := byId[message.ThreadId]
+		if thread == nil {
+			thread = &models.Thread{Id: message.ThreadId, Subject: message.Subject}
+			byId[message.ThreadId] = thread
+			threads = append(threads, thread)
+		}
+
+		thread.Messages = append(thread.Messages, message)
+		thread.Count++
+		if !message.Seen {
+			thread.Unread++
+		}
+		if message.ReceivedDate.After(thread.LastReceived) {
+			thread.LastReceived = message.ReceivedDate
+		}
+	}
+
+	sort.SliceStable(threads, func(i, j int) bool {
+		return threads[i].LastReceived.After(threads[j].LastReceived)
+	})
+
+	return threads
+}
+
+//Select list of threads without messages
+func (e engine) selectThreads(command *command) {
+	//If mailbox is inexisting
+	if e.store[command.key] == nil {
+		command.result <- CommandResult{
+			Success: false,
+			Rows:    0,
+			Error:   "There is not such mailbox",
+			Code:    ErrorNotFound}
+		return
+	}
+
+	threads := groupThreads(e.store[command.key])
+	for _, thread := range threads {
+		thread.Messages = nil
+	}
+
+	//Send threads
+	command.result <- CommandResult{
+		Success: true,
+		Rows:    len(threads),
+		Value:   threads}
+}
+
+//Select single thread with its messages
+func (e engine) selectThread(command *command) {
+	//If mailbox is inexisting
+	if e.store[command.key] == nil {
+		command.result <- CommandResult{
+			Success: false,
+			Rows:    0,
+			Error:   "There is not such mailbox",
+			Code:    ErrorNotFound}
+		return
+	}
+
+	for _, thread := range groupThreads(e.store[command.key]) {
+		if thread.Id == command.id {
+			//Messages of thread are copied, engine keeps changing the originals
+			for index, message := range thread.Messages {
+				thread.Messages[index] = message.Copy()
+			}
+
+			//Send thread
+			command.result <- CommandResult{
+				Success: true,
+				Rows:    1,
+				Value:   thread}
+			return
+		}
+	}
+
+	command.result <- CommandResult{
+		Success: false,
+		Rows:    0,
+		Error:   "Thread is not found",
+		Code:    ErrorNotFound}
+}
+
+//Check if list contains string
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,105 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Prefix of every metric
+const namespace = "mail_service"
+
+// Channels through which messages are received
+const (
+	ChannelApi  = "api"
+	ChannelSmtp = "smtp"
+	ChannelImap = "imap"
+)
+
+// Reasons of rejected deliveries which don't come from database errors
+const (
+	ReasonRateLimit = "rate_limit"
+	ReasonParse     = "parse"
+	ReasonAddress   = "address"
+)
+
+var (
+	// Messages saved to mailboxes
+	MessagesReceived = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_received_total",
+		Help:      "Count of received messages by channel.",
+	}, []string{"channel"})
+
+	// Messages which were not saved
+	DeliveriesRejected = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "deliveries_rejected_total",
+		Help:      "Count of rejected deliveries by channel and reason.",
+	}, []string{"channel", "reason"})
+
+	// Runs of expired messages collector
+	CollectorRuns = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "collector_runs_total",
+		Help:      "Count of expired messages collector runs.",
+	})
+
+	// Messages removed by collector
+	CollectorDeleted = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "collector_deleted_messages_total",
+		Help:      "Count of expired messages removed by collector.",
+	})
+
+	// Time of database command including time spent in commands queue
+	CommandDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "memdb_command_duration_seconds",
+		Help:      "Latency of database commands by action and entity.",
+		Buckets:   []float64{.00001, .00005, .0001, .0005, .001, .005, .01, .05, .1, .5, 1},
+	}, []string{"action", "entity"})
+
+	// Handled HTTP requests
+	HttpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "Count of HTTP requests by method, route and status.",
+	}, []string{"method", "route", "status"})
+
+	// Time of HTTP requests
+	HttpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Latency of HTTP requests by method and route.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	// Accepted SMTP connections
+	SmtpSessions = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "smtp_sessions_total",
+		Help:      "Count of SMTP sessions.",
+	})
+
+	// Time of SMTP transactions from MAIL to the end of DATA
+	SmtpTransactions = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "smtp_transaction_duration_seconds",
+		Help:      "Latency of SMTP transactions by result.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"result"})
+)
+
+func init() {
+	prometheus.MustRegister(
+		MessagesReceived,
+		DeliveriesRejected,
+		CollectorRuns,
+		CollectorDeleted,
+		CommandDuration,
+		HttpRequests,
+		HttpDuration,
+		SmtpSessions,
+		SmtpTransactions,
+		storage,
+	)
+}
@@ -0,0 +1,66 @@
+package metrics
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Stored data of single tenant
+type Storage struct {
+	Tenant    string
+	Mailboxes int
+	Messages  int
+	Bytes     int
+}
+
+// Collector which reads stored data on every scrape
+type storageCollector struct {
+	mutex  sync.Mutex
+	source func() []Storage
+
+	mailboxes *prometheus.Desc
+	messages  *prometheus.Desc
+	bytes     *prometheus.Desc
+}
+
+var storage = &storageCollector{
+	mailboxes: prometheus.NewDesc(namespace+"_mailboxes_stored", "Count of stored mailboxes by tenant.", []string{"tenant"}, nil),
+	messages:  prometheus.NewDesc(namespace+"_messages_stored", "Count of stored messages by tenant.", []string{"tenant"}, nil),
+	bytes:     prometheus.NewDesc(namespace+"_bytes_stored", "Size of stored messages in bytes by tenant.", []string{"tenant"}, nil),
+}
+
+/**
+Set function which returns stored data, it is called on every scrape
+@params source func() []Storage
+
+@return void
+*/
+func SetStorageSource(source func() []Storage) {
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+
+	storage.source = source
+}
+
+func (c *storageCollector) Describe(descs chan<- *prometheus.Desc) {
+	descs <- c.mailboxes
+	descs <- c.messages
+	descs <- c.bytes
+}
+
+func (c *storageCollector) Collect(metrics chan<- prometheus.Metric) {
+	c.mutex.Lock()
+	source := c.source
+	c.mutex.Unlock()
+
+	if source == nil {
+		return
+	}
+
+	for _, tenant := range source() {
+		metrics <- prometheus.MustNewConstMetric(c.mailboxes, prometheus.GaugeValue, float64(tenant.Mailboxes), tenant.Tenant)
+		metrics <- prometheus.MustNewConstMetric(c.messages, prometheus.GaugeValue, float64(tenant.Messages), tenant.Tenant)
+		metrics <- prometheus.MustNewConstMetric(c.bytes, prometheus.GaugeValue, float64(tenant.Bytes), tenant.Tenant)
+	}
+}
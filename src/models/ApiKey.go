@@ -0,0 +1,14 @@
+package models
+
+import (
+	"time"
+)
+
+// Key without tenant is a static admin key with access to all tenants
+type ApiKey struct {
+	Key     string    `form:"-" json:"key"`
+	Admin   bool      `form:"admin" json:"admin"`
+	Mailbox string    `form:"mailbox" json:"mailbox,omitempty"`
+	Tenant  string    `form:"-" json:"tenant"`
+	Created time.Time `form:"-" json:"created"`
+}
@@ -0,0 +1,44 @@
+package models
+
+// Results of authentication checks, names are the same as in Authentication-Results header
+const (
+	AuthPass      = "pass"
+	AuthFail      = "fail"
+	AuthNone      = "none"
+	AuthNeutral   = "neutral"
+	AuthSoftFail  = "softfail"
+	AuthTempError = "temperror"
+	AuthPermError = "permerror"
+)
+
+// Result of verification of single DKIM-Signature header
+type DkimResult struct {
+	Result string `json:"result"`
+	Reason string `json:"reason,omitempty"`
+	// signing domain (d=) and selector (s=) of signature
+	Domain     string `json:"domain"`
+	Selector   string `json:"selector"`
+	Identifier string `json:"identifier,omitempty"`
+}
+
+// Result of SPF check of envelope sender, HELO name is checked for null sender
+type SpfResult struct {
+	Result string `json:"result"`
+	Reason string `json:"reason,omitempty"`
+	// checked domain and its identity: "mailfrom" or "helo"
+	Domain   string `json:"domain"`
+	Identity string `json:"identity"`
+	ClientIP string `json:"client_ip"`
+}
+
+// Result of DMARC evaluation of From header domain
+type DmarcResult struct {
+	Result string `json:"result"`
+	Reason string `json:"reason,omitempty"`
+	Domain string `json:"domain"`
+	// requested policy: none, quarantine or reject, empty if domain has no record
+	Policy string `json:"policy,omitempty"`
+	// passed checks which domains are aligned with From domain
+	SpfAligned  bool `json:"spf_aligned"`
+	DkimAligned bool `json:"dkim_aligned"`
+}
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"strings"
+)
+
+// Fields of message which are checked by forward condition
+var ForwardFields = []string{"from", "to", "subject", "body", "tag"}
+
+// Rule which copies every new message of mailbox to another mailbox
+type ForwardRule struct {
+	Id int `json:"id"`
+	// address of target mailbox or its alias
+	To string `json:"to"`
+	// nil condition matches every message
+	Condition *ForwardCondition `json:"condition"`
+}
+
+// Message matches condition if its field contains value, case insensitive
+type ForwardCondition struct {
+	Field    string `json:"field"`
+	Contains string `json:"contains"`
+}
+
+/**
+Check if message has to be forwarded by rule
+@params message *Message
+
+@return bool
+*/
+func (r *ForwardRule) Match(message *Message) bool {
+	if r.Condition == nil {
+		return true
+	}
+
+	value := ""
+	switch r.Condition.Field {
+	case "from":
+		value = message.From
+	case "to":
+		value = message.To
+	case "subject":
+		value = message.Subject
+	case "body":
+		value = message.Body
+	case "tag":
+		value = message.Tag
+	}
+
+	return strings.Contains(strings.ToLower(value), strings.ToLower(r.Condition.Contains))
+}
+
+/**
+Create copy of message for another mailbox, flags and releases are not copied
+@params mailbox string - address of mailbox which forwards message
+
+@return *Message
+*/
+func (m *Message) Forward(mailbox string) *Message {
+	forwarded := *m
+	forwarded.Id = 0
+	forwarded.ThreadId = 0
+	forwarded.Seen, forwarded.Flagged, forwarded.Answered = false, false, false
+	forwarded.Flags = nil
+	forwarded.Releases = nil
+	forwarded.ForwardedFrom = append(append([]string{}, m.ForwardedFrom...), mailbox)
+
+	return &forwarded
+}
@@ -1,7 +1,73 @@
 package models
 
+import (
+	"time"
+)
+
 type MailBox struct {
-	Id       int		`form:"message_id" json:"message_id"`
-	Address  string 	`form:"email" json:"email" binding:"required"`
-	Messages []*Message
+	Id          int		`form:"message_id" json:"message_id"`
+	Address     string 	`form:"email" json:"email" binding:"required"`
+	Messages    []*Message
+	CreatedDate time.Time	`form:"-" json:"-"`
+	// other addresses which deliver to mailbox
+	Aliases     []string	`form:"-" json:"aliases"`
+	Forwards    []*ForwardRule	`form:"-" json:"forwards"`
+}
+
+/**
+Copy mailbox with copies of its messages, aliases and forward rules
+
+@return *MailBox
+*/
+func (m *MailBox) Copy() *MailBox {
+	copied := *m
+	copied.Messages = make([]*Message, len(m.Messages))
+	for index, message := range m.Messages {
+		copied.Messages[index] = message.Copy()
+	}
+	copied.Aliases = append(m.Aliases[:0:0], m.Aliases...)
+	copied.Forwards = append(m.Forwards[:0:0], m.Forwards...)
+
+	return &copied
+}
+
+/**
+Count messages which are not seen yet
+
+@return int
+*/
+func (m *MailBox) UnreadCount() int {
+	count := 0
+	for _, message := range m.Messages {
+		if !message.Seen {
+			count++
+		}
+	}
+
+	return count
+}
+
+/**
+Collect statistics of mailbox
+
+@return *MailBoxStats
+*/
+func (m *MailBox) Stats() *MailBoxStats {
+	stats := &MailBoxStats{
+		Id:       m.Id,
+		Address:  m.Address,
+		Messages: len(m.Messages),
+		Unread:   m.UnreadCount(),
+		Created:  m.CreatedDate,
+	}
+
+	for _, message := range m.Messages {
+		stats.Size += message.Size()
+		if stats.LastReceived == nil || message.ReceivedDate.After(*stats.LastReceived) {
+			received := message.ReceivedDate
+			stats.LastReceived = &received
+		}
+	}
+
+	return stats
 }
@@ -0,0 +1,15 @@
+package models
+
+import (
+	"time"
+)
+
+type MailBoxStats struct {
+	Id           int        `json:"id"`
+	Address      string     `json:"email"`
+	Messages     int        `json:"messages"`
+	Unread       int        `json:"unread"`
+	Size         int        `json:"size"`
+	LastReceived *time.Time `json:"last_received"`
+	Created      time.Time  `json:"created"`
+}
@@ -1,9 +1,25 @@
 package models
 
 import (
+	"bytes"
+	"fmt"
+	"mime"
+	"net/mail"
+	"regexp"
+	"strings"
 	"time"
 )
 
+// Flags which are stored as separate fields of message
+const (
+	SeenFlag     = "\\Seen"
+	FlaggedFlag  = "\\Flagged"
+	AnsweredFlag = "\\Answered"
+)
+
+// Matches message ids like <unique@domain>
+var messageIdPattern = regexp.MustCompile(`<[^<>\s]+>`)
+
 type Message struct {
 	Id           int
 	ReceivedDate time.Time
@@ -11,4 +27,161 @@ type Message struct {
 	To           string	`form:"to" json:"to" binding:"required"`
 	Subject      string	`form:"subject" json:"subject" binding:"required"`
 	Body         string	`form:"message" json:"message" binding:"required"`
+	// original RFC 5322 data, empty for messages posted through API
+	Raw          []byte	`form:"-" json:"-"`
+	Seen         bool	`form:"-" json:"seen"`
+	Flagged      bool	`form:"-" json:"flagged"`
+	Answered     bool	`form:"-" json:"answered"`
+	// IMAP flags of the message except seen, flagged and answered
+	Flags        []string	`form:"-" json:"-"`
+	// attempts to release message to upstream SMTP server
+	Releases     []Release	`form:"-" json:"releases"`
+	// threading headers, Message-ID is generated if absent
+	MessageID    string	`form:"message-id" json:"message-id"`
+	InReplyTo    string	`form:"in-reply-to" json:"in-reply-to"`
+	References   []string	`form:"references" json:"references"`
+	ThreadId     int	`form:"-" json:"thread_id"`
+	// subaddress of recipient, e.g. signup for email_1+signup@some.domain
+	Tag          string	`form:"-" json:"tag"`
+	// mailboxes which forwarded the message, in order of forwarding
+	ForwardedFrom []string	`form:"-" json:"forwarded_from"`
+	// results of authentication checks of SMTP messages, one DKIM result per signature
+	Dkim         []DkimResult	`form:"-" json:"dkim"`
+	Spf          *SpfResult	`form:"-" json:"spf"`
+	Dmarc        *DmarcResult	`form:"-" json:"dmarc"`
+}
+
+/**
+Returns message in RFC 5322 format
+If original data is absent, message is composed from its fields
+
+@return []byte
+*/
+func (m *Message) RawData() []byte {
+	if len(m.Raw) > 0 {
+		return m.Raw
+	}
+
+	var raw bytes.Buffer
+	fmt.Fprintf(&raw, "From: %s\r\n", m.From)
+	fmt.Fprintf(&raw, "To: %s\r\n", m.To)
+	fmt.Fprintf(&raw, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", m.Subject))
+	fmt.Fprintf(&raw, "Date: %s\r\n", m.ReceivedDate.Format(time.RFC1123Z))
+	fmt.Fprintf(&raw, "Message-ID: %s\r\n", m.MessageID)
+	if m.InReplyTo != "" {
+		fmt.Fprintf(&raw, "In-Reply-To: %s\r\n", m.InReplyTo)
+	}
+	if len(m.References) > 0 {
+		fmt.Fprintf(&raw, "References: %s\r\n", strings.Join(m.References, " "))
+	}
+	raw.WriteString("MIME-Version: 1.0\r\n")
+	raw.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	raw.WriteString("\r\n")
+	raw.WriteString(strings.Replace(strings.Replace(m.Body, "\r\n", "\n", -1), "\n", "\r\n", -1))
+
+	return raw.Bytes()
+}
+
+/**
+Returns all flags of message in IMAP notation
+
+@return []string
+*/
+func (m *Message) AllFlags() []string {
+	flags := make([]string, 0, len(m.Flags)+3)
+	if m.Seen {
+		flags = append(flags, SeenFlag)
+	}
+	if m.Flagged {
+		flags = append(flags, FlaggedFlag)
+	}
+	if m.Answered {
+		flags = append(flags, AnsweredFlag)
+	}
+
+	return append(flags, m.Flags...)
+}
+
+/**
+Replace all flags of message using IMAP notation
+@params flags []string
+
+@return void
+*/
+func (m *Message) SetFlags(flags []string) {
+	m.Seen, m.Flagged, m.Answered = false, false, false
+	m.Flags = make([]string, 0, len(flags))
+
+	for _, flag := range flags {
+		switch flag {
+		case SeenFlag:
+			m.Seen = true
+		case FlaggedFlag:
+			m.Flagged = true
+		case AnsweredFlag:
+			m.Answered = true
+		default:
+			m.Flags = append(m.Flags, flag)
+		}
+	}
+}
+
+/**
+Copy message with its mutable fields, so copy can be read outside of database engine
+Raw data is never changed after saving and is shared
+
+@return *Message
+*/
+func (m *Message) Copy() *Message {
+	copied := *m
+	copied.Flags = append(m.Flags[:0:0], m.Flags...)
+	copied.Releases = append(m.Releases[:0:0], m.Releases...)
+	copied.References = append(m.References[:0:0], m.References...)
+	copied.ForwardedFrom = append(m.ForwardedFrom[:0:0], m.ForwardedFrom...)
+	copied.Dkim = append(m.Dkim[:0:0], m.Dkim...)
+	if m.Spf != nil {
+		spf := *m.Spf
+		copied.Spf = &spf
+	}
+	if m.Dmarc != nil {
+		dmarc := *m.Dmarc
+		copied.Dmarc = &dmarc
+	}
+
+	return &copied
+}
+
+/**
+Size of message in RFC 5322 format
+
+@return int
+*/
+func (m *Message) Size() int {
+	return len(m.RawData())
+}
+
+/**
+Read threading headers of received message
+@params header mail.Header
+
+@return void
+*/
+func (m *Message) SetThreadHeaders(header mail.Header) {
+	if ids := ParseMessageIds(header.Get("Message-Id")); len(ids) > 0 {
+		m.MessageID = ids[0]
+	}
+	if ids := ParseMessageIds(header.Get("In-Reply-To")); len(ids) > 0 {
+		m.InReplyTo = ids[0]
+	}
+	m.References = ParseMessageIds(header.Get("References"))
+}
+
+/**
+Extract all message ids from header value
+@params value string
+
+@return []string
+*/
+func ParseMessageIds(value string) []string {
+	return messageIdPattern.FindAllString(value, -1)
 }
@@ -0,0 +1,26 @@
+package models
+
+// Partial update of message flags, absent fields are not changed
+type MessageFlags struct {
+	Seen     *bool `form:"seen" json:"seen"`
+	Flagged  *bool `form:"flagged" json:"flagged"`
+	Answered *bool `form:"answered" json:"answered"`
+}
+
+/**
+Apply changed flags to message
+@params message *Message
+
+@return void
+*/
+func (f MessageFlags) Apply(message *Message) {
+	if f.Seen != nil {
+		message.Seen = *f.Seen
+	}
+	if f.Flagged != nil {
+		message.Flagged = *f.Flagged
+	}
+	if f.Answered != nil {
+		message.Answered = *f.Answered
+	}
+}
@@ -0,0 +1,13 @@
+package models
+
+import (
+	"time"
+)
+
+type Release struct {
+	To      string    `json:"to"`
+	Host    string    `json:"host"`
+	Date    time.Time `json:"date"`
+	Success bool      `json:"success"`
+	Error   string    `json:"error,omitempty"`
+}
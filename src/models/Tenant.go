@@ -0,0 +1,15 @@
+package models
+
+import (
+	"time"
+)
+
+// Namespace with its own mailboxes, id sequences and API keys
+// Zero quota means there is no limit
+type Tenant struct {
+	Name         string    `form:"name" json:"name" binding:"required"`
+	MaxMailboxes int       `form:"max_mailboxes" json:"max_mailboxes"`
+	MaxMessages  int       `form:"max_messages" json:"max_messages"`
+	MaxSize      int       `form:"max_size" json:"max_size"`
+	Created      time.Time `form:"-" json:"created"`
+}
@@ -0,0 +1,14 @@
+package models
+
+import (
+	"time"
+)
+
+type Thread struct {
+	Id           int        `json:"thread_id"`
+	Subject      string     `json:"subject"`
+	Count        int        `json:"count"`
+	Unread       int        `json:"unread"`
+	LastReceived time.Time  `json:"last_received"`
+	Messages     []*Message `json:"messages,omitempty"`
+}
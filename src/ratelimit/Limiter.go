@@ -0,0 +1,152 @@
+package ratelimit
+
+import (
+	"math"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Interval after which full buckets are removed
+const cleanupInterval = time.Minute
+
+// Token bucket limiter with separate bucket for every key (API key, client IP, sender)
+// Zero rate means there is no limit
+type Limiter struct {
+	// tokens added per minute
+	PerMinute int
+	// max count of tokens in bucket
+	Burst int
+
+	mutex   sync.Mutex
+	buckets map[string]*bucket
+	cleaned time.Time
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// Result of taking token from bucket
+type Result struct {
+	Allowed bool
+	// max count of tokens in bucket
+	Limit int
+	// tokens left after this request
+	Remaining int
+	// time after which bucket is full again
+	Reset time.Duration
+	// time after which next token is available, zero if request is allowed
+	RetryAfter time.Duration
+}
+
+/**
+Create new limiter
+@params perMinute int - count of allowed actions per minute, zero disables limiter
+@params burst int - count of actions allowed at once, perMinute if zero
+
+@return *Limiter
+*/
+func NewLimiter(perMinute int, burst int) *Limiter {
+	if burst <= 0 {
+		burst = perMinute
+	}
+
+	return &Limiter{PerMinute: perMinute, Burst: burst, buckets: make(map[string]*bucket)}
+}
+
+/**
+Create new limiter with settings from environment
+{name} - count of allowed actions per minute, {name}_BURST - count of actions allowed at once
+@params name string - environment variable
+@params perMinute int - default count of actions per minute
+
+@return *Limiter
+*/
+func FromEnv(name string, perMinute int) *Limiter {
+	if value, err := strconv.Atoi(os.Getenv(name)); err == nil {
+		perMinute = value
+	}
+	burst, _ := strconv.Atoi(os.Getenv(name + "_BURST"))
+
+	return NewLimiter(perMinute, burst)
+}
+
+/**
+Check if limiter restricts anything
+
+@return bool
+*/
+func (l *Limiter) Enabled() bool {
+	return l != nil && l.PerMinute > 0
+}
+
+/**
+Take token from bucket of key
+@params key string
+
+@return Result
+*/
+func (l *Limiter) Allow(key string) Result {
+	if !l.Enabled() {
+		return Result{Allowed: true}
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := time.Now()
+	l.cleanup(now)
+
+	current := l.buckets[key]
+	if current == nil {
+		current = &bucket{tokens: float64(l.Burst), updated: now}
+		l.buckets[key] = current
+	}
+	current.refill(now, l.rate(), float64(l.Burst))
+
+	result := Result{Limit: l.Burst}
+	if current.tokens >= 1 {
+		current.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = l.duration(1 - current.tokens)
+	}
+	result.Remaining = int(math.Floor(current.tokens))
+	result.Reset = l.duration(float64(l.Burst) - current.tokens)
+
+	return result
+}
+
+// Tokens added per second
+func (l *Limiter) rate() float64 {
+	return float64(l.PerMinute) / 60
+}
+
+// Time needed to add tokens to bucket
+func (l *Limiter) duration(tokens float64) time.Duration {
+	return time.Duration(math.Ceil(tokens / l.rate() * float64(time.Second)))
+}
+
+// Remove buckets which are full again, so map doesn't grow with every new client
+func (l *Limiter) cleanup(now time.Time) {
+	if now.Sub(l.cleaned) < cleanupInterval {
+		return
+	}
+	l.cleaned = now
+
+	for key, current := range l.buckets {
+		current.refill(now, l.rate(), float64(l.Burst))
+		if current.tokens >= float64(l.Burst) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// Add tokens for time passed since last update
+func (b *bucket) refill(now time.Time, rate float64, burst float64) {
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.updated).Seconds()*rate)
+	b.updated = now
+}
@@ -0,0 +1,168 @@
+package relay
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Connection security modes of upstream server
+const (
+	SecurityNone     = "none"
+	SecurityStartTLS = "starttls"
+	SecurityTLS      = "tls"
+)
+
+const defaultPort = 25
+
+// time of the whole transaction with upstream server in seconds
+const defaultTimeout = 30
+
+// Settings of upstream SMTP server
+type Config struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	// one of SecurityNone, SecurityStartTLS, SecurityTLS
+	Security string
+	// skip verification of upstream server certificate
+	InsecureSkipVerify bool
+	// envelope sender, if empty - sender of original message is used
+	From string
+	// connecting and the whole transaction have to finish in time, so hung server doesn't block release
+	Timeout time.Duration
+}
+
+/**
+Read upstream server settings from environment
+RELAY_HOST, RELAY_PORT, RELAY_USERNAME, RELAY_PASSWORD,
+RELAY_SECURITY (none|starttls|tls), RELAY_INSECURE_SKIP_VERIFY, RELAY_FROM, RELAY_TIMEOUT (seconds)
+
+@return Config
+*/
+func ConfigFromEnv() Config {
+	config := Config{
+		Host:     os.Getenv("RELAY_HOST"),
+		Port:     defaultPort,
+		Username: os.Getenv("RELAY_USERNAME"),
+		Password: os.Getenv("RELAY_PASSWORD"),
+		Security: os.Getenv("RELAY_SECURITY"),
+		From:     os.Getenv("RELAY_FROM"),
+		Timeout:  defaultTimeout * time.Second,
+	}
+
+	if port, err := strconv.Atoi(os.Getenv("RELAY_PORT")); err == nil {
+		config.Port = port
+	}
+	if config.Security == "" {
+		config.Security = SecurityNone
+	}
+	config.InsecureSkipVerify, _ = strconv.ParseBool(os.Getenv("RELAY_INSECURE_SKIP_VERIFY"))
+	if timeout, err := strconv.Atoi(os.Getenv("RELAY_TIMEOUT")); err == nil && timeout > 0 {
+		config.Timeout = time.Duration(timeout) * time.Second
+	}
+
+	return config
+}
+
+/**
+Relay raw message to upstream SMTP server
+@params config Config - upstream server settings
+@params from string - envelope sender
+@params to []string - envelope recipients
+@params data []byte - RFC 5322 message
+
+@return error
+*/
+func Send(config Config, from string, to []string, data []byte) error {
+	if config.Host == "" {
+		return errors.New("Upstream SMTP server is not configured")
+	}
+	if config.From != "" {
+		from = config.From
+	}
+
+	client, err := dial(config)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if config.Username != "" {
+		auth := smtp.PlainAuth("", config.Username, config.Password, config.Host)
+		if err = client.Auth(auth); err != nil {
+			return err
+		}
+	}
+
+	if err = client.Mail(from); err != nil {
+		return err
+	}
+	for _, recipient := range to {
+		if err = client.Rcpt(recipient); err != nil {
+			return err
+		}
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err = writer.Write(data); err != nil {
+		return err
+	}
+	if err = writer.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
+
+/**
+Connect to upstream server using configured security mode
+Deadline of connection limits the whole transaction by timeout of config
+
+@return *smtp.Client, error
+*/
+func dial(config Config) (*smtp.Client, error) {
+	address := net.JoinHostPort(config.Host, strconv.Itoa(config.Port))
+	tlsConfig := &tls.Config{ServerName: config.Host, InsecureSkipVerify: config.InsecureSkipVerify}
+	dialer := &net.Dialer{Timeout: config.Timeout}
+
+	var conn net.Conn
+	var err error
+	switch config.Security {
+	case SecurityNone, SecurityStartTLS:
+		conn, err = dialer.Dial("tcp", address)
+	case SecurityTLS:
+		conn, err = tls.DialWithDialer(dialer, "tcp", address, tlsConfig)
+	default:
+		return nil, fmt.Errorf("Unknown security mode %s", config.Security)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if config.Timeout > 0 {
+		conn.SetDeadline(time.Now().Add(config.Timeout))
+	}
+
+	client, err := smtp.NewClient(conn, config.Host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if config.Security == SecurityStartTLS {
+		if err = client.StartTLS(tlsConfig); err != nil {
+			client.Close()
+			return nil, err
+		}
+	}
+
+	return client, nil
+}
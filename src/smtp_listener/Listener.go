@@ -2,47 +2,380 @@ package smtp_listener
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"health"
+	"io"
 	"io/ioutil"
-	"log"
+	"logger"
+	"mail_address"
+	"mail_auth"
 	"memdb"
+	"metrics"
 	"models"
 	"net"
 	"net/mail"
+	"os"
+	"ratelimit"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
+	"tracing"
 
-	"github.com/mhale/smtpd"
+	"github.com/emersion/go-smtp"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const listenPort = 2525
 
+// name of listener in health status
+const healthName = "smtp"
+
+// default limits of sessions, they are changed by SMTP_MAX_MESSAGE_BYTES, SMTP_MAX_RECIPIENTS
+// and SMTP_TIMEOUT (seconds of reading command or data and writing reply)
+const (
+	defaultMaxMessageBytes = 10 << 20
+	defaultMaxRecipients   = 100
+	defaultTimeout         = 60
+)
+
+// limiters of received messages, RATE_LIMIT_SMTP_IP messages per minute for every client IP
+// and RATE_LIMIT_SMTP_SENDER messages per minute for every envelope sender
+// they are replaced at runtime while sessions are served
+var (
+	clientLimiter atomic.Pointer[ratelimit.Limiter]
+	senderLimiter atomic.Pointer[ratelimit.Limiter]
+)
+
+// settings of authentication checks of received messages
+type authSettings struct {
+	// resolver of DKIM keys, SPF and DMARC records
+	resolver mail_auth.Resolver
+	enabled  bool
+}
+
+// MAIL_AUTH_ZONE_FILE replaces DNS with static zone
+// MAIL_AUTH_CHECKS=false disables authentication checks of received messages
+var (
+	authConfig atomic.Pointer[authSettings]
+	// identifier of Authentication-Results header
+	authServId = hostname()
+)
+
+func init() {
+	clientLimiter.Store(ratelimit.FromEnv("RATE_LIMIT_SMTP_IP", 60))
+	senderLimiter.Store(ratelimit.FromEnv("RATE_LIMIT_SMTP_SENDER", 60))
+	authConfig.Store(&authSettings{resolver: resolverFromEnv(), enabled: os.Getenv("MAIL_AUTH_CHECKS") != "false"})
+}
+
+// SMTP session of single client connection
+type session struct {
+	origin net.Addr
+	// connection of client, its HELO name is known after greeting
+	conn *smtp.Conn
+	// context with session id and span of connection
+	session context.Context
+	span    trace.Span
+	// context with session id, request id and span of current transaction
+	ctx         context.Context
+	transaction trace.Span
+	from        string
+	to          []string
+	// client requested SMTPUTF8 for current transaction
+	utf8 bool
+	// start of current transaction
+	started time.Time
+}
+
 /**
 Create new SMTP listener
 
 @return void
 */
 func Listen() {
-	log.Println("[SMTP]: Start listening on port :2525")
-	// create server
-	smtpd.ListenAndServe(fmt.Sprintf("127.0.0.1:%d", listenPort), mailHandler, "SMTPListener", "")
+	address := fmt.Sprintf("127.0.0.1:%d", listenPort)
+	health.Starting(healthName, "Binding "+address)
+
+	// port is bound separately, so failure is visible in health status
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		logger.Default().Error("SMTP listener failed", "address", address, "error", err)
+		health.Failed(healthName, err)
+		return
+	}
+
+	logger.Default().Info("SMTP listener started", "address", address)
+	health.Ok(healthName, "Listening on "+address)
+
+	if err := Serve(listener); err != nil {
+		logger.Default().Error("SMTP listener stopped", "error", err)
+		health.Failed(healthName, err)
+	}
 }
 
 /**
-Handler for SMTP server
+Serve SMTP clients on existing listener
+@params listener net.Listener
+
+@return error
+*/
+func Serve(listener net.Listener) error {
+	return newServer().Serve(listener)
+}
+
+/**
+Change limits of received messages
+@params perClient int - count of messages per minute for every client IP, zero disables limit
+@params perSender int - count of messages per minute for every envelope sender, zero disables limit
+
+@return void
+*/
+func SetRateLimits(perClient int, perSender int) {
+	clientLimiter.Store(ratelimit.NewLimiter(perClient, 0))
+	senderLimiter.Store(ratelimit.NewLimiter(perSender, 0))
+}
+
+/**
+Replace resolver of authentication checks, nil disables checks
+@params resolver mail_auth.Resolver
+
+@return void
+*/
+func SetResolver(resolver mail_auth.Resolver) {
+	authConfig.Store(&authSettings{resolver: resolver, enabled: resolver != nil})
+}
+
+/**
+Create resolver of authentication checks from environment, DNS is used if zone file can't be read
+
+@return mail_auth.Resolver
+*/
+func resolverFromEnv() mail_auth.Resolver {
+	resolver, err := mail_auth.FromEnv()
+	if err != nil {
+		logger.Default().Error("Zone file of authentication checks can't be read, DNS is used", "error", err)
+		return mail_auth.DNS()
+	}
+
+	return resolver
+}
+
+/**
+Create SMTP server with sessions which save messages to database
+
+@return *smtp.Server
+*/
+func newServer() *smtp.Server {
+	server := smtp.NewServer(smtp.BackendFunc(func(c *smtp.Conn) (smtp.Session, error) {
+		metrics.SmtpSessions.Inc()
+
+		id := logger.NewId()
+		ctx, span := tracing.Start(logger.WithSessionId(context.Background(), id), "smtp session",
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(semconv.NetworkPeerAddress(c.Conn().RemoteAddr().String()), attribute.String(logger.SessionIdKey, id)))
+
+		logger.From(ctx).Info("SMTP session started", "client", c.Conn().RemoteAddr().String())
+		return &session{origin: c.Conn().RemoteAddr(), conn: c, session: ctx, span: span, ctx: ctx}, nil
+	}))
+	server.Domain = authServId
+	server.AllowInsecureAuth = true
+	server.EnableSMTPUTF8 = true
+	// DATA over size is rejected with 552, slow clients are disconnected
+	server.MaxMessageBytes = int64(limitFromEnv("SMTP_MAX_MESSAGE_BYTES", defaultMaxMessageBytes))
+	server.MaxRecipients = limitFromEnv("SMTP_MAX_RECIPIENTS", defaultMaxRecipients)
+	server.ReadTimeout = time.Duration(limitFromEnv("SMTP_TIMEOUT", defaultTimeout)) * time.Second
+	server.WriteTimeout = server.ReadTimeout
+
+	return server
+}
+
+/**
+Read positive limit from environment variable
+@params name string
+@params value int - default value, it is used if variable is not set or invalid
+
+@return int
+*/
+func limitFromEnv(name string, value int) int {
+	if limit, err := strconv.Atoi(os.Getenv(name)); err == nil && limit > 0 {
+		return limit
+	}
+	return value
+}
+
+/**
+Start new message, client IP and sender have to be inside rate limits
+@params from string - envelope sender
+
+@return error
+*/
+func (s *session) Mail(from string, opts *smtp.MailOptions) error {
+	// null sender of bounces is allowed
+	if from != "" {
+		address, err := parsePath(from, opts != nil && opts.UTF8, smtp.EnhancedCode{5, 1, 7}, "Sender")
+		if err != nil {
+			return err
+		}
+		from = address
+	}
+
+	host, _, err := net.SplitHostPort(s.origin.String())
+	if err != nil {
+		host = s.origin.String()
+	}
+
+	if !clientLimiter.Load().Allow(host).Allowed {
+		s.span.AddEvent("rate limit", trace.WithAttributes(attribute.String("smtp.limit", "client")))
+		metrics.DeliveriesRejected.WithLabelValues(metrics.ChannelSmtp, metrics.ReasonRateLimit).Inc()
+		return &smtp.SMTPError{
+			Code:         421,
+			EnhancedCode: smtp.EnhancedCode{4, 7, 0},
+			Message:      "Too many messages from your IP, try again later",
+		}
+	}
+	if !senderLimiter.Load().Allow(strings.ToLower(from)).Allowed {
+		s.span.AddEvent("rate limit", trace.WithAttributes(attribute.String("smtp.limit", "sender")))
+		metrics.DeliveriesRejected.WithLabelValues(metrics.ChannelSmtp, metrics.ReasonRateLimit).Inc()
+		return &smtp.SMTPError{
+			Code:         452,
+			EnhancedCode: smtp.EnhancedCode{4, 7, 1},
+			Message:      "Too many messages from this sender, try again later",
+		}
+	}
+
+	s.endTransaction()
+	s.from = from
+	s.utf8 = opts != nil && opts.UTF8
+	s.started = time.Now()
+
+	// every transaction of session has own request id and span
+	id := logger.NewId()
+	ctx, span := tracing.Start(logger.WithRequestId(s.session, id), "smtp transaction", trace.WithAttributes(
+		attribute.String("smtp.mail_from", from),
+		attribute.String(logger.RequestIdKey, id)))
+	s.ctx = ctx
+	s.transaction = span
+	return nil
+}
+
+func (s *session) Rcpt(to string, opts *smtp.RcptOptions) error {
+	address, err := parsePath(to, s.utf8, smtp.EnhancedCode{5, 1, 3}, "Recipient")
+	if err != nil {
+		return err
+	}
+
+	s.to = append(s.to, address)
+	return nil
+}
+
+func (s *session) Data(r io.Reader) error {
+	// transaction is finished after its DATA span
+	defer s.endTransaction()
+	ctx, span := tracing.Start(s.ctx, "smtp data")
+	defer span.End()
+
+	// reader of data is limited by MaxMessageBytes of server, message over it fails with 552
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		tracing.Fail(span, err)
+		return err
+	}
+	span.SetAttributes(attribute.Int("smtp.message_size", len(data)), attribute.Int("smtp.recipients", len(s.to)))
+
+	err = mailHandler(ctx, s.origin, s.conn.Hostname(), s.from, s.to, data)
+
+	result := "accepted"
+	if err != nil {
+		result = "rejected"
+		tracing.Fail(span, err)
+		tracing.Fail(s.transaction, err)
+	}
+	metrics.SmtpTransactions.WithLabelValues(result).Observe(time.Since(s.started).Seconds())
+
+	return err
+}
+
+func (s *session) Reset() {
+	s.endTransaction()
+	s.from = ""
+	s.to = nil
+}
+
+func (s *session) Logout() error {
+	s.endTransaction()
+	s.span.End()
+
+	logger.From(s.session).Info("SMTP session finished")
+	return nil
+}
+
+/**
+Validate address of MAIL or RCPT command
+@params path string - address decoded by SMTP server
+@params utf8 bool - client requested SMTPUTF8
+@params code smtp.EnhancedCode - status of invalid address
+@params role string - Sender or Recipient
+
+@return string, error - canonical address or SMTP error
+*/
+func parsePath(path string, utf8 bool, code smtp.EnhancedCode, role string) (string, error) {
+	address, err := mail_address.ParsePath(path)
+	if err != nil {
+		metrics.DeliveriesRejected.WithLabelValues(metrics.ChannelSmtp, metrics.ReasonAddress).Inc()
+		return "", &smtp.SMTPError{Code: 553, EnhancedCode: code, Message: fmt.Sprintf("%s address is invalid: %s", role, err)}
+	}
+	if address.RequiresSMTPUTF8() && !utf8 {
+		metrics.DeliveriesRejected.WithLabelValues(metrics.ChannelSmtp, metrics.ReasonAddress).Inc()
+		return "", &smtp.SMTPError{Code: 553, EnhancedCode: smtp.EnhancedCode{5, 6, 7}, Message: role + " address requires SMTPUTF8"}
+	}
+
+	return address.String(), nil
+}
+
+/**
+Finish span of current transaction, if transaction is started
 
 @return void
 */
-func mailHandler(origin net.Addr, from string, to []string, data []byte) {
+func (s *session) endTransaction() {
+	if s.transaction == nil {
+		return
+	}
+
+	s.transaction.End()
+	s.transaction = nil
+	s.ctx = s.session
+}
+
+/**
+Handler for SMTP server
+@params ctx context.Context - correlation ids of session and transaction
+@params origin net.Addr - address of client
+@params helo string - HELO name of client
+
+@return error
+*/
+func mailHandler(ctx context.Context, origin net.Addr, helo string, from string, to []string, data []byte) error {
 
 	// read message from request data
-	msg, _ := mail.ReadMessage(bytes.NewReader(data))
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		metrics.DeliveriesRejected.WithLabelValues(metrics.ChannelSmtp, metrics.ReasonParse).Inc()
+		logger.From(ctx).Warn("Message can not be parsed", "from", from, "error", err)
+		return &smtp.SMTPError{Code: 554, EnhancedCode: smtp.EnhancedCode{5, 6, 0}, Message: "Message can not be parsed"}
+	}
 	// get mail subject
 	subject := msg.Header.Get("Subject")
 	// read body raw data
 	body, err := ioutil.ReadAll(msg.Body)
 	if err != nil {
-		log.Printf("Error on parsing mail: %v", err.Error())
-		return
+		metrics.DeliveriesRejected.WithLabelValues(metrics.ChannelSmtp, metrics.ReasonParse).Inc()
+		logger.From(ctx).Warn("Message body can not be read", "from", from, "error", err)
+		return &smtp.SMTPError{Code: 554, EnhancedCode: smtp.EnhancedCode{5, 6, 0}, Message: "Message can not be parsed"}
 	}
 
 	// create new data model
@@ -51,19 +384,97 @@ func mailHandler(origin net.Addr, from string, to []string, data []byte) {
 		From:         from,
 		Subject:      subject,
 		Body:         string(body[:]),
+		Raw:          data,
 		ReceivedDate: time.Now(),
 	}
+	insertMessage.SetThreadHeaders(msg.Header)
+	if config := authConfig.Load(); config.enabled {
+		authenticate(ctx, config.resolver, insertMessage, origin, helo, msg.Header.Get("From"))
+	}
 
-	// get DB instance
-	instance := memdb.GetInstance()
+	// get DB instance inside tenant of recipient
+	instance := memdb.GetInstance().Tenant(memdb.AddressTenant(insertMessage.To)).WithContext(ctx)
 
 	// trying to insert new message
 	response := instance.InsertMessage(insertMessage.To, insertMessage)
 
 	if !response.Success {
-		log.Printf("Error on posting message: %v", response.Error)
-		return
+		metrics.DeliveriesRejected.WithLabelValues(metrics.ChannelSmtp, response.Code.String()).Inc()
+		logger.From(ctx).Warn("Message is rejected", "from", from, "to", insertMessage.To, "code", response.Code.String(), "error", response.Error)
+		return commandError(response)
+	}
+	metrics.MessagesReceived.WithLabelValues(metrics.ChannelSmtp).Inc()
+
+	logger.From(ctx).Info("Message received", "from", from, "to", to[0], "subject", subject, "size", len(data))
+	return nil
+}
+
+/**
+Check DKIM signatures, SPF of envelope sender and DMARC of From domain
+Results are saved to message and prepended to its data as Authentication-Results header
+@params ctx context.Context
+@params resolver mail_auth.Resolver - resolver of DKIM keys, SPF and DMARC records
+@params message *models.Message - message with envelope sender and original data
+@params origin net.Addr - address of client
+@params helo string - HELO name of client
+@params from string - From header
+
+@return void
+*/
+func authenticate(ctx context.Context, resolver mail_auth.Resolver, message *models.Message, origin net.Addr, helo string, from string) {
+	ctx, span := tracing.Start(ctx, "mail authentication")
+	defer span.End()
+
+	var ip net.IP
+	if address, ok := origin.(*net.TCPAddr); ok {
+		ip = address.IP
+	}
+
+	dkim := mail_auth.VerifyDkim(ctx, resolver, message.Raw)
+	spf := mail_auth.CheckSpf(ctx, resolver, ip, helo, message.From)
+	dmarc := mail_auth.CheckDmarc(ctx, resolver, from, spf, dkim)
+	message.Dkim, message.Spf, message.Dmarc = dkim, &spf, &dmarc
+	message.Raw = append([]byte(mail_auth.ResultsHeader(authServId, dkim, &spf, &dmarc)), message.Raw...)
+
+	for _, result := range dkim {
+		logger.From(ctx).Info("DKIM signature verified",
+			"domain", result.Domain, "selector", result.Selector, "result", result.Result, "reason", result.Reason)
+	}
+	logger.From(ctx).Info("Sender authenticated", "spf", spf.Result, "spf_domain", spf.Domain,
+		"dmarc", dmarc.Result, "dmarc_domain", dmarc.Domain, "reason", dmarc.Reason)
+	span.SetAttributes(
+		attribute.Int("dkim.signatures", len(dkim)),
+		attribute.String("spf.result", spf.Result),
+		attribute.String("dmarc.result", dmarc.Result))
+}
+
+/**
+Get host name of server, it identifies server in SMTP greeting and Authentication-Results header
+
+@return string
+*/
+func hostname() string {
+	name, err := os.Hostname()
+	if err != nil || name == "" {
+		return "localhost"
+	}
+
+	return name
+}
+
+/**
+Convert failed database command to SMTP reply
+@params response memdb.CommandResult
+
+@return error
+*/
+func commandError(response memdb.CommandResult) error {
+	switch response.Code {
+	case memdb.ErrorNotFound:
+		return &smtp.SMTPError{Code: 550, EnhancedCode: smtp.EnhancedCode{5, 1, 1}, Message: response.Error}
+	case memdb.ErrorQuotaExceeded:
+		return &smtp.SMTPError{Code: 452, EnhancedCode: smtp.EnhancedCode{4, 2, 2}, Message: response.Error}
 	}
 
-	log.Printf("Received mail from %s for %s with subject %s", from, to[0], subject)
+	return errors.New(response.Error)
 }
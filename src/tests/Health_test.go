@@ -0,0 +1,47 @@
+package tests
+
+import (
+	"errors"
+	"health"
+	"net/http"
+	"testing"
+	"time"
+)
+
+//Test liveness and readiness follow reported state of components
+func Test_Health_Endpoints(t *testing.T) {
+	health.Starting("test-listener", "Binding port")
+	defer health.Ok("test-listener", "Listening")
+
+	code, body := apiRequest("GET", "/healthz", "")
+	components, _ := body["components"].(map[string]interface{})
+	if code != http.StatusOK || components["memdb"] == nil || components["test-listener"] == nil {
+		t.Errorf("Starting component fails liveness: %d %v", code, body)
+	}
+
+	code, _ = apiRequest("GET", "/readyz", "")
+	if code != http.StatusServiceUnavailable {
+		t.Errorf("Service is ready while component is starting: %d", code)
+	}
+
+	health.Ok("test-listener", "Listening")
+	code, body = apiRequest("GET", "/readyz", "")
+	if code != http.StatusOK || body["status"] != "ok" {
+		t.Errorf("Service is not ready: %d %v", code, body)
+	}
+
+	health.Failed("test-listener", errors.New("address already in use"))
+	code, body = apiRequest("GET", "/healthz", "")
+	if code != http.StatusServiceUnavailable || body["status"] != "unavailable" {
+		t.Errorf("Failed component doesn't fail liveness: %d %v", code, body)
+	}
+
+	health.Ok("test-listener", "Listening")
+	health.Expect("test-listener", time.Millisecond)
+	defer health.Expect("test-listener", 0)
+	time.Sleep(5 * time.Millisecond)
+
+	if health.Components()["test-listener"].Status != health.StatusFailed {
+		t.Errorf("Outdated component is not failed")
+	}
+}
@@ -0,0 +1,171 @@
+package tests
+
+import (
+	"fmt"
+	"imap_listener"
+	"memdb"
+	"models"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/client"
+)
+
+//Start IMAP listener on random port, it is closed when test ends
+func startImap(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Can not start listener: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go imap_listener.Serve(listener)
+
+	return listener.Addr().String()
+}
+
+//Create mailbox of imap tenant with messages and log in to it
+func imapMailbox(t *testing.T, subjects ...string) (*client.Client, string) {
+	memdb.GetInstance().InsertTenant(&models.Tenant{Name: "imap"})
+	tenant := memdb.GetInstance().Tenant("imap")
+
+	address := tenant.InsertMailBox().Value.(*models.MailBox).Address
+	for _, subject := range subjects {
+		raw := fmt.Sprintf("From: from@some.domain\r\nTo: %s\r\nSubject: %s\r\n\r\n%s\r\n", address, subject, subject)
+		tenant.InsertMessage(address, &models.Message{To: address, From: "from@some.domain", Subject: subject, Raw: []byte(raw), ReceivedDate: time.Now()})
+	}
+
+	imapClient, err := client.Dial(startImap(t))
+	if err != nil {
+		t.Fatalf("Can not connect to listener: %v", err)
+	}
+	t.Cleanup(func() { imapClient.Logout() })
+
+	if err := imapClient.Login(address, "password"); err != nil {
+		t.Fatalf("Login is rejected: %v", err)
+	}
+
+	return imapClient, address
+}
+
+//Fetch items of messages in sequence set
+func imapFetch(t *testing.T, imapClient *client.Client, set string, items ...imap.FetchItem) []*imap.Message {
+	seqSet, _ := imap.ParseSeqSet(set)
+	fetched := make(chan *imap.Message, 10)
+	if err := imapClient.Fetch(seqSet, items, fetched); err != nil {
+		t.Fatalf("FETCH is rejected: %v", err)
+	}
+
+	messages := []*imap.Message{}
+	for message := range fetched {
+		messages = append(messages, message)
+	}
+	return messages
+}
+
+//Test login to inexisting mailbox is rejected
+func Test_Imap_Login(t *testing.T) {
+	imapClient, err := client.Dial(startImap(t))
+	if err != nil {
+		t.Fatalf("Can not connect to listener: %v", err)
+	}
+	defer imapClient.Logout()
+
+	if err := imapClient.Login("inexisting@imap.some.domain", "password"); err == nil {
+		t.Errorf("Login to inexisting mailbox is accepted")
+	}
+}
+
+//Test selected mailbox has messages with envelope and reading of body marks message as seen
+func Test_Imap_Select_Fetch(t *testing.T) {
+	imapClient, address := imapMailbox(t, "First", "Second")
+
+	status, err := imapClient.Select("INBOX", false)
+	if err != nil || status.Messages != 2 || status.UnseenSeqNum != 1 {
+		t.Fatalf("Wrong status of selected mailbox: %v %v", status, err)
+	}
+
+	messages := imapFetch(t, imapClient, "1:*", imap.FetchEnvelope, imap.FetchUid, imap.FetchFlags)
+	if len(messages) != 2 || messages[0].Envelope.Subject != "First" || messages[1].Envelope.Subject != "Second" || messages[0].Uid == 0 {
+		t.Fatalf("Wrong fetched messages: %v", messages)
+	}
+
+	section := &imap.BodySectionName{}
+	imapFetch(t, imapClient, "2", section.FetchItem())
+
+	stored := memdb.GetInstance().Tenant("imap").GetMessage(address, int(messages[1].Uid)).Value.(*models.Message)
+	if !stored.Seen {
+		t.Errorf("Read message is not marked as seen")
+	}
+}
+
+//Test search by subject, storing of flags and removing of deleted messages
+func Test_Imap_Search_Store_Expunge(t *testing.T) {
+	imapClient, address := imapMailbox(t, "Invoice", "Newsletter", "Invoice reminder")
+	if _, err := imapClient.Select("INBOX", false); err != nil {
+		t.Fatalf("SELECT is rejected: %v", err)
+	}
+
+	criteria := imap.NewSearchCriteria()
+	criteria.Header.Add("Subject", "invoice")
+	found, err := imapClient.Search(criteria)
+	if err != nil || fmt.Sprint(found) != "[1 3]" {
+		t.Errorf("Wrong search result: %v %v", found, err)
+	}
+
+	seqSet, _ := imap.ParseSeqSet("2")
+	if err := imapClient.Store(seqSet, imap.FormatFlagsOp(imap.AddFlags, true), []interface{}{imap.DeletedFlag}, nil); err != nil {
+		t.Fatalf("STORE is rejected: %v", err)
+	}
+	seqSet, _ = imap.ParseSeqSet("1")
+	if err := imapClient.Store(seqSet, imap.FormatFlagsOp(imap.AddFlags, true), []interface{}{imap.FlaggedFlag}, nil); err != nil {
+		t.Fatalf("STORE is rejected: %v", err)
+	}
+
+	if err := imapClient.Expunge(nil); err != nil {
+		t.Fatalf("EXPUNGE is rejected: %v", err)
+	}
+
+	messages := memdb.GetInstance().Tenant("imap").GetMailBox(address).Value.(*models.MailBox).Messages
+	if len(messages) != 2 || messages[0].Subject != "Invoice" || !messages[0].Flagged || messages[1].Subject != "Invoice reminder" {
+		t.Errorf("Wrong messages after expunge: %v", messages)
+	}
+}
+
+//Test idle client is notified about new message
+func Test_Imap_Idle(t *testing.T) {
+	imapClient, address := imapMailbox(t, "First")
+	if _, err := imapClient.Select("INBOX", false); err != nil {
+		t.Fatalf("SELECT is rejected: %v", err)
+	}
+
+	updates := make(chan client.Update, 10)
+	imapClient.Updates = updates
+	stop := make(chan struct{})
+	idle := make(chan error, 1)
+	go func() {
+		idle <- imapClient.Idle(stop, nil)
+	}()
+	//Message is saved after client is waiting for updates
+	time.Sleep(100 * time.Millisecond)
+
+	memdb.GetInstance().Tenant("imap").InsertMessage(address, &models.Message{To: address, Subject: "Second", ReceivedDate: time.Now()})
+
+	timeout := time.After(time.Second)
+	for received := false; !received; {
+		select {
+		case update := <-updates:
+			if mailbox, ok := update.(*client.MailboxUpdate); ok && mailbox.Mailbox.Messages == 2 {
+				received = true
+			}
+		case <-timeout:
+			t.Fatalf("Idle client is not notified")
+		}
+	}
+
+	close(stop)
+	if err := <-idle; err != nil {
+		t.Errorf("IDLE is failed: %v", err)
+	}
+}
@@ -303,3 +303,297 @@ func Test_InMemoryDb_Clear_Not_Relevant_Messages(t *testing.T) {
 	}
 
 }
+
+//Test gets copy of mailbox with messages in order of receiving
+func Test_InMemoryDb_Get_Mailbox(t *testing.T) {
+	db := memdb.GetInstance()
+
+	db.InsertMessage("email_5@some.domain", &models.Message{To: "first@email.com"})
+	db.InsertMessage("email_5@some.domain", &models.Message{To: "second@email.com"})
+
+	result := db.GetMailBox("email_5@some.domain")
+
+	if !result.Success {
+		t.Error("Select operation is not successfully")
+	}
+
+	mailbox := result.Value.(*models.MailBox)
+
+	if len(mailbox.Messages) != 2 || mailbox.Messages[0].Id > mailbox.Messages[1].Id {
+		t.Error("Wrong messages are returned")
+	}
+
+	if db.GetMailBox("inexisting@mail.box").Success {
+		t.Error("Select operation is successfully, but shouldn`t")
+	}
+}
+
+//Test updates flags of message
+func Test_InMemoryDb_Set_Message_Flags(t *testing.T) {
+	db := memdb.GetInstance()
+
+	insertResult := db.InsertMessage("email_5@some.domain", &models.Message{To: "some@email.com"})
+	messageId := insertResult.Value.(*models.Message).Id
+
+	result := db.SetMessageFlags("email_5@some.domain", messageId, []string{models.SeenFlag, "\\Deleted"})
+
+	if !result.Success {
+		t.Error("Update operation is not successfully")
+	}
+
+	message := db.GetMessage("email_5@some.domain", messageId).Value.(*models.Message)
+
+	if !message.Seen || message.Flagged || len(message.Flags) != 1 || message.Flags[0] != "\\Deleted" {
+		t.Error("Flags are not updated")
+	}
+
+	if db.SetMessageFlags("email_5@some.domain", 54364, nil).Success {
+		t.Error("Update operation is successfully, but shouldn`t")
+	}
+}
+
+//Test subscriber receives events about inserted and deleted messages
+func Test_InMemoryDb_Subscribe(t *testing.T) {
+	db := memdb.GetInstance()
+
+	events := db.Subscribe()
+	defer db.Unsubscribe(events)
+
+	insertResult := db.InsertMessage("email_7@some.domain", &models.Message{To: "some@email.com"})
+	db.DeleteMessage("email_7@some.domain", insertResult.Value.(*models.Message).Id)
+
+	inserted := <-events
+	if inserted.Type != memdb.MessageInserted || inserted.Address != "email_7@some.domain" || inserted.Index != 0 {
+		t.Error("Wrong insert event is received")
+	}
+
+	deleted := <-events
+	if deleted.Type != memdb.MessageDeleted || deleted.Message.Id != insertResult.Value.(*models.Message).Id {
+		t.Error("Wrong delete event is received")
+	}
+}
+
+//Test changes seen and flagged state of message
+func Test_InMemoryDb_Update_Message_Flags(t *testing.T) {
+	db := memdb.GetInstance()
+
+	insertResult := db.InsertMessage("email_8@some.domain", &models.Message{To: "some@email.com"})
+	messageId := insertResult.Value.(*models.Message).Id
+
+	flagged := true
+	result := db.UpdateMessageFlags("email_8@some.domain", messageId, models.MessageFlags{Flagged: &flagged})
+
+	if !result.Success {
+		t.Error("Update operation is not successfully")
+	}
+
+	message := result.Value.(*models.Message)
+
+	if !message.Flagged || message.Seen {
+		t.Error("Flags are not updated")
+	}
+}
+
+//Test filters messages by seen state
+func Test_InMemoryDb_Filter_Unread_Messages(t *testing.T) {
+	db := memdb.GetInstance()
+
+	seen := true
+	unread := false
+
+	readResult := db.InsertMessage("email_8@some.domain", &models.Message{To: "some@email.com"})
+	db.UpdateMessageFlags("email_8@some.domain", readResult.Value.(*models.Message).Id, models.MessageFlags{Seen: &seen})
+	db.InsertMessage("email_8@some.domain", &models.Message{To: "some@email.com"})
+
+	result := db.FilterMailBoxMessages("email_8@some.domain", &memdb.PageCursor{Count: 10}, &memdb.MessageFilter{Seen: &unread})
+
+	for _, message := range result.Value.([]*models.Message) {
+		if message.Seen {
+			t.Error("Seen message is returned by unread filter")
+		}
+	}
+
+	mailbox := db.GetMailBox("email_8@some.domain").Value.(*models.MailBox)
+
+	if result.Rows != mailbox.UnreadCount() || result.Rows == 0 {
+		t.Error("Wrong count of unread messages")
+	}
+
+	//Unread count of whole mailbox doesn't depend on filter
+	cursor := &memdb.PageCursor{Count: 1}
+	flagged := true
+	db.FilterMailBoxMessages("email_8@some.domain", cursor, &memdb.MessageFilter{Flagged: &flagged})
+
+	if cursor.Unread != mailbox.UnreadCount() {
+		t.Errorf("Wrong count of unread messages of cursor: %d", cursor.Unread)
+	}
+}
+
+//Test groups messages to threads by headers and subject
+func Test_InMemoryDb_Threads(t *testing.T) {
+	db := memdb.GetInstance()
+
+	address := db.InsertMailBox().Value.(*models.MailBox).Address
+
+	//Saved messages are read back, inserted messages are not changed by database
+	saved := []*models.Message{}
+	for _, message := range []*models.Message{
+		{Subject: "Sign up", MessageID: "<original@some.domain>"},
+		{Subject: "Confirmation", InReplyTo: "<original@some.domain>"},
+		{Subject: "Fwd: RE: sign up"},
+		{Subject: "Password reset"},
+	} {
+		id := db.InsertMessage(address, message).Value.(*models.Message).Id
+		saved = append(saved, db.GetMessage(address, id).Value.(*models.Message))
+	}
+	original, reply, forward, other := saved[0], saved[1], saved[2], saved[3]
+
+	if reply.ThreadId != original.ThreadId || forward.ThreadId != original.ThreadId {
+		t.Error("Messages are not grouped to the same thread")
+	}
+
+	if other.ThreadId == original.ThreadId {
+		t.Error("Different conversations are grouped to the same thread")
+	}
+
+	threadsResult := db.GetMailBoxThreads(address)
+
+	if !threadsResult.Success || threadsResult.Rows != 2 {
+		t.Error("Wrong count of threads")
+	}
+
+	threadResult := db.GetThread(address, original.ThreadId)
+	thread := threadResult.Value.(*models.Thread)
+
+	if thread.Count != 3 || thread.Messages[0].Id != original.Id {
+		t.Error("Wrong messages of thread are returned")
+	}
+
+	if db.GetThread(address, 54364).Success {
+		t.Error("Select operation is successfully, but shouldn`t")
+	}
+}
+
+//Test pages messages forward from oldest one and reports total count
+func Test_InMemoryDb_Get_Messages_Ascending(t *testing.T) {
+	db := memdb.GetInstance()
+
+	address := db.InsertMailBox().Value.(*models.MailBox).Address
+	for i := 0; i < 5; i++ {
+		db.InsertMessage(address, &models.Message{To: "client@email.com"})
+	}
+
+	page1 := &memdb.PageCursor{Count: 3, Ascending: true}
+	messages1 := db.GetMailBoxMessages(address, page1).Value.([]*models.Message)
+
+	if !(messages1[0].Id < messages1[1].Id && messages1[1].Id < messages1[2].Id) {
+		t.Error("Wrong order of messages is returned")
+	}
+
+	if !page1.HasMore || page1.Total != 5 {
+		t.Error("Wrong state of first page")
+	}
+
+	page2 := &memdb.PageCursor{Count: 3, Ascending: true, SinceId: &messages1[2].Id}
+	messages2 := db.GetMailBoxMessages(address, page2).Value.([]*models.Message)
+
+	if len(messages2) != 2 || messages2[0].Id <= messages1[2].Id {
+		t.Error("Wrong messages of second page are returned")
+	}
+
+	if page2.HasMore || page2.Total != 5 {
+		t.Error("Wrong state of last page")
+	}
+}
+
+//Test lists mailboxes by domain with statistics
+func Test_InMemoryDb_Get_Mailboxes(t *testing.T) {
+	db := memdb.GetInstance()
+
+	address := db.InsertMailBox().Value.(*models.MailBox).Address
+	db.InsertMessage(address, &models.Message{Subject: "Stats", ReceivedDate: time.Now()})
+
+	page := &memdb.PageCursor{Count: 1000}
+	result := db.GetMailBoxes(page, &memdb.MailBoxFilter{Domain: "some.domain"})
+
+	if !result.Success || result.Rows == 0 || page.Total != result.Rows {
+		t.Error("Mailboxes are not returned")
+	}
+
+	var stats *models.MailBoxStats
+	for _, item := range result.Value.([]*models.MailBoxStats) {
+		if item.Address == address {
+			stats = item
+		}
+	}
+
+	if stats == nil || stats.Messages != 1 || stats.Unread != 1 || stats.Size == 0 || stats.LastReceived == nil {
+		t.Error("Wrong statistics of mailbox")
+	}
+
+	other := db.GetMailBoxes(&memdb.PageCursor{Count: 10}, &memdb.MailBoxFilter{Domain: "other.domain"})
+
+	if other.Rows != 0 {
+		t.Error("Mailboxes from other domain are returned")
+	}
+}
+
+//Test inserted and read messages are copies which are not changed by engine
+func Test_InMemoryDb_Read_Copies(t *testing.T) {
+	db := memdb.GetInstance()
+
+	address := db.InsertMailBox().Value.(*models.MailBox).Address
+	inserted := &models.Message{To: address}
+	id := db.InsertMessage(address, inserted).Value.(*models.Message).Id
+
+	if inserted.Id != 0 || inserted.ThreadId != 0 {
+		t.Error("Inserted message of caller is changed by engine")
+	}
+
+	message := db.GetMessage(address, id).Value.(*models.Message)
+	mailbox := db.GetMailBox(address).Value.(*models.MailBox)
+	messages := db.GetMailBoxMessages(address, &memdb.PageCursor{Count: 1}).Value.([]*models.Message)
+
+	db.SetMessageFlags(address, id, []string{models.SeenFlag, "$Label"})
+	db.AddMessageRelease(address, id, models.Release{To: address})
+
+	for _, read := range []*models.Message{message, mailbox.Messages[0], messages[0]} {
+		if read.Seen || len(read.Flags) != 0 || len(read.Releases) != 0 {
+			t.Error("Read message is changed by engine")
+		}
+	}
+
+	message.Flags = append(message.Flags, "$Changed")
+	if stored := db.GetMessage(address, id).Value.(*models.Message); !stored.Seen || len(stored.Flags) != 1 {
+		t.Error("Stored message is changed by reader")
+	}
+}
+
+//Test chanel of slow subscriber is closed instead of losing events silently
+func Test_InMemoryDb_Subscriber_Overflow(t *testing.T) {
+	db := memdb.GetInstance()
+
+	address := db.InsertMailBox().Value.(*models.MailBox).Address
+	events := db.Subscribe()
+	for i := 0; i <= 100; i++ {
+		db.InsertMessage(address, &models.Message{To: address})
+	}
+
+	received := 0
+	for range events {
+		received++
+	}
+	if received != 100 {
+		t.Errorf("Slow subscriber received %d events before its chanel is closed", received)
+	}
+
+	if !db.Unsubscribe(events).Success {
+		t.Error("Removed subscriber can not be unsubscribed")
+	}
+
+	events = db.Subscribe()
+	db.Unsubscribe(events)
+	if _, open := <-events; open {
+		t.Error("Chanel of unsubscribed subscriber is not closed")
+	}
+}
@@ -0,0 +1,82 @@
+package tests
+
+import (
+	"api"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"logger"
+	"memdb"
+	"models"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+)
+
+//Test request id is returned to client and written to every log line of request
+func Test_Logging_Request_Id(t *testing.T) {
+	var output bytes.Buffer
+	logger.SetOutput(&output)
+	defer logger.SetOutput(os.Stdout)
+	logger.SetLevel("debug")
+	defer logger.SetLevel("info")
+
+	address := memdb.GetInstance().InsertMailBox().Value.(*models.MailBox).Address
+	form := url.Values{"from": {"from@some.domain"}, "to": {address}, "subject": {"Logging"}, "message": {"Logging"}}
+
+	request, _ := http.NewRequest("POST", fmt.Sprintf("/mailboxes/%s/messages", address), strings.NewReader(form.Encode()))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("X-Request-ID", "test-request")
+	recorder := httptest.NewRecorder()
+	api.Handle().ServeHTTP(recorder, request)
+
+	if recorder.Header().Get("X-Request-ID") != "test-request" {
+		t.Errorf("Request id is not returned: %q", recorder.Header().Get("X-Request-ID"))
+	}
+
+	messages := map[string]bool{}
+	for _, line := range strings.Split(strings.TrimSpace(output.String()), "\n") {
+		entry := map[string]interface{}{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("Log line is not JSON: %s", line)
+		}
+		if entry["request_id"] == "test-request" {
+			messages[entry["msg"].(string)] = true
+		}
+	}
+
+	for _, message := range []string{"Database command executed", "Message saved", "Request handled"} {
+		if !messages[message] {
+			t.Errorf("Log line has no request id: %s", message)
+		}
+	}
+
+	recorder = httptest.NewRecorder()
+	api.Handle().ServeHTTP(recorder, httptest.NewRequest("GET", "/healthz", nil))
+	if len(recorder.Header().Get("X-Request-ID")) != 16 {
+		t.Errorf("Request id is not generated: %q", recorder.Header().Get("X-Request-ID"))
+	}
+}
+
+//Test log level is changed at runtime
+func Test_Logging_Level(t *testing.T) {
+	defer logger.SetLevel("info")
+
+	code, body := apiFormRequest("PUT", "/log/level", "", url.Values{"level": {"debug"}})
+	if code != http.StatusOK || body["level"] != "debug" || logger.Level() != "debug" {
+		t.Errorf("Log level is not changed: %d %v", code, body)
+	}
+
+	code, _ = apiFormRequest("PUT", "/log/level", "", url.Values{"level": {"verbose"}})
+	if code != http.StatusBadRequest {
+		t.Errorf("Invalid log level is accepted: %d", code)
+	}
+
+	code, body = apiRequest("GET", "/log/level", "")
+	if code != http.StatusOK || body["level"] != "debug" {
+		t.Errorf("Log level is not returned: %d %v", code, body)
+	}
+}
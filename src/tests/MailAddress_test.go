@@ -0,0 +1,157 @@
+package tests
+
+import (
+	"fmt"
+	"mail_address"
+	"memdb"
+	"models"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+//Test valid forms of mailbox are parsed to canonical address and display name
+func Test_Address_Parse(t *testing.T) {
+	cases := []struct {
+		value   string
+		address string
+		name    string
+	}{
+		{"email_1@some.domain", "email_1@some.domain", ""},
+		{"  first.last+tag@some.domain  ", "first.last+tag@some.domain", ""},
+		{`"john doe"@some.domain`, `"john doe"@some.domain`, ""},
+		{`"john\"quote"@some.domain`, `"john\"quote"@some.domain`, ""},
+		{`"simple"@some.domain`, "simple@some.domain", ""},
+		{"user@[192.0.2.1]", "user@[192.0.2.1]", ""},
+		{"user@[IPv6:2001:DB8::1]", "user@[IPv6:2001:db8::1]", ""},
+		{"user@bücher.example", "user@xn--bcher-kva.example", ""},
+		{"пользователь@пример.рф", "пользователь@xn--e1afmkfd.xn--p1ai", ""},
+		{`"Doe, John" <john@some.domain>`, "john@some.domain", "Doe, John"},
+		{"John Q. Public <john@some.domain>", "john@some.domain", "John Q. Public"},
+		{"=?utf-8?q?J=C3=BCrgen?= <juergen@some.domain>", "juergen@some.domain", "Jürgen"},
+		{"<john@some.domain>", "john@some.domain", ""},
+	}
+
+	for _, c := range cases {
+		address, err := mail_address.Parse(c.value)
+		if err != nil {
+			t.Errorf("Address %s is not parsed: %v", c.value, err)
+			continue
+		}
+		if address.String() != c.address || address.Name != c.name {
+			t.Errorf("Address %s is parsed to %s with name %q instead of %s with name %q", c.value, address, address.Name, c.address, c.name)
+		}
+	}
+
+	//Unicode form of domain and header form of display name
+	address, _ := mail_address.Parse(`"Doe, Jürgen" <user@bücher.example>`)
+	if address.Unicode() != "user@bücher.example" || address.Header() != "=?utf-8?q?Doe,_J=C3=BCrgen?= <user@xn--bcher-kva.example>" {
+		t.Errorf("Address is formatted as %s and %s", address.Unicode(), address.Header())
+	}
+}
+
+//Test invalid addresses are rejected with the invalid part
+func Test_Address_Parse_Errors(t *testing.T) {
+	cases := []struct {
+		value string
+		part  string
+	}{
+		{"", mail_address.PartAddress},
+		{"text before a@b.c", mail_address.PartLocal},
+		{"a@b.c text after", mail_address.PartDomain},
+		{"a@b@c.d", mail_address.PartLocal},
+		{"no-at-sign", mail_address.PartAddress},
+		{"@some.domain", mail_address.PartLocal},
+		{".dot@some.domain", mail_address.PartLocal},
+		{"two..dots@some.domain", mail_address.PartLocal},
+		{`"unclosed@some.domain`, mail_address.PartLocal},
+		{strings.Repeat("a", 65) + "@some.domain", mail_address.PartLocal},
+		{"user@", mail_address.PartDomain},
+		{"user@-some.domain", mail_address.PartDomain},
+		{"user@some..domain", mail_address.PartDomain},
+		{"user@some_domain.com", mail_address.PartDomain},
+		{"user@" + strings.Repeat("a", 64) + ".domain", mail_address.PartDomain},
+		{"user@[300.1.1.1]", mail_address.PartDomain},
+		{"user@xn--zz.domain", mail_address.PartDomain},
+		{"Name, Other <user@some.domain>", mail_address.PartDisplayName},
+		{"Name user@some.domain>", mail_address.PartAddress},
+	}
+
+	for _, c := range cases {
+		_, err := mail_address.Parse(c.value)
+		failure, ok := err.(*mail_address.Error)
+		if !ok || failure.Part != c.part {
+			t.Errorf("Address %q is rejected with %v instead of %s error", c.value, err, c.part)
+		}
+	}
+}
+
+//Test API reports every invalid address field and saves canonical addresses
+func Test_Address_API_Fields(t *testing.T) {
+	address := memdb.GetInstance().InsertMailBox().Value.(*models.MailBox).Address
+	path := fmt.Sprintf("/mailboxes/%s/messages", address)
+
+	form := url.Values{"from": {"a@b.c trailing"}, "to": {"<" + address}, "subject": {"Invalid"}, "message": {"Invalid"}}
+	code, body := apiFormRequest("POST", path, "", form)
+	fields, _ := body["fields"].([]interface{})
+	if code != http.StatusBadRequest || body["error"] != "bad_request" || len(fields) != 2 {
+		t.Fatalf("Wrong response for invalid addresses: %d %v", code, body)
+	}
+	if field := fields[0].(map[string]interface{}); field["field"] != "to" || field["value"] != "<"+address || field["message"] == "" {
+		t.Errorf("Wrong error of to field: %v", field)
+	}
+
+	form = url.Values{"from": {`"Doe, John" <john@bücher.example>`}, "to": {"Some Name <" + address + ">"}, "subject": {"Valid"}, "message": {"Valid"}}
+	code, body = apiFormRequest("POST", path, "", form)
+	if code != http.StatusOK {
+		t.Fatalf("Message with display names is rejected: %d %v", code, body)
+	}
+	messages := memdb.GetInstance().GetMailBoxMessages(address, &memdb.PageCursor{Count: 1}).Value.([]*models.Message)
+	if messages[0].From != `"Doe, John" <john@xn--bcher-kva.example>` || messages[0].To != address {
+		t.Errorf("Addresses are saved as %s and %s", messages[0].From, messages[0].To)
+	}
+
+	code, body = apiRequest("GET", "/mailboxes/not-an-address/messages", "")
+	if fields, _ := body["fields"].([]interface{}); code != http.StatusBadRequest || len(fields) != 1 {
+		t.Errorf("Wrong response for invalid mailbox of path: %d %v", code, body)
+	}
+}
+
+//Test SMTP rejects invalid addresses and requires SMTPUTF8 for UTF-8 local parts
+func Test_Address_SMTP(t *testing.T) {
+	//Commands are sent as is, client of net/smtp adds SMTPUTF8 parameter by itself
+	conn, err := textproto.Dial("tcp", startSmtp(t))
+	if err != nil {
+		t.Fatalf("Can not connect: %v", err)
+	}
+	defer conn.Close()
+	command := func(line string) (int, string) {
+		id, _ := conn.Cmd("%s", line)
+		conn.StartResponse(id)
+		defer conn.EndResponse(id)
+		code, message, _ := conn.ReadResponse(0)
+		return code, message
+	}
+	conn.ReadResponse(220)
+	command("EHLO localhost")
+
+	cases := []struct {
+		line string
+		code int
+	}{
+		{"MAIL FROM:<from@-some.domain>", 553},
+		{"MAIL FROM:<from@some.domain>", 250},
+		{"RCPT TO:<to@some..domain>", 553},
+		{"RCPT TO:<пользователь@some.domain>", 553},
+		{`RCPT TO:<"to user"@some.domain>`, 250},
+		{"MAIL FROM:<from@some.domain> SMTPUTF8", 250},
+		{"RCPT TO:<пользователь@пример.рф>", 250},
+	}
+	for _, c := range cases {
+		if code, message := command(c.line); code != c.code {
+			t.Errorf("Command %s got %d %s instead of %d", c.line, code, message, c.code)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"mail_auth"
+	"memdb"
+	"models"
+	"net"
+	"smtp_listener"
+	"strings"
+	"testing"
+
+	"github.com/emersion/go-msgauth/dkim"
+)
+
+//Sign message by key of selector of some.domain
+func signMessage(t *testing.T, key *rsa.PrivateKey, selector string, message string) string {
+	var signed bytes.Buffer
+	options := &dkim.SignOptions{Domain: "some.domain", Selector: selector, Signer: key}
+	if err := dkim.Sign(&signed, strings.NewReader(message), options); err != nil {
+		t.Fatalf("Message can not be signed: %v", err)
+	}
+
+	return signed.String()
+}
+
+//Send message over SMTP and return it as saved to database
+func receiveMessage(t *testing.T, from string, message string) *models.Message {
+	address := memdb.GetInstance().InsertMailBox().Value.(*models.MailBox).Address
+	if err := sendMail(t, from, address, message); err != nil {
+		t.Fatalf("Message is rejected: %v", err)
+	}
+
+	messages := memdb.GetInstance().GetMailBoxMessages(address, &memdb.PageCursor{Count: 1}).Value.([]*models.Message)
+	if len(messages) != 1 {
+		t.Fatalf("Message is not saved")
+	}
+
+	return messages[0]
+}
+
+//Test every signature gets result, key is resolved from zone
+func Test_Auth_Dkim(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	public, _ := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	zone := mail_auth.NewZone().Add("valid._domainkey.some.domain", "TXT", "v=DKIM1; k=rsa; p="+base64.StdEncoding.EncodeToString(public))
+	smtp_listener.SetResolver(zone)
+	defer smtp_listener.SetResolver(nil)
+
+	message := "From: from@some.domain\r\nTo: to@some.domain\r\nSubject: Signed\r\n\r\nSigned body\r\n"
+	signed := signMessage(t, key, "valid", message)
+	cases := []struct {
+		name     string
+		message  string
+		expected []string
+	}{
+		{"unsigned", message, []string{}},
+		{"valid", signed, []string{models.AuthPass}},
+		{"tampered", strings.Replace(signed, "Signed body", "Tampered body", 1), []string{models.AuthFail}},
+		{"missing key", signMessage(t, key, "missing", message), []string{models.AuthPermError}},
+		{"two signatures", signMessage(t, key, "missing", signed), []string{models.AuthPermError, models.AuthPass}},
+	}
+
+	for _, c := range cases {
+		received := receiveMessage(t, "from@some.domain", c.message)
+		if len(received.Dkim) != len(c.expected) {
+			t.Fatalf("Message %s has %d results instead of %d: %+v", c.name, len(received.Dkim), len(c.expected), received.Dkim)
+		}
+		for i, result := range received.Dkim {
+			if result.Result != c.expected[i] || result.Domain != "some.domain" {
+				t.Errorf("Signature %d of message %s has result %+v instead of %s", i, c.name, result, c.expected[i])
+			}
+			if result.Result != models.AuthPass && result.Reason == "" {
+				t.Errorf("Failed signature %d of message %s has no reason", i, c.name)
+			}
+		}
+	}
+
+	//Selector is taken from signature
+	if selector := receiveMessage(t, "from@some.domain", signed).Dkim[0].Selector; selector != "valid" {
+		t.Errorf("Selector is %s instead of valid", selector)
+	}
+}
+
+//Test SPF of envelope sender and DMARC alignment of From domain are saved and added as header
+func Test_Auth_Spf_Dmarc(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	public, _ := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	zone := mail_auth.NewZone().
+		Add("valid._domainkey.some.domain", "TXT", "v=DKIM1; k=rsa; p="+base64.StdEncoding.EncodeToString(public)).
+		Add("some.domain", "TXT", "v=spf1 ip4:127.0.0.0/8 -all").
+		Add("_dmarc.some.domain", "TXT", "v=DMARC1; p=reject; sp=quarantine").
+		Add("fail.domain", "TXT", "v=spf1 ip4:192.0.2.0/24 -all").
+		Add("include.domain", "TXT", "v=spf1 include:_spf.some.domain ~all").
+		Add("_spf.some.domain", "TXT", "v=spf1 a:mail.some.domain").
+		Add("mail.some.domain", "A", "127.0.0.1")
+	smtp_listener.SetResolver(zone)
+	defer smtp_listener.SetResolver(nil)
+
+	message := "From: from@some.domain\r\nTo: to@some.domain\r\nSubject: Authenticated\r\n\r\nBody\r\n"
+	cases := []struct {
+		name    string
+		sender  string
+		message string
+		spf     string
+		dmarc   string
+		policy  string
+		header  string
+	}{
+		{"aligned spf", "from@some.domain", message, models.AuthPass, models.AuthPass, "reject",
+			"smtp.mailfrom=some.domain; dmarc=pass header.from=some.domain"},
+		{"failed spf", "from@fail.domain", message, models.AuthFail, models.AuthFail, "reject",
+			"spf=fail"},
+		{"aligned dkim", "from@fail.domain", signMessage(t, key, "valid", message), models.AuthFail, models.AuthPass, "reject",
+			"dkim=pass header.d=some.domain"},
+		{"subdomain", "from@include.domain", strings.Replace(message, "from@some.domain", "from@news.some.domain", 1), models.AuthPass, models.AuthFail, "quarantine",
+			"dmarc=fail"},
+		{"relaxed alignment", "from@some.domain", strings.Replace(message, "from@some.domain", "from@news.some.domain", 1), models.AuthPass, models.AuthPass, "quarantine",
+			"dmarc=pass header.from=news.some.domain"},
+		{"no spf record", "from@mail.some.domain", message, models.AuthNone, models.AuthFail, "reject",
+			"spf=none"},
+	}
+
+	for _, c := range cases {
+		received := receiveMessage(t, c.sender, c.message)
+		if received.Spf == nil || received.Spf.Result != c.spf || received.Spf.ClientIP != "127.0.0.1" {
+			t.Errorf("SPF of message %s is %+v instead of %s", c.name, received.Spf, c.spf)
+		}
+		if received.Dmarc == nil || received.Dmarc.Result != c.dmarc || received.Dmarc.Policy != c.policy {
+			t.Errorf("DMARC of message %s is %+v instead of %s with policy %s", c.name, received.Dmarc, c.dmarc, c.policy)
+		}
+		if !strings.HasPrefix(string(received.Raw), "Authentication-Results: ") || !strings.Contains(strings.SplitN(string(received.Raw), "\r\n", 2)[0], c.header) {
+			t.Errorf("Message %s has no Authentication-Results header with %s: %s", c.name, c.header, strings.SplitN(string(received.Raw), "\r\n", 2)[0])
+		}
+	}
+}
+
+//Test SPF mechanisms, macros and lookup limits
+func Test_Auth_Spf_Record(t *testing.T) {
+	zone := mail_auth.NewZone().
+		Add("ip6.domain", "TXT", "v=spf1 ip6:2001:db8::/32 -all").
+		Add("mx.domain", "TXT", "v=spf1 mx/24 -all").
+		Add("mx.domain", "MX", "10 mail.mx.domain").
+		Add("mail.mx.domain", "A", "198.51.100.10").
+		Add("redirect.domain", "TXT", "v=spf1 redirect=mx.domain").
+		Add("exists.domain", "TXT", "v=spf1 exists:%{ir}.%{l}._spf.%{d} ?all").
+		Add("1.100.51.198.user._spf.exists.domain", "A", "127.0.0.2").
+		Add("loop.domain", "TXT", "v=spf1 include:loop.domain -all").
+		Add("void.domain", "TXT", "v=spf1 a:a.void.domain a:b.void.domain a:c.void.domain -all").
+		Add("twice.domain", "TXT", "v=spf1 -all").
+		Add("twice.domain", "TXT", "v=spf1 +all")
+
+	cases := []struct {
+		ip       string
+		sender   string
+		expected string
+	}{
+		{"2001:db8::1", "user@ip6.domain", models.AuthPass},
+		{"2001:db9::1", "user@ip6.domain", models.AuthFail},
+		{"198.51.100.1", "user@mx.domain", models.AuthPass},
+		{"198.51.101.1", "user@mx.domain", models.AuthFail},
+		{"198.51.100.1", "user@redirect.domain", models.AuthPass},
+		{"198.51.100.1", "user@exists.domain", models.AuthPass},
+		{"198.51.100.1", "other@exists.domain", models.AuthNeutral},
+		{"198.51.100.1", "user@loop.domain", models.AuthPermError},
+		{"198.51.100.1", "user@void.domain", models.AuthPermError},
+		{"198.51.100.1", "user@twice.domain", models.AuthPermError},
+		{"198.51.100.1", "user@missing.domain", models.AuthNone},
+		{"198.51.100.1", "", models.AuthPass},
+	}
+
+	for _, c := range cases {
+		result := mail_auth.CheckSpf(context.Background(), zone, net.ParseIP(c.ip), "mx.domain", c.sender)
+		if result.Result != c.expected {
+			t.Errorf("SPF of %s for %s is %+v instead of %s", c.sender, c.ip, result, c.expected)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package tests
+
+import (
+	"auth"
+	"fmt"
+	"memdb"
+	"models"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+//Test messages to alias are delivered to its mailbox from SMTP and API
+func Test_Forward_Alias(t *testing.T) {
+	address := memdb.GetInstance().InsertMailBox().Value.(*models.MailBox).Address
+	alias := "Alias_" + address
+	domain := address[strings.Index(address, "@")+1:]
+
+	code, body := apiFormRequest("POST", fmt.Sprintf("/mailboxes/%s/aliases", address), "", url.Values{"alias": {alias}})
+	if code != http.StatusCreated || body["alias"] != strings.ToLower(alias) {
+		t.Fatalf("Alias is not added: %d %v", code, body)
+	}
+	if code, _ := apiFormRequest("POST", fmt.Sprintf("/mailboxes/%s/aliases", address), "", url.Values{"alias": {alias}}); code != http.StatusConflict {
+		t.Errorf("Alias is added twice: %d", code)
+	}
+	if code, _ := apiFormRequest("POST", fmt.Sprintf("/mailboxes/%s/aliases", address), "", url.Values{"alias": {"alias@other." + domain}}); code != http.StatusUnprocessableEntity {
+		t.Errorf("Alias of another tenant is added: %d", code)
+	}
+
+	form := url.Values{"from": {"from@some.domain"}, "to": {alias}, "subject": {"API"}, "message": {"API"}}
+	if code, body := apiFormRequest("POST", fmt.Sprintf("/mailboxes/%s/messages", address), "", form); code != http.StatusOK {
+		t.Fatalf("Message to alias is rejected: %d %v", code, body)
+	}
+
+	if err := sendMail(t, "from@some.domain", alias, "Subject: SMTP\r\n\r\nSMTP\r\n"); err != nil {
+		t.Fatalf("Message to alias is rejected: %v", err)
+	}
+
+	messages := memdb.GetInstance().GetMailBoxMessages(address, &memdb.PageCursor{Count: 10}).Value.([]*models.Message)
+	if len(messages) != 2 {
+		t.Fatalf("Messages to alias are not delivered: %d", len(messages))
+	}
+
+	//Removed alias does not deliver anymore
+	if code, body := apiRequest("DELETE", fmt.Sprintf("/mailboxes/%s/aliases/%s", address, alias), ""); code != http.StatusOK {
+		t.Fatalf("Alias is not removed: %d %v", code, body)
+	}
+	if response := memdb.GetInstance().InsertMessage(alias, &models.Message{From: "from@some.domain", To: alias}); response.Code != memdb.ErrorNotFound {
+		t.Errorf("Message to removed alias is saved: %v", response)
+	}
+}
+
+//Test conditional forward rule and stopping of forwarding loop
+func Test_Forward_Rules(t *testing.T) {
+	first := memdb.GetInstance().InsertMailBox().Value.(*models.MailBox).Address
+	second := memdb.GetInstance().InsertMailBox().Value.(*models.MailBox).Address
+	third := memdb.GetInstance().InsertMailBox().Value.(*models.MailBox).Address
+
+	rules := []struct {
+		mailbox string
+		form    url.Values
+	}{
+		{first, url.Values{"to": {second}}},
+		{second, url.Values{"to": {first}}},
+		{second, url.Values{"to": {third}, "field": {"subject"}, "contains": {"INVOICE"}}},
+	}
+	for _, rule := range rules {
+		if code, body := apiFormRequest("POST", fmt.Sprintf("/mailboxes/%s/forwards", rule.mailbox), "", rule.form); code != http.StatusCreated {
+			t.Fatalf("Forward rule is not added: %d %v", code, body)
+		}
+	}
+
+	invalid := []url.Values{
+		{"to": {first}},
+		{"to": {third}, "field": {"header"}, "contains": {"value"}},
+		{"to": {third}, "field": {"subject"}},
+	}
+	for _, form := range invalid {
+		if code, _ := apiFormRequest("POST", fmt.Sprintf("/mailboxes/%s/forwards", first), "", form); code == http.StatusCreated {
+			t.Errorf("Invalid forward rule %v is added", form)
+		}
+	}
+
+	for _, subject := range []string{"Hello", "Your invoice"} {
+		form := url.Values{"from": {"from@some.domain"}, "to": {first}, "subject": {subject}, "message": {subject}}
+		if code, body := apiFormRequest("POST", fmt.Sprintf("/mailboxes/%s/messages", first), "", form); code != http.StatusOK {
+			t.Fatalf("Message is rejected: %d %v", code, body)
+		}
+	}
+
+	counts := map[string]int{first: 2, second: 2, third: 1}
+	for address, count := range counts {
+		messages := memdb.GetInstance().GetMailBoxMessages(address, &memdb.PageCursor{Count: 10}).Value.([]*models.Message)
+		if len(messages) != count {
+			t.Errorf("Mailbox %s has %d messages instead of %d", address, len(messages), count)
+		}
+	}
+
+	forwarded := memdb.GetInstance().GetMailBoxMessages(third, &memdb.PageCursor{Count: 1}).Value.([]*models.Message)[0]
+	if forwarded.Subject != "Your invoice" || strings.Join(forwarded.ForwardedFrom, ",") != first+","+second {
+		t.Errorf("Forwarded message is %s from %v", forwarded.Subject, forwarded.ForwardedFrom)
+	}
+
+	code, body := apiRequest("GET", fmt.Sprintf("/mailboxes/%s/forwards", second), "")
+	forwards, _ := body["forwards"].([]interface{})
+	if code != http.StatusOK || len(forwards) != 2 {
+		t.Fatalf("Forward rules are not listed: %d %v", code, body)
+	}
+	id := forwards[0].(map[string]interface{})["id"]
+	if code, body := apiRequest("DELETE", fmt.Sprintf("/mailboxes/%s/forwards/%v", second, id), ""); code != http.StatusOK {
+		t.Errorf("Forward rule is not removed: %d %v", code, body)
+	}
+}
+
+//Test mailbox which is reached by several forward rules gets one copy
+func Test_Forward_Diamond(t *testing.T) {
+	first := memdb.GetInstance().InsertMailBox().Value.(*models.MailBox).Address
+	second := memdb.GetInstance().InsertMailBox().Value.(*models.MailBox).Address
+	third := memdb.GetInstance().InsertMailBox().Value.(*models.MailBox).Address
+
+	for _, rule := range [][]string{{first, second}, {first, third}, {second, third}} {
+		if code, body := apiFormRequest("POST", fmt.Sprintf("/mailboxes/%s/forwards", rule[0]), "", url.Values{"to": {rule[1]}}); code != http.StatusCreated {
+			t.Fatalf("Forward rule is not added: %d %v", code, body)
+		}
+	}
+
+	form := url.Values{"from": {"from@some.domain"}, "to": {first}, "subject": {"Diamond"}, "message": {"Diamond"}}
+	if code, body := apiFormRequest("POST", fmt.Sprintf("/mailboxes/%s/messages", first), "", form); code != http.StatusOK {
+		t.Fatalf("Message is rejected: %d %v", code, body)
+	}
+
+	for _, address := range []string{first, second, third} {
+		messages := memdb.GetInstance().GetMailBoxMessages(address, &memdb.PageCursor{Count: 10}).Value.([]*models.Message)
+		if len(messages) != 1 {
+			t.Errorf("Mailbox %s has %d messages instead of 1", address, len(messages))
+		}
+	}
+}
+
+//Test mailbox token can't add alias or forward messages to mailbox it has no access to
+func Test_Forward_Authorization(t *testing.T) {
+	auth.SetAdminKeys([]string{"admin-key"})
+	defer auth.SetAdminKeys(nil)
+
+	_, body := apiRequest("POST", "/mailboxes", "admin-key")
+	address, token := body["mailbox"].(string), body["token"].(string)
+	other := memdb.GetInstance().InsertMailBox().Value.(*models.MailBox).Address
+	domain := address[strings.Index(address, "@")+1:]
+
+	code, _ := apiFormRequest("POST", fmt.Sprintf("/mailboxes/%s/aliases", address), token, url.Values{"alias": {"claimed@" + domain}})
+	if code != http.StatusForbidden {
+		t.Errorf("Mailbox token adds alias: %d", code)
+	}
+	if aliases := memdb.GetInstance().GetMailBox(address).Value.(*models.MailBox).Aliases; len(aliases) != 0 {
+		t.Errorf("Alias is claimed by mailbox token: %v", aliases)
+	}
+
+	code, _ = apiFormRequest("POST", fmt.Sprintf("/mailboxes/%s/forwards", address), token, url.Values{"to": {other}})
+	if code != http.StatusForbidden {
+		t.Errorf("Mailbox token forwards to other mailbox: %d", code)
+	}
+	if forwards := memdb.GetInstance().GetMailBox(address).Value.(*models.MailBox).Forwards; len(forwards) != 0 {
+		t.Errorf("Forward rule is added by mailbox token: %v", forwards)
+	}
+
+	code, _ = apiFormRequest("POST", fmt.Sprintf("/mailboxes/%s/forwards", address), "admin-key", url.Values{"to": {other}})
+	if code != http.StatusCreated {
+		t.Errorf("Admin key can't forward to other mailbox: %d", code)
+	}
+	code, _ = apiFormRequest("POST", fmt.Sprintf("/mailboxes/%s/aliases", address), "admin-key", url.Values{"alias": {"claimed@" + domain}})
+	if code != http.StatusCreated {
+		t.Errorf("Admin key can't add alias: %d", code)
+	}
+}
@@ -0,0 +1,95 @@
+package tests
+
+import (
+	"fmt"
+	"memdb"
+	"models"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+//Build mailctl by import path like Dockerfile does, test is skipped without go tool
+func buildMailctl(t *testing.T) string {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go tool is not found, mailctl can not be built")
+	}
+
+	path := filepath.Join(t.TempDir(), "mailctl")
+	if output, err := exec.Command("go", "build", "-o", path, "mailctl").CombinedOutput(); err != nil {
+		t.Fatalf("mailctl is not built: %v\n%s", err, output)
+	}
+	return path
+}
+
+//Test mailctl commands against API and SMTP listener of test
+func Test_Mailctl_Commands(t *testing.T) {
+	server := startApiServer()
+	defer server.Close()
+	binary := buildMailctl(t)
+	smtpAddress := startSmtp(t)
+
+	mailctl := func(args ...string) string {
+		command := exec.Command(binary, append([]string{"-url", server.URL}, args...)...)
+		command.Env = append(os.Environ(), "MAILCTL_SMTP="+smtpAddress, "MAILCTL_API_KEY=", "MAILCTL_TENANT=")
+		output, err := command.CombinedOutput()
+		if err != nil {
+			t.Fatalf("mailctl %v failed: %v\n%s", args, err, output)
+		}
+		return string(output)
+	}
+
+	output := mailctl("create")
+	address := strings.SplitN(output, "\n", 2)[0]
+	if !strings.Contains(output, "token: ") || !memdb.GetInstance().GetMailBox(address).Success {
+		t.Fatalf("Mailbox is not created: %s", output)
+	}
+
+	local := address[:strings.Index(address, "@")]
+	if output := mailctl("list", "-prefix", local); !strings.Contains(output, address) {
+		t.Errorf("Created mailbox is not listed: %s", output)
+	}
+
+	if output := mailctl("send", "-from", "sender@some.domain", "-to", address, "-subject", "Hello", "-body", "Text body"); !strings.Contains(output, "Message sent to "+address) {
+		t.Errorf("Message is not sent: %s", output)
+	}
+	html := memdb.GetInstance().InsertMessage(address, &models.Message{To: address, From: "from@some.domain", Subject: "Report", Raw: []byte(multipartMessage), ReceivedDate: time.Now()})
+	messages := memdb.GetInstance().GetMailBox(address).Value.(*models.MailBox).Messages
+	if len(messages) != 2 {
+		t.Fatalf("Sent message is not received: %d", len(messages))
+	}
+	text := messages[0].Id
+	htmlId := html.Value.(*models.Message).Id
+
+	if output := mailctl("messages", address); !strings.Contains(output, "Hello") || !strings.Contains(output, "Report") {
+		t.Errorf("Wrong list of messages: %s", output)
+	}
+
+	if output := mailctl("show", address, fmt.Sprint(text)); !strings.Contains(output, "Subject: Hello") || !strings.Contains(output, "Text body") {
+		t.Errorf("Wrong text of message: %s", output)
+	}
+	if output := mailctl("show", "-format", "html", address, fmt.Sprint(htmlId)); !strings.Contains(output, "<p") || !strings.Contains(output, "Report") {
+		t.Errorf("Wrong HTML of message: %s", output)
+	}
+	if output := mailctl("show", "-format", "raw", address, fmt.Sprint(text)); !strings.Contains(output, "Message-ID: <") || !strings.Contains(output, "\r\n") {
+		t.Errorf("Wrong raw message: %q", output)
+	}
+
+	if output := mailctl("search", "-subject", "hello", address); !strings.Contains(output, "Hello") || strings.Contains(output, "Report") {
+		t.Errorf("Wrong found messages: %s", output)
+	}
+
+	file := filepath.Join(t.TempDir(), "export.mbox")
+	mailctl("export", "-o", file, address)
+	mbox, err := os.ReadFile(file)
+	if err != nil || strings.Count(string(mbox), "\nFrom ")+1 != 2 || !strings.HasPrefix(string(mbox), "From sender@some.domain ") || strings.Contains(string(mbox), "\r\n") {
+		t.Errorf("Wrong exported mailbox: %v\n%s", err, mbox)
+	}
+
+	if output := mailctl("delete", address); !strings.Contains(output, address+" removed") || memdb.GetInstance().GetMailBox(address).Success {
+		t.Errorf("Mailbox is not removed: %s", output)
+	}
+}
@@ -0,0 +1,41 @@
+package tests
+
+import (
+	"api"
+	"fmt"
+	"memdb"
+	"models"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+//Test metrics of received messages, database commands, storage and requests are exposed
+func Test_Metrics_Endpoint(t *testing.T) {
+	address := memdb.GetInstance().InsertMailBox().Value.(*models.MailBox).Address
+	apiFormRequest("POST", fmt.Sprintf("/mailboxes/%s/messages", address), "",
+		url.Values{"from": {"from@some.domain"}, "to": {address}, "subject": {"Metrics"}, "message": {"Metrics"}})
+
+	request, _ := http.NewRequest("GET", "/metrics", nil)
+	recorder := httptest.NewRecorder()
+	api.Handle().ServeHTTP(recorder, request)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Metrics are not served: %d", recorder.Code)
+	}
+
+	body := recorder.Body.String()
+	for _, metric := range []string{
+		`mail_service_messages_received_total{channel="api"}`,
+		`mail_service_memdb_command_duration_seconds_count{action="insert",entity="message"}`,
+		`mail_service_mailboxes_stored{tenant="default"}`,
+		`mail_service_bytes_stored{tenant="default"}`,
+		`mail_service_http_requests_total{method="POST",route="/mailboxes/:email/messages",status="200"}`,
+	} {
+		if !strings.Contains(body, metric) {
+			t.Errorf("Metric is not exposed: %s", metric)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package tests
+
+import (
+	"api"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+//Matches gin route params like :email and *path
+var routeParam = regexp.MustCompile(`[:*]([a-z_]+)`)
+
+//Collect every $ref of spec
+func specRefs(value interface{}, refs *[]string) {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for key, item := range value {
+			if ref, ok := item.(string); ok && key == "$ref" {
+				*refs = append(*refs, ref)
+			}
+			specRefs(item, refs)
+		}
+	case []interface{}:
+		for _, item := range value {
+			specRefs(item, refs)
+		}
+	}
+}
+
+//Test OpenAPI description contains every registered route and nothing else
+func Test_OpenApi_Routes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := api.Handle()
+
+	recorder := httptest.NewRecorder()
+	request, _ := http.NewRequest("GET", "/openapi.json", nil)
+	router.ServeHTTP(recorder, request)
+
+	spec := map[string]interface{}{}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &spec); recorder.Code != http.StatusOK || err != nil {
+		t.Fatalf("OpenAPI description is not served: %d %v", recorder.Code, err)
+	}
+
+	//Routes without own servers are available in default tenant and inside /tenants/{tenant}
+	described := map[string]bool{}
+	for path, item := range spec["paths"].(map[string]interface{}) {
+		operations := item.(map[string]interface{})
+		for method := range operations {
+			if method == "servers" || method == "parameters" {
+				continue
+			}
+			described[strings.ToUpper(method)+" "+path] = true
+			if operations["servers"] == nil {
+				described[strings.ToUpper(method)+" /tenants/{tenant}"+path] = true
+			}
+		}
+	}
+
+	for _, route := range router.Routes() {
+		operation := route.Method + " " + routeParam.ReplaceAllString(route.Path, "{$1}")
+		if !described[operation] {
+			t.Errorf("Route is not described: %s", operation)
+		}
+		delete(described, operation)
+	}
+
+	for operation := range described {
+		t.Errorf("Described route is not registered: %s", operation)
+	}
+
+	//Every reference points to existing component
+	components := spec["components"].(map[string]interface{})
+	refs := []string{}
+	specRefs(spec, &refs)
+	for _, ref := range refs {
+		parts := strings.Split(strings.TrimPrefix(ref, "#/components/"), "/")
+		group, ok := components[parts[0]].(map[string]interface{})
+		if len(parts) != 2 || !ok || group[parts[1]] == nil {
+			t.Errorf("Reference to missing component: %s", ref)
+		}
+	}
+}
+
+//Test viewer of API description is served from embedded files without external scripts
+func Test_OpenApi_Docs_Assets(t *testing.T) {
+	for path, expected := range map[string]string{"/docs/": "docs.js", "/docs/docs.js": "/openapi.json", "/docs/docs.css": "body"} {
+		recorder := httptest.NewRecorder()
+		api.Handle().ServeHTTP(recorder, httptest.NewRequest("GET", path, nil))
+
+		if recorder.Code != http.StatusOK || !strings.Contains(recorder.Body.String(), expected) || strings.Contains(recorder.Body.String(), "https://") {
+			t.Errorf("Viewer file %s is not served: %d", path, recorder.Code)
+		}
+	}
+
+	recorder := httptest.NewRecorder()
+	api.Handle().ServeHTTP(recorder, httptest.NewRequest("GET", "/docs", nil))
+	if recorder.Code != http.StatusMovedPermanently || recorder.Header().Get("Location") != "/docs/" {
+		t.Errorf("Viewer is not redirected: %d %v", recorder.Code, recorder.Header())
+	}
+}
@@ -0,0 +1,85 @@
+package tests
+
+import (
+	"mail_parser"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+//Test text, html and attachments are decoded from multipart message
+func Test_Parse_Multipart_Message(t *testing.T) {
+	raw := strings.Replace(`From: shop@some.domain
+To: email_1@some.domain
+Subject: =?utf-8?q?Confirm_your_account?=
+MIME-Version: 1.0
+Content-Type: multipart/related; boundary="related"
+
+--related
+Content-Type: multipart/alternative; boundary="alternative"
+
+--alternative
+Content-Type: text/plain; charset=utf-8
+
+Your code is 123456
+--alternative
+Content-Type: text/html; charset=utf-8
+Content-Transfer-Encoding: quoted-printable
+
+<p>Your code is <b>123456</b></p><img src=3D"cid:logo@shop">
+--alternative--
+--related
+Content-Type: image/png; name="logo.png"
+Content-Transfer-Encoding: base64
+Content-ID: <logo@shop>
+
+aW1hZ2U=
+--related--
+`, "\n", "\r\n", -1)
+
+	message, err := mail_parser.Parse([]byte(raw))
+	if err != nil {
+		t.Fatalf("Message is not parsed: %v", err)
+	}
+
+	if message.Subject != "Confirm your account" {
+		t.Errorf("Wrong subject: %s", message.Subject)
+	}
+	if message.Text != "Your code is 123456" {
+		t.Errorf("Wrong text: %q", message.Text)
+	}
+	if message.HTML != `<p>Your code is <b>123456</b></p><img src="cid:logo@shop">` {
+		t.Errorf("Wrong html: %q", message.HTML)
+	}
+
+	logo := message.Attachment("<logo@shop>")
+	if logo == nil || !logo.Inline || logo.Filename != "logo.png" || string(logo.Data) != "image" {
+		t.Errorf("Wrong inline attachment: %v", logo)
+	}
+}
+
+//Test built-in patterns of verification codes
+func Test_Parse_Codes(t *testing.T) {
+	for text, expected := range map[string]string{
+		"Your verification code is 4821":       "4821",
+		"PIN: AB12CD, it expires in 10 minutes": "AB12CD",
+		"Use 739201 to sign in":                 "739201",
+		"Password reset link, nothing else":     "",
+	} {
+		codes := (&mail_parser.Message{Text: text}).Codes(mail_parser.BuiltinCodePatterns)
+		if (expected == "" && len(codes) != 0) || (expected != "" && (len(codes) == 0 || codes[0].Code != expected)) {
+			t.Errorf("Wrong codes of %q: %v", text, codes)
+		}
+	}
+}
+
+//Test codes of caller patterns are kept as is and whole match is used if the first group is empty
+func Test_Parse_Codes_Caller_Patterns(t *testing.T) {
+	message := &mail_parser.Message{Text: "Use 482913 or backup code AB-12-CD"}
+	pattern := mail_parser.CodePattern{Name: "caller", Regex: regexp.MustCompile(`Use (\d{6})|[A-Z]{2}-\d{2}-[A-Z]{2}`)}
+
+	codes := message.Codes([]mail_parser.CodePattern{pattern})
+	if len(codes) != 2 || codes[0].Code != "482913" || codes[1].Code != "AB-12-CD" {
+		t.Errorf("Wrong codes of caller pattern: %v", codes)
+	}
+}
@@ -0,0 +1,132 @@
+package tests
+
+import (
+	"api"
+	"auth"
+	"memdb"
+	"models"
+	"net/http"
+	"net/http/httptest"
+	"ratelimit"
+	"smtp_listener"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+//Test token bucket allows burst and rejects next actions
+func Test_Rate_Limiter(t *testing.T) {
+	limiter := ratelimit.NewLimiter(60, 2)
+
+	if !limiter.Allow("client").Allowed || !limiter.Allow("client").Allowed {
+		t.Errorf("Burst is not allowed")
+	}
+
+	result := limiter.Allow("client")
+	if result.Allowed || result.Remaining != 0 || result.RetryAfter <= 0 {
+		t.Errorf("Action over limit is allowed: %v", result)
+	}
+
+	if !limiter.Allow("other client").Allowed {
+		t.Errorf("Limit is shared between keys")
+	}
+
+	if !ratelimit.NewLimiter(0, 0).Allow("client").Allowed {
+		t.Errorf("Disabled limiter rejects actions")
+	}
+}
+
+//Test API requests over limit are rejected with 429
+func Test_Rate_Limit_API(t *testing.T) {
+	api.SetRateLimit(60, 1)
+	defer api.SetRateLimit(0, 0)
+	gin.SetMode(gin.TestMode)
+
+	key := auth.IssueKey(memdb.DefaultTenant, true, "").Value.(*models.ApiKey).Key
+	request, _ := http.NewRequest("GET", "/mailboxes", nil)
+	request.Header.Set("X-API-Key", key)
+
+	recorder := httptest.NewRecorder()
+	api.Handle().ServeHTTP(recorder, request)
+
+	if recorder.Code != http.StatusOK || recorder.Header().Get("X-RateLimit-Limit") != "1" || recorder.Header().Get("X-RateLimit-Remaining") != "0" {
+		t.Errorf("Wrong response inside limit: %d %v", recorder.Code, recorder.Header())
+	}
+
+	recorder = httptest.NewRecorder()
+	api.Handle().ServeHTTP(recorder, request)
+
+	if recorder.Code != http.StatusTooManyRequests || recorder.Header().Get("Retry-After") == "" {
+		t.Errorf("Wrong response over limit: %d %v", recorder.Code, recorder.Header())
+	}
+
+	code, _ := apiRequest("GET", "/mailboxes", "")
+
+	if code != http.StatusOK {
+		t.Errorf("Limit of API key is applied to other clients: %d", code)
+	}
+
+	code, _ = apiRequest("GET", "/mailboxes", "random-unknown-key")
+
+	if code != http.StatusTooManyRequests {
+		t.Errorf("Unknown API key bypasses limit of client IP: %d", code)
+	}
+}
+
+//Test SMTP messages over limits are rejected with 421 and 452
+func Test_Rate_Limit_SMTP(t *testing.T) {
+	address := memdb.GetInstance().InsertMailBox().Value.(*models.MailBox).Address
+	send := func(from string) error {
+		return sendMail(t, from, address, "Subject: Limits\r\n\r\nLimits\r\n")
+	}
+
+	smtp_listener.SetRateLimits(0, 1)
+	if err := send("sender@some.domain"); err != nil {
+		t.Fatalf("Message inside limits is rejected: %v", err)
+	}
+	if err := send("sender@some.domain"); err == nil || !strings.HasPrefix(err.Error(), "452") {
+		t.Errorf("Wrong reply for sender over limit: %v", err)
+	}
+
+	smtp_listener.SetRateLimits(1, 0)
+	send("other@some.domain")
+	if err := send("other@some.domain"); err == nil || !strings.HasPrefix(err.Error(), "421") {
+		t.Errorf("Wrong reply for client over limit: %v", err)
+	}
+	smtp_listener.SetRateLimits(0, 0)
+
+	if messages := memdb.GetInstance().GetMailBox(address).Value.(*models.MailBox).Messages; len(messages) != 2 {
+		t.Errorf("Wrong count of received messages: %d", len(messages))
+	}
+}
+
+//Test forwarded client IP doesn't bypass limit and probes are not limited
+func Test_Rate_Limit_Client_IP(t *testing.T) {
+	api.SetRateLimit(60, 1)
+	defer api.SetRateLimit(0, 0)
+	gin.SetMode(gin.TestMode)
+	router := api.Handle()
+
+	request := func(path string, forwarded string) int {
+		request, _ := http.NewRequest("GET", path, nil)
+		request.RemoteAddr = "192.0.2.10:1234"
+		request.Header.Set("X-Forwarded-For", forwarded)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		return recorder.Code
+	}
+
+	if code := request("/mailboxes", "198.51.100.1"); code != http.StatusOK {
+		t.Errorf("Request inside limit is rejected: %d", code)
+	}
+	if code := request("/mailboxes", "198.51.100.2"); code != http.StatusTooManyRequests {
+		t.Errorf("Forwarded IP bypasses limit of client IP: %d", code)
+	}
+
+	for _, path := range []string{"/healthz", "/readyz", "/metrics"} {
+		if code := request(path, ""); code != http.StatusOK {
+			t.Errorf("Route %s is limited: %d", path, code)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package tests
+
+import (
+	"api"
+	"bufio"
+	"fmt"
+	"mail_address"
+	"memdb"
+	"models"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+//Local stand-in of upstream SMTP server
+//Accepts single transaction and sends received sender, recipient and data to chanels
+//Sender has to be a bare address or null path, like real servers require
+func startUpstreamServer(t *testing.T, senders chan<- string, recipients chan<- string, data chan<- string) net.Listener {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		reader := bufio.NewReader(conn)
+		fmt.Fprint(conn, "220 localhost ready\r\n")
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+
+			switch command := strings.ToUpper(strings.TrimSpace(line)); {
+			case strings.HasPrefix(command, "MAIL FROM:"):
+				path := strings.TrimSpace(strings.TrimSpace(line)[10:])
+				sender := strings.TrimSuffix(strings.TrimPrefix(path, "<"), ">")
+				if _, err := mail_address.ParseAddress(sender); len(path) < 2 || path[0] != '<' || path[len(path)-1] != '>' || (sender != "" && err != nil) {
+					fmt.Fprint(conn, "501 Syntax error in MAIL FROM\r\n")
+					continue
+				}
+				senders <- sender
+				fmt.Fprint(conn, "250 OK\r\n")
+			case strings.HasPrefix(command, "RCPT TO:"):
+				recipients <- strings.Trim(strings.TrimSpace(line)[8:], "<>")
+				fmt.Fprint(conn, "250 OK\r\n")
+			case command == "DATA":
+				fmt.Fprint(conn, "354 Go ahead\r\n")
+				var body []string
+				for {
+					line, err = reader.ReadString('\n')
+					if err != nil || line == ".\r\n" {
+						break
+					}
+					body = append(body, line)
+				}
+				data <- strings.Join(body, "")
+				fmt.Fprint(conn, "250 OK\r\n")
+			case command == "QUIT":
+				fmt.Fprint(conn, "221 Bye\r\n")
+				return
+			default:
+				fmt.Fprint(conn, "250 OK\r\n")
+			}
+		}
+	}()
+
+	return listener
+}
+
+//Create mailbox with single message for release
+func insertReleaseMessage(t *testing.T) (string, int) {
+	db := memdb.GetInstance()
+
+	mailbox := db.InsertMailBox().Value.(*models.MailBox)
+	message := &models.Message{From: `"Sender Name" <sender@some.domain>`, To: mailbox.Address, Subject: "Release me", Body: "Hello", ReceivedDate: time.Now()}
+
+	result := db.InsertMessage(mailbox.Address, message)
+	if !result.Success {
+		t.Fatal("Insert operation is not successfully")
+	}
+
+	return mailbox.Address, result.Value.(*models.Message).Id
+}
+
+//Post release request to api router
+func postRelease(address string, id int, to string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+
+	form := url.Values{"to": {to}}
+	request, _ := http.NewRequest("POST", fmt.Sprintf("/mailboxes/%s/messages/%d/release", address, id), strings.NewReader(form.Encode()))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	recorder := httptest.NewRecorder()
+	api.Handle().ServeHTTP(recorder, request)
+
+	return recorder
+}
+
+//Test releases message to upstream server and records status
+func Test_Release_Message_To_Upstream(t *testing.T) {
+	senders := make(chan string, 1)
+	recipients := make(chan string, 1)
+	data := make(chan string, 1)
+	upstream := startUpstreamServer(t, senders, recipients, data)
+	defer upstream.Close()
+
+	host, port, _ := net.SplitHostPort(upstream.Addr().String())
+	os.Setenv("RELAY_HOST", host)
+	os.Setenv("RELAY_PORT", port)
+	defer os.Unsetenv("RELAY_HOST")
+	defer os.Unsetenv("RELAY_PORT")
+
+	address, id := insertReleaseMessage(t)
+
+	recorder := postRelease(address, id, "real@inbox.domain")
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Release is not successfully: %d %s", recorder.Code, recorder.Body.String())
+	}
+
+	if sender := <-senders; sender != "sender@some.domain" {
+		t.Errorf("Message is released from wrong envelope sender %s", sender)
+	}
+
+	if recipient := <-recipients; recipient != "real@inbox.domain" {
+		t.Errorf("Message is released to wrong recipient %s", recipient)
+	}
+
+	if body := <-data; !strings.Contains(body, "Subject: Release me") {
+		t.Error("Wrong message is released")
+	}
+
+	message := memdb.GetInstance().GetMessage(address, id).Value.(*models.Message)
+
+	if len(message.Releases) != 1 || !message.Releases[0].Success || message.Releases[0].To != "real@inbox.domain" {
+		t.Error("Release status is not recorded")
+	}
+}
+
+//Test release fails when upstream server is not configured
+func Test_Release_Message_Without_Upstream(t *testing.T) {
+	address, id := insertReleaseMessage(t)
+
+	recorder := postRelease(address, id, "real@inbox.domain")
+
+	if recorder.Code != http.StatusBadGateway {
+		t.Errorf("Release is successfully, but shouldn`t: %d", recorder.Code)
+	}
+
+	message := memdb.GetInstance().GetMessage(address, id).Value.(*models.Message)
+
+	if len(message.Releases) != 1 || message.Releases[0].Success || message.Releases[0].Error == "" {
+		t.Error("Failed release is not recorded")
+	}
+}
+
+//Test release fails in time when upstream server doesn't answer
+func Test_Release_Message_Upstream_Timeout(t *testing.T) {
+	upstream, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer upstream.Close()
+
+	host, port, _ := net.SplitHostPort(upstream.Addr().String())
+	os.Setenv("RELAY_HOST", host)
+	os.Setenv("RELAY_PORT", port)
+	os.Setenv("RELAY_TIMEOUT", "1")
+	defer os.Unsetenv("RELAY_HOST")
+	defer os.Unsetenv("RELAY_PORT")
+	defer os.Unsetenv("RELAY_TIMEOUT")
+
+	address, id := insertReleaseMessage(t)
+
+	//Connection is accepted by backlog of listener, but greeting is never sent
+	started := time.Now()
+	recorder := postRelease(address, id, "real@inbox.domain")
+
+	if recorder.Code != http.StatusBadGateway || time.Since(started) > 5*time.Second {
+		t.Errorf("Release to hung upstream is not failed in time: %d %v", recorder.Code, time.Since(started))
+	}
+}
@@ -0,0 +1,184 @@
+package tests
+
+import (
+	"api"
+	"client"
+	"context"
+	"fmt"
+	"memdb"
+	"models"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+//Start API on random port
+func startApiServer() *httptest.Server {
+	gin.SetMode(gin.TestMode)
+	return httptest.NewServer(api.Handle())
+}
+
+//Test client manages mailboxes and messages
+func Test_Client_Messages(t *testing.T) {
+	server := startApiServer()
+	defer server.Close()
+
+	ctx := context.Background()
+	apiClient := client.New(server.URL, "")
+
+	mailbox, err := apiClient.CreateMailbox(ctx)
+	if err != nil || mailbox.Address == "" || mailbox.Token == "" {
+		t.Fatalf("Mailbox is not created: %v %v", mailbox, err)
+	}
+
+	for _, subject := range []string{"Welcome", "Confirm your account", "Invoice"} {
+		message := &models.Message{From: "shop@some.domain", To: mailbox.Address, Subject: subject, Body: subject + " body"}
+		if err := apiClient.AddMessage(ctx, message); err != nil {
+			t.Fatalf("Message is not added: %v", err)
+		}
+	}
+
+	page, err := apiClient.ListMessages(ctx, mailbox.Address, nil, &client.ListOptions{Limit: 2})
+	if err != nil || len(page.Messages) != 2 || page.Total != 3 || !page.HasMore || page.MaxId == 0 {
+		t.Fatalf("Wrong page of messages: %v %v", page, err)
+	}
+
+	found, err := apiClient.Search(ctx, mailbox.Address, client.SearchQuery{Subject: "confirm"})
+	if err != nil || len(found) != 1 || found[0].Subject != "Confirm your account" {
+		t.Fatalf("Wrong search result: %v %v", found, err)
+	}
+
+	message, err := apiClient.GetMessage(ctx, mailbox.Address, found[0].Id)
+	if err != nil || message.Body != "Confirm your account body" {
+		t.Errorf("Wrong message: %v %v", message, err)
+	}
+
+	flagged := true
+	message, err = apiClient.UpdateMessage(ctx, mailbox.Address, found[0].Id, models.MessageFlags{Flagged: &flagged})
+	if err != nil || !message.Flagged {
+		t.Errorf("Message is not flagged: %v %v", message, err)
+	}
+
+	if err := apiClient.DeleteMessage(ctx, mailbox.Address, found[0].Id); err != nil {
+		t.Errorf("Message is not removed: %v", err)
+	}
+
+	_, err = apiClient.GetMessage(ctx, mailbox.Address, found[0].Id)
+	if !client.IsErrorCode(err, "not_found") {
+		t.Errorf("Wrong error for removed message: %v", err)
+	}
+
+	if err := apiClient.DeleteMailbox(ctx, mailbox.Address); err != nil {
+		t.Errorf("Mailbox is not removed: %v", err)
+	}
+}
+
+//Test client waits for message which arrives later
+func Test_Client_Wait_For_Message(t *testing.T) {
+	server := startApiServer()
+	defer server.Close()
+
+	apiClient := client.New(server.URL, "")
+	apiClient.PollInterval = 10 * time.Millisecond
+	mailbox, _ := apiClient.CreateMailbox(context.Background())
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		apiClient.AddMessage(context.Background(), &models.Message{From: "shop@some.domain", To: mailbox.Address, Subject: "Your code", Body: "123456"})
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	message, err := apiClient.WaitForMessage(ctx, mailbox.Address, client.SearchQuery{Subject: "code"})
+	if err != nil || message.Body != "123456" {
+		t.Errorf("Message is not received: %v %v", message, err)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err = apiClient.WaitForMessage(ctx, mailbox.Address, client.SearchQuery{Subject: "never"})
+	if err != context.DeadlineExceeded {
+		t.Errorf("Waiting is not stopped by context: %v", err)
+	}
+}
+
+//Test client repeats failed requests
+func Test_Client_Retries(t *testing.T) {
+	attempts := int32(0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"mailboxes": [], "count": 0}`))
+	}))
+	defer server.Close()
+
+	apiClient := client.New(server.URL, "")
+	apiClient.RetryWait = time.Millisecond
+
+	if _, err := apiClient.ListMailboxes(context.Background(), "", "", nil); err != nil || attempts != 3 {
+		t.Errorf("Request is not retried: %v %d", err, attempts)
+	}
+
+	apiClient.Retries = 0
+	atomic.StoreInt32(&attempts, 0)
+
+	_, err := apiClient.ListMailboxes(context.Background(), "", "", nil)
+	if apiError, ok := err.(*client.Error); !ok || apiError.Status != http.StatusServiceUnavailable {
+		t.Errorf("Wrong error without retries: %v", err)
+	}
+}
+
+//Message with links and verification code in HTML part only
+const confirmationMessage = "From: from@some.domain\r\n" +
+	"Subject: Confirm your account\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: text/html; charset=utf-8\r\n" +
+	"\r\n" +
+	"<p>Your verification code is <b>482 913</b>.</p>\r\n" +
+	"<p><a href=\"https://app.some.domain/confirm?token=abc&amp;user=1\">Confirm   <i>account</i></a>\r\n" +
+	"<a href=\"javascript:alert(1)\">Script</a> <a href=\"https://app.some.domain/unsubscribe\">Unsubscribe</a></p>\r\n" +
+	"<style>.order-2024 { color: red }</style><p>Order 2024-11</p>\r\n"
+
+//Test links and codes are extracted through client
+func Test_Extract_Links_And_Codes(t *testing.T) {
+	server := startApiServer()
+	defer server.Close()
+	api := client.New(server.URL, "")
+
+	address := memdb.GetInstance().InsertMailBox().Value.(*models.MailBox).Address
+	id := memdb.GetInstance().InsertMessage(address, &models.Message{To: address, From: "from@some.domain", Subject: "Confirm your account", Raw: []byte(confirmationMessage), ReceivedDate: time.Now()}).Value.(*models.Message).Id
+
+	links, err := api.GetLinks(context.Background(), address, id, "")
+	if err != nil || len(links) != 2 || links[0].URL != "https://app.some.domain/confirm?token=abc&user=1" || links[0].Text != "Confirm account" {
+		t.Fatalf("Wrong links of message: %v %v", links, err)
+	}
+
+	links, err = api.GetLinks(context.Background(), address, id, "CONFIRM")
+	if err != nil || len(links) != 1 {
+		t.Errorf("Links are not filtered: %v %v", links, err)
+	}
+
+	codes, err := api.GetCodes(context.Background(), address, id)
+	if err != nil || len(codes) != 1 || codes[0].Code != "482913" || codes[0].Pattern != "split_digits" {
+		t.Errorf("Wrong codes of message: %v %v", codes, err)
+	}
+
+	codes, err = api.GetCodes(context.Background(), address, id, `Order (\d{4}-\d{2})`)
+	if err != nil || len(codes) != 2 || codes[0].Code != "2024-11" {
+		t.Errorf("Codes of caller pattern are not found: %v %v", codes, err)
+	}
+
+	code, _ := apiRequest("GET", fmt.Sprintf("/mailboxes/%s/messages/%d/codes?pattern=%s", address, id, url.QueryEscape("(")), "")
+	if code != http.StatusBadRequest {
+		t.Errorf("Invalid pattern is accepted: %d", code)
+	}
+}
@@ -0,0 +1,282 @@
+package tests
+
+import (
+	"api"
+	"auth"
+	"encoding/json"
+	"fmt"
+	"memdb"
+	"models"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+//Send request to api router and decode JSON response
+func apiRequest(method string, path string, key string) (int, map[string]interface{}) {
+	return apiFormRequest(method, path, key, nil)
+}
+
+//Send request with form to api router and decode JSON response
+func apiFormRequest(method string, path string, key string, form url.Values) (int, map[string]interface{}) {
+	gin.SetMode(gin.TestMode)
+
+	request, _ := http.NewRequest(method, path, strings.NewReader(form.Encode()))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if key != "" {
+		request.Header.Set("Authorization", "Bearer "+key)
+	}
+	recorder := httptest.NewRecorder()
+	api.Handle().ServeHTTP(recorder, request)
+
+	body := map[string]interface{}{}
+	json.Unmarshal(recorder.Body.Bytes(), &body)
+
+	return recorder.Code, body
+}
+
+//Test database errors are mapped to HTTP statuses with the same error body
+func Test_Routes_Error_Statuses(t *testing.T) {
+	address := memdb.GetInstance().InsertMailBox().Value.(*models.MailBox).Address
+
+	code, body := apiRequest("GET", fmt.Sprintf("/mailboxes/%s/messages/54364", address), "")
+
+	if code != http.StatusNotFound || body["error"] != "not_found" || body["message"] == "" {
+		t.Errorf("Wrong response for inexisting message: %d %v", code, body)
+	}
+
+	code, body = apiRequest("DELETE", "/mailboxes/inexisting@mail.box", "")
+
+	if code != http.StatusNotFound || body["error"] != "not_found" {
+		t.Errorf("Wrong response for inexisting mailbox: %d %v", code, body)
+	}
+
+	code, body = apiRequest("GET", fmt.Sprintf("/mailboxes/%s/messages/0", address), "")
+
+	if code != http.StatusBadRequest || body["error"] != "bad_request" {
+		t.Errorf("Wrong response for invalid message id: %d %v", code, body)
+	}
+}
+
+//Test admin key and mailbox token access
+func Test_Routes_Authentication(t *testing.T) {
+	auth.SetAdminKeys([]string{"admin-key"})
+	defer auth.SetAdminKeys(nil)
+
+	code, _ := apiRequest("GET", "/mailboxes", "")
+
+	if code != http.StatusUnauthorized {
+		t.Errorf("Request without key is allowed: %d", code)
+	}
+
+	code, body := apiRequest("POST", "/mailboxes", "admin-key")
+
+	if code != http.StatusCreated || body["token"] == nil {
+		t.Fatalf("Mailbox is not created by admin key: %d %v", code, body)
+	}
+
+	address := body["mailbox"].(string)
+	token := body["token"].(string)
+
+	code, _ = apiRequest("GET", fmt.Sprintf("/mailboxes/%s/messages", address), token)
+
+	if code != http.StatusOK {
+		t.Errorf("Mailbox token has no access to its mailbox: %d", code)
+	}
+
+	code, _ = apiRequest("GET", "/mailboxes/email_1@some.domain/messages", token)
+
+	if code != http.StatusForbidden {
+		t.Errorf("Mailbox token has access to other mailbox: %d", code)
+	}
+
+	code, _ = apiRequest("GET", "/keys", token)
+
+	if code != http.StatusForbidden {
+		t.Errorf("Mailbox token has access to admin routes: %d", code)
+	}
+
+	code, _ = apiRequest("DELETE", "/keys/"+token, "admin-key")
+
+	if code != http.StatusOK {
+		t.Errorf("Key is not removed: %d", code)
+	}
+
+	code, _ = apiRequest("GET", fmt.Sprintf("/mailboxes/%s/messages", address), token)
+
+	if code != http.StatusUnauthorized {
+		t.Errorf("Removed key is still valid: %d", code)
+	}
+}
+
+//Test flags of message are changed by PATCH route and invalid values are rejected
+func Test_Routes_Update_Message_Flags(t *testing.T) {
+	address := memdb.GetInstance().InsertMailBox().Value.(*models.MailBox).Address
+	id := memdb.GetInstance().InsertMessage(address, &models.Message{To: address}).Value.(*models.Message).Id
+	path := fmt.Sprintf("/mailboxes/%s/messages/%d", address, id)
+
+	code, body := apiFormRequest("PATCH", path, "", url.Values{"flagged": {"true"}, "answered": {"true"}})
+
+	content, _ := body["content"].(map[string]interface{})
+	if code != http.StatusOK || content["flagged"] != true || content["answered"] != true || content["seen"] != false {
+		t.Errorf("Flags are not updated: %d %v", code, body)
+	}
+
+	code, body = apiFormRequest("PATCH", path, "", url.Values{"answered": {"false"}})
+
+	content, _ = body["content"].(map[string]interface{})
+	if code != http.StatusOK || content["flagged"] != true || content["answered"] != false {
+		t.Errorf("Absent flag is changed: %d %v", code, body)
+	}
+
+	code, body = apiFormRequest("PATCH", path, "", url.Values{"seen": {"maybe"}})
+
+	if code != http.StatusBadRequest || body["error"] != "bad_request" {
+		t.Errorf("Invalid flag value is accepted: %d %v", code, body)
+	}
+
+	code, _ = apiFormRequest("PATCH", fmt.Sprintf("/mailboxes/%s/messages/54364", address), "", url.Values{"seen": {"true"}})
+
+	if code != http.StatusNotFound {
+		t.Errorf("Flags of inexisting message are updated: %d", code)
+	}
+}
+
+//Test message is marked as seen on read unless marking is disabled
+func Test_Routes_Mark_Seen_On_Read(t *testing.T) {
+	defer api.SetMarkSeenOnRead(true)
+
+	address := memdb.GetInstance().InsertMailBox().Value.(*models.MailBox).Address
+	for _, mark := range []bool{false, true} {
+		api.SetMarkSeenOnRead(mark)
+		id := memdb.GetInstance().InsertMessage(address, &models.Message{To: address}).Value.(*models.Message).Id
+
+		code, body := apiRequest("GET", fmt.Sprintf("/mailboxes/%s/messages/%d", address, id), "")
+
+		content, _ := body["content"].(map[string]interface{})
+		if code != http.StatusOK || content["seen"] != mark {
+			t.Errorf("Read message has seen %v with marking %v: %d", content["seen"], mark, code)
+		}
+
+		if stored := memdb.GetInstance().GetMessage(address, id).Value.(*models.Message); stored.Seen != mark {
+			t.Errorf("Stored message has seen %v with marking %v", stored.Seen, mark)
+		}
+	}
+
+	//Only message read without marking is unread, count doesn't depend on page
+	code, body := apiRequest("GET", fmt.Sprintf("/mailboxes/%s/messages?limit=1&flagged=true", address), "")
+
+	if code != http.StatusOK || body["unread"] != float64(1) {
+		t.Errorf("Wrong count of unread messages: %d %v", code, body["unread"])
+	}
+}
+
+//Test next links keep the opposite bound of requested range in both orders
+func Test_Routes_Page_Links_Range(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	address := memdb.GetInstance().InsertMailBox().Value.(*models.MailBox).Address
+	ids := []int{}
+	for i := 0; i < 10; i++ {
+		ids = append(ids, memdb.GetInstance().InsertMessage(address, &models.Message{To: address}).Value.(*models.Message).Id)
+	}
+
+	cases := []struct {
+		query    string
+		expected []int
+	}{
+		{fmt.Sprintf("order=desc&sinceId=%d&limit=3", ids[1]), []int{ids[9], ids[8], ids[7], ids[6], ids[5], ids[4], ids[3], ids[2]}},
+		{fmt.Sprintf("order=asc&maxId=%d&limit=3", ids[8]), []int{ids[0], ids[1], ids[2], ids[3], ids[4], ids[5], ids[6], ids[7]}},
+	}
+	for _, c := range cases {
+		received := []int{}
+		next := fmt.Sprintf("/mailboxes/%s/messages?%s", address, c.query)
+		for next != "" && len(received) <= len(ids) {
+			request, _ := http.NewRequest("GET", next, nil)
+			recorder := httptest.NewRecorder()
+			api.Handle().ServeHTTP(recorder, request)
+
+			body := map[string][]*models.Message{}
+			json.Unmarshal(recorder.Body.Bytes(), &body)
+			for _, message := range body["messages"] {
+				received = append(received, message.Id)
+			}
+
+			next = ""
+			for _, link := range strings.Split(recorder.Header().Get("Link"), ", ") {
+				if strings.HasSuffix(link, `rel="next"`) {
+					next = link[strings.Index(link, "<")+1 : strings.Index(link, ">")]
+				}
+			}
+		}
+
+		if fmt.Sprint(received) != fmt.Sprint(c.expected) {
+			t.Errorf("Pages of %s have messages %v instead of %v", c.query, received, c.expected)
+		}
+	}
+}
+
+//Test mailbox token can not add message to other mailbox through recipient of message
+func Test_Routes_Add_Message_To_Other_Mailbox(t *testing.T) {
+	auth.SetAdminKeys([]string{"admin-key"})
+	defer auth.SetAdminKeys(nil)
+
+	_, body := apiRequest("POST", "/mailboxes", "admin-key")
+	address, token := body["mailbox"].(string), body["token"].(string)
+	other := memdb.GetInstance().InsertMailBox().Value.(*models.MailBox).Address
+	path := fmt.Sprintf("/mailboxes/%s/messages", address)
+
+	code, body := apiFormRequest("POST", path, token, url.Values{"from": {"from@some.domain"}, "to": {other}, "subject": {"Other"}, "message": {"Other"}})
+
+	if code != http.StatusBadRequest || body["error"] != "bad_request" {
+		t.Errorf("Message to other mailbox is accepted: %d %v", code, body)
+	}
+
+	if messages := memdb.GetInstance().GetMailBox(other).Value.(*models.MailBox).Messages; len(messages) != 0 {
+		t.Errorf("Message is saved to other mailbox")
+	}
+
+	code, body = apiFormRequest("POST", path, token, url.Values{"from": {"from@some.domain"}, "to": {strings.ToUpper(address)}, "subject": {"Own"}, "message": {"Own"}})
+
+	if code != http.StatusOK {
+		t.Errorf("Message to own mailbox is rejected: %d %v", code, body)
+	}
+}
+
+//Test email query parameter doesn't replace authorized mailbox of path
+func Test_Routes_Mailbox_From_Query(t *testing.T) {
+	auth.SetAdminKeys([]string{"admin-key"})
+	defer auth.SetAdminKeys(nil)
+
+	_, body := apiRequest("POST", "/mailboxes", "admin-key")
+	address, token := body["mailbox"].(string), body["token"].(string)
+	other := memdb.GetInstance().InsertMailBox().Value.(*models.MailBox).Address
+	query := "?email=" + url.QueryEscape(other)
+
+	code, body := apiRequest("GET", fmt.Sprintf("/mailboxes/%s/messages%s", address, query), token)
+
+	if code != http.StatusOK || body["message"] != "Messages from "+address {
+		t.Errorf("Messages of other mailbox are listed: %d %v", code, body)
+	}
+
+	code, body = apiFormRequest("POST", fmt.Sprintf("/mailboxes/%s/messages%s", address, query), token, url.Values{"from": {"from@some.domain"}, "to": {other}, "subject": {"Other"}, "message": {"Other"}})
+
+	if code != http.StatusBadRequest {
+		t.Errorf("Message to other mailbox is accepted: %d %v", code, body)
+	}
+
+	code, _ = apiRequest("DELETE", "/mailboxes/"+other, token)
+
+	if code != http.StatusForbidden {
+		t.Errorf("Mailbox token removes other mailbox: %d", code)
+	}
+
+	code, _ = apiRequest("DELETE", fmt.Sprintf("/mailboxes/%s%s", address, query), token)
+
+	if code != http.StatusOK || !memdb.GetInstance().GetMailBox(other).Success || memdb.GetInstance().GetMailBox(address).Success {
+		t.Errorf("Other mailbox is removed instead of own: %d", code)
+	}
+}
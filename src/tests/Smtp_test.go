@@ -0,0 +1,111 @@
+package tests
+
+import (
+	"fmt"
+	"memdb"
+	"models"
+	"net"
+	"net/smtp"
+	"os"
+	"smtp_listener"
+	"strings"
+	"testing"
+)
+
+//Start SMTP listener on random port, it is closed when test ends
+func startSmtp(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Can not start listener: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go smtp_listener.Serve(listener)
+
+	return listener.Addr().String()
+}
+
+//Send message to recipient through new SMTP listener
+func sendMail(t *testing.T, from string, to string, message string) error {
+	return smtp.SendMail(startSmtp(t), nil, from, []string{to}, []byte(message))
+}
+
+//Test messages of single SMTP session are saved with envelope sender and headers
+func Test_Smtp_Receive(t *testing.T) {
+	address := memdb.GetInstance().InsertMailBox().Value.(*models.MailBox).Address
+
+	client, err := smtp.Dial(startSmtp(t))
+	if err != nil {
+		t.Fatalf("Can not connect to listener: %v", err)
+	}
+	defer client.Close()
+
+	for i, from := range []string{"first@some.domain", "second@some.domain"} {
+		if err := client.Mail(from); err != nil {
+			t.Fatalf("MAIL is rejected: %v", err)
+		}
+		if err := client.Rcpt(address); err != nil {
+			t.Fatalf("RCPT is rejected: %v", err)
+		}
+		writer, err := client.Data()
+		if err != nil {
+			t.Fatalf("DATA is rejected: %v", err)
+		}
+		fmt.Fprintf(writer, "Subject: Message %d\r\nMessage-ID: <%d@some.domain>\r\n\r\nBody %d\r\n", i, i, i)
+		if err := writer.Close(); err != nil {
+			t.Fatalf("Message is rejected: %v", err)
+		}
+	}
+	client.Quit()
+
+	messages := memdb.GetInstance().GetMailBox(address).Value.(*models.MailBox).Messages
+	if len(messages) != 2 {
+		t.Fatalf("Wrong count of received messages: %d", len(messages))
+	}
+	for i, from := range []string{"first@some.domain", "second@some.domain"} {
+		message := messages[i]
+		if message.From != from || message.Subject != fmt.Sprintf("Message %d", i) || message.MessageID != fmt.Sprintf("<%d@some.domain>", i) {
+			t.Errorf("Message %d is saved wrong: %s %s %s", i, message.From, message.Subject, message.MessageID)
+		}
+		if len(message.Raw) == 0 {
+			t.Errorf("Original data of message %d is not saved", i)
+		}
+	}
+}
+
+//Test message over size limit is rejected with 552 and recipients over limit with 452
+func Test_Smtp_Limits(t *testing.T) {
+	address := memdb.GetInstance().InsertMailBox().Value.(*models.MailBox).Address
+	os.Setenv("SMTP_MAX_MESSAGE_BYTES", "1024")
+	os.Setenv("SMTP_MAX_RECIPIENTS", "1")
+	defer os.Unsetenv("SMTP_MAX_MESSAGE_BYTES")
+	defer os.Unsetenv("SMTP_MAX_RECIPIENTS")
+
+	client, err := smtp.Dial(startSmtp(t))
+	if err != nil {
+		t.Fatalf("Can not connect to listener: %v", err)
+	}
+	defer client.Close()
+
+	if err := client.Mail("from@some.domain"); err != nil {
+		t.Fatalf("MAIL is rejected: %v", err)
+	}
+	if err := client.Rcpt(address); err != nil {
+		t.Fatalf("RCPT is rejected: %v", err)
+	}
+	if err := client.Rcpt(address); err == nil || !strings.HasPrefix(err.Error(), "452") {
+		t.Errorf("Wrong reply for recipients over limit: %v", err)
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		t.Fatalf("DATA is rejected: %v", err)
+	}
+	fmt.Fprintf(writer, "Subject: Large\r\n\r\n%s\r\n", strings.Repeat(strings.Repeat("a", 62)+"\r\n", 32))
+	if err := writer.Close(); err == nil || !strings.HasPrefix(err.Error(), "552") {
+		t.Errorf("Wrong reply for message over size limit: %v", err)
+	}
+
+	if messages := memdb.GetInstance().GetMailBox(address).Value.(*models.MailBox).Messages; len(messages) != 0 {
+		t.Errorf("Message over size limit is saved")
+	}
+}
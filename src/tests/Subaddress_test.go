@@ -0,0 +1,77 @@
+package tests
+
+import (
+	"fmt"
+	"memdb"
+	"models"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+//Test canonical address and tag with default and changed folding
+func Test_Subaddress_Canonical(t *testing.T) {
+	cases := []struct {
+		fold      bool
+		separator string
+		address   string
+		canonical string
+		tag       string
+	}{
+		{true, "+", "Email_1@Some.Domain", "email_1@some.domain", ""},
+		{true, "+", "email_1+Signup@some.domain", "email_1@some.domain", "Signup"},
+		{true, "+", "email_1+a+b@some.domain", "email_1@some.domain", "a+b"},
+		{true, "+", "+only-tag@some.domain", "+only-tag@some.domain", ""},
+		{true, "+", `"Quoted+Local"@some.domain`, `"quoted+local"@some.domain`, ""},
+		{false, "+", "Email_1+tag@Some.Domain", "Email_1@some.domain", "tag"},
+		{true, "", "email_1+tag@some.domain", "email_1+tag@some.domain", ""},
+		{true, "-", "email_1-tag@some.domain", "email_1@some.domain", "tag"},
+	}
+	defer memdb.SetAddressFolding(true, "+")
+
+	for _, c := range cases {
+		memdb.SetAddressFolding(c.fold, c.separator)
+		canonical, tag := memdb.CanonicalAddress(c.address)
+		if canonical != c.canonical || tag != c.tag {
+			t.Errorf("Address %s is %s with tag %s instead of %s with tag %s", c.address, canonical, tag, c.canonical, c.tag)
+		}
+	}
+}
+
+//Test messages to different case and subaddresses are delivered to base mailbox and filtered by tag
+func Test_Subaddress_Delivery(t *testing.T) {
+	address := memdb.GetInstance().InsertMailBox().Value.(*models.MailBox).Address
+	local, domain := address[:strings.Index(address, "@")], address[strings.Index(address, "@")+1:]
+
+	//API message to subaddress with different case
+	form := url.Values{"from": {"from@some.domain"}, "to": {strings.ToUpper(local) + "+Signup@" + strings.ToUpper(domain)}, "subject": {"API"}, "message": {"API"}}
+	if code, body := apiFormRequest("POST", fmt.Sprintf("/mailboxes/%s/messages", address), "", form); code != http.StatusOK {
+		t.Fatalf("Message to subaddress is rejected: %d %v", code, body)
+	}
+
+	//SMTP messages to subaddress and base address
+	for _, to := range []string{local + "+news@" + domain, strings.ToUpper(address)} {
+		if err := sendMail(t, "from@some.domain", to, "Subject: SMTP\r\n\r\nSMTP\r\n"); err != nil {
+			t.Fatalf("Message to %s is rejected: %v", to, err)
+		}
+	}
+
+	code, body := apiRequest("GET", fmt.Sprintf("/mailboxes/%s/messages?order=asc", strings.ToUpper(address)), "")
+	messages, _ := body["messages"].([]interface{})
+	if code != http.StatusOK || len(messages) != 3 {
+		t.Fatalf("Messages are not delivered to base mailbox: %d %v", code, body)
+	}
+	for i, tag := range []string{"Signup", "news", ""} {
+		if received := messages[i].(map[string]interface{})["tag"]; received != tag {
+			t.Errorf("Message %d has tag %v instead of %s", i, received, tag)
+		}
+	}
+
+	for tag, count := range map[string]int{"news": 1, "SIGNUP": 1, "": 1, "other": 0} {
+		_, body = apiRequest("GET", fmt.Sprintf("/mailboxes/%s/messages?tag=%s", address, tag), "")
+		if messages, _ := body["messages"].([]interface{}); len(messages) != count {
+			t.Errorf("Filter by tag %q returns %d messages instead of %d", tag, len(messages), count)
+		}
+	}
+}
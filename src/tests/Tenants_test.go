@@ -0,0 +1,138 @@
+package tests
+
+import (
+	"auth"
+	"fmt"
+	"memdb"
+	"models"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+//Test tenants have own mailboxes and id sequences
+func Test_Tenants_Isolation(t *testing.T) {
+	instance := memdb.GetInstance()
+
+	response := instance.InsertTenant(&models.Tenant{Name: "team-a"})
+	if !response.Success {
+		t.Fatalf("Tenant is not created: %v", response.Error)
+	}
+
+	response = instance.InsertTenant(&models.Tenant{Name: "team-a"})
+	if response.Code != memdb.ErrorConflict {
+		t.Errorf("Duplicate tenant is created: %v", response)
+	}
+
+	response = instance.InsertTenant(&models.Tenant{Name: "Team A"})
+	if response.Code != memdb.ErrorInvalid {
+		t.Errorf("Tenant with invalid name is created: %v", response)
+	}
+
+	tenant := instance.Tenant("team-a")
+	mailbox := tenant.InsertMailBox().Value.(*models.MailBox)
+
+	if mailbox.Id != 1 || mailbox.Address != "email_1@team-a.some.domain" {
+		t.Errorf("Tenant mailbox does not use own sequence: %d %s", mailbox.Id, mailbox.Address)
+	}
+
+	if memdb.AddressTenant(mailbox.Address) != "team-a" {
+		t.Errorf("Wrong tenant of address: %s", memdb.AddressTenant(mailbox.Address))
+	}
+
+	if instance.GetMailBox(mailbox.Address).Success {
+		t.Errorf("Tenant mailbox is visible in default tenant")
+	}
+
+	response = tenant.InsertMessage(mailbox.Address, &models.Message{To: mailbox.Address, Subject: "Tenant message", ReceivedDate: time.Now()})
+	if !response.Success || response.Value.(*models.Message).Id != 1 {
+		t.Errorf("Tenant message does not use own sequence: %v %v", response.Error, response.Value)
+	}
+
+	response = instance.Tenant("inexisting").GetMailBoxes(nil, nil)
+	if response.Code != memdb.ErrorNotFound {
+		t.Errorf("Inexisting tenant is found: %v", response)
+	}
+
+	response = instance.DeleteTenant("team-a")
+	if !response.Success {
+		t.Errorf("Tenant is not removed: %v", response.Error)
+	}
+
+	if tenant.GetMailBox(mailbox.Address).Success {
+		t.Errorf("Mailbox of removed tenant is still available")
+	}
+
+	response = instance.DeleteTenant(memdb.DefaultTenant)
+	if response.Code != memdb.ErrorInvalid {
+		t.Errorf("Default tenant is removed: %v", response)
+	}
+}
+
+//Test tenant quotas
+func Test_Tenants_Quota(t *testing.T) {
+	instance := memdb.GetInstance()
+	instance.InsertTenant(&models.Tenant{Name: "team-quota", MaxMailboxes: 1, MaxMessages: 1})
+	defer instance.DeleteTenant("team-quota")
+
+	tenant := instance.Tenant("team-quota")
+	mailbox := tenant.InsertMailBox().Value.(*models.MailBox)
+
+	response := tenant.InsertMailBox()
+	if response.Code != memdb.ErrorQuotaExceeded {
+		t.Errorf("Mailbox quota is not applied: %v", response)
+	}
+
+	first := tenant.InsertMessage(mailbox.Address, &models.Message{To: mailbox.Address, ReceivedDate: time.Now()}).Value.(*models.Message)
+	response = tenant.InsertMessage(mailbox.Address, &models.Message{To: mailbox.Address, ReceivedDate: time.Now()})
+	if response.Code != memdb.ErrorQuotaExceeded {
+		t.Errorf("Message quota is not applied: %v", response)
+	}
+
+	code, body := apiFormRequest("POST", fmt.Sprintf("/tenants/team-quota/mailboxes/%s/messages", mailbox.Address), "",
+		url.Values{"from": {"from@some.domain"}, "to": {mailbox.Address}, "subject": {"Quota"}, "message": {"Quota"}})
+	if code != http.StatusInsufficientStorage || body["error"] != "quota_exceeded" {
+		t.Errorf("Wrong response for exceeded quota: %d %v", code, body)
+	}
+
+	//Deleted message frees quota
+	tenant.DeleteMessage(mailbox.Address, first.Id)
+	response = tenant.InsertMessage(mailbox.Address, &models.Message{To: mailbox.Address, ReceivedDate: time.Now()})
+	if !response.Success {
+		t.Errorf("Quota is not freed by deleted message: %v", response)
+	}
+}
+
+//Test API keys of tenant have no access to other tenants
+func Test_Tenants_Keys(t *testing.T) {
+	auth.SetAdminKeys([]string{"admin-key"})
+	defer auth.SetAdminKeys(nil)
+
+	code, _ := apiFormRequest("POST", "/tenants", "admin-key", url.Values{"name": {"team-b"}})
+	if code != http.StatusCreated {
+		t.Fatalf("Tenant is not created by admin key: %d", code)
+	}
+	defer apiRequest("DELETE", "/tenants/team-b", "admin-key")
+
+	code, body := apiFormRequest("POST", "/tenants/team-b/keys", "admin-key", url.Values{"admin": {"true"}})
+	if code != http.StatusCreated {
+		t.Fatalf("Tenant key is not created: %d %v", code, body)
+	}
+	key := body["key"].(map[string]interface{})["key"].(string)
+
+	code, body = apiRequest("POST", "/mailboxes", key)
+	if code != http.StatusCreated || body["mailbox"] != "email_1@team-b.some.domain" {
+		t.Errorf("Tenant key does not create mailbox inside own tenant: %d %v", code, body)
+	}
+
+	code, _ = apiRequest("GET", "/tenants/default/mailboxes", key)
+	if code != http.StatusForbidden {
+		t.Errorf("Tenant key has access to other tenant: %d", code)
+	}
+
+	code, _ = apiRequest("GET", "/tenants", key)
+	if code != http.StatusForbidden {
+		t.Errorf("Tenant key has access to tenants management: %d", code)
+	}
+}
@@ -0,0 +1,97 @@
+package tests
+
+import (
+	"api"
+	"fmt"
+	"memdb"
+	"models"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+//Record spans of test instead of exporting them
+func recordSpans() (*tracetest.SpanRecorder, func()) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	return recorder, func() { otel.SetTracerProvider(previous) }
+}
+
+//Find ended span by name, parent is checked if it is given
+func findSpan(recorder *tracetest.SpanRecorder, name string, parent sdktrace.ReadOnlySpan) sdktrace.ReadOnlySpan {
+	for _, span := range recorder.Ended() {
+		if span.Name() == name && (parent == nil || span.Parent().SpanID() == parent.SpanContext().SpanID()) {
+			return span
+		}
+	}
+
+	return nil
+}
+
+//Test API request continues trace of client and database command is split into queue and execution
+func Test_Tracing_API(t *testing.T) {
+	recorder, restore := recordSpans()
+	defer restore()
+
+	address := memdb.GetInstance().InsertMailBox().Value.(*models.MailBox).Address
+	form := url.Values{"from": {"from@some.domain"}, "to": {address}, "subject": {"Tracing"}, "message": {"Tracing"}}
+
+	request, _ := http.NewRequest("POST", fmt.Sprintf("/mailboxes/%s/messages", address), strings.NewReader(form.Encode()))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	api.Handle().ServeHTTP(httptest.NewRecorder(), request)
+
+	server := findSpan(recorder, "POST /mailboxes/:email/messages", nil)
+	if server == nil || server.SpanContext().TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Fatalf("Request span doesn't continue trace of client: %v", server)
+	}
+
+	command := findSpan(recorder, "memdb insert message", server)
+	if command == nil {
+		t.Fatalf("Database command is not a child of request span")
+	}
+
+	for _, name := range []string{"memdb.queue", "memdb.execute"} {
+		if findSpan(recorder, name, command) == nil {
+			t.Errorf("Span %s is not a child of database command", name)
+		}
+	}
+}
+
+//Test SMTP session, transaction and DATA spans are parents of database command
+func Test_Tracing_SMTP(t *testing.T) {
+	recorder, restore := recordSpans()
+	defer restore()
+
+	address := memdb.GetInstance().InsertMailBox().Value.(*models.MailBox).Address
+	if err := sendMail(t, "from@some.domain", address, "Subject: Tracing\r\n\r\nTracing\r\n"); err != nil {
+		t.Fatalf("Message is rejected: %v", err)
+	}
+
+	//Session span is finished after client quits
+	var session sdktrace.ReadOnlySpan
+	for started := time.Now(); session == nil && time.Since(started) < time.Second; time.Sleep(10 * time.Millisecond) {
+		session = findSpan(recorder, "smtp session", nil)
+	}
+	if session == nil {
+		t.Fatalf("Session span is not finished")
+	}
+
+	parent := session
+	for _, name := range []string{"smtp transaction", "smtp data", "memdb insert message"} {
+		span := findSpan(recorder, name, parent)
+		if span == nil {
+			t.Fatalf("Span %s is not a child of %s", name, parent.Name())
+		}
+		parent = span
+	}
+}
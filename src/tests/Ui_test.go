@@ -0,0 +1,112 @@
+package tests
+
+import (
+	"api"
+	"fmt"
+	"memdb"
+	"models"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+//Multipart message with HTML body and attachment
+const multipartMessage = "From: from@some.domain\r\n" +
+	"Subject: =?utf-8?q?Report_=E2=9C=93?=\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/mixed; boundary=outer\r\n" +
+	"\r\n" +
+	"--outer\r\n" +
+	"Content-Type: text/html; charset=utf-8\r\n" +
+	"\r\n" +
+	"<p onclick=\"alert(1)\">Report <img src=\"cid:logo@some.domain\"><img src=\"http://tracker/pixel.gif\"></p><script>alert(1)</script>\r\n" +
+	"--outer\r\n" +
+	"Content-Type: image/png\r\n" +
+	"Content-ID: <logo@some.domain>\r\n" +
+	"Content-Disposition: inline; filename=logo.png\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"\r\n" +
+	"iVBORw0KGgo=\r\n" +
+	"--outer\r\n" +
+	"Content-Type: text/csv\r\n" +
+	"Content-Disposition: attachment; filename=report.csv\r\n" +
+	"\r\n" +
+	"a,b\r\n" +
+	"--outer--\r\n"
+
+//Save multipart message to new mailbox
+func insertMultipartMessage() (string, int) {
+	address := memdb.GetInstance().InsertMailBox().Value.(*models.MailBox).Address
+	message := &models.Message{To: address, From: "from@some.domain", Subject: "Report", Raw: []byte(multipartMessage), ReceivedDate: time.Now()}
+
+	return address, memdb.GetInstance().InsertMessage(address, message).Value.(*models.Message).Id
+}
+
+//Test web UI is served from embedded files
+func Test_Ui_Assets(t *testing.T) {
+	for path, expected := range map[string]string{"/ui/": "app.js", "/ui/app.js": "/content", "/ui/app.css": "body"} {
+		recorder := httptest.NewRecorder()
+		api.Handle().ServeHTTP(recorder, httptest.NewRequest("GET", path, nil))
+
+		if recorder.Code != http.StatusOK || !strings.Contains(recorder.Body.String(), expected) {
+			t.Errorf("UI file %s is not served: %d", path, recorder.Code)
+		}
+	}
+}
+
+//Test decoded content and attachments of message
+func Test_Ui_Message_Content(t *testing.T) {
+	address, id := insertMultipartMessage()
+
+	code, body := apiRequest("GET", fmt.Sprintf("/mailboxes/%s/messages/%d/content", address, id), "")
+	content, _ := body["content"].(map[string]interface{})
+	if code != http.StatusOK || content["subject"] != "Report ✓" || !strings.Contains(content["html"].(string), "cid:logo@some.domain") {
+		t.Fatalf("Wrong content of message: %d %v", code, body)
+	}
+
+	attachments := content["attachments"].([]interface{})
+	if len(attachments) != 2 || attachments[1].(map[string]interface{})["filename"] != "report.csv" {
+		t.Fatalf("Wrong attachments of message: %v", attachments)
+	}
+
+	recorder := httptest.NewRecorder()
+	api.Handle().ServeHTTP(recorder, httptest.NewRequest("GET", fmt.Sprintf("/mailboxes/%s/messages/%d/attachments/2", address, id), nil))
+	if recorder.Code != http.StatusOK || recorder.Body.String() != "a,b" || !strings.Contains(recorder.Header().Get("Content-Disposition"), "report.csv") {
+		t.Errorf("Wrong attachment: %d %q %v", recorder.Code, recorder.Body.String(), recorder.Header())
+	}
+
+	code, _ = apiRequest("GET", fmt.Sprintf("/mailboxes/%s/messages/%d/attachments/3", address, id), "")
+	if code != http.StatusNotFound {
+		t.Errorf("Missing attachment is found: %d", code)
+	}
+}
+
+//Test HTML body is sanitized and inline images are embedded
+func Test_Ui_Message_Html(t *testing.T) {
+	address, id := insertMultipartMessage()
+
+	recorder := httptest.NewRecorder()
+	api.Handle().ServeHTTP(recorder, httptest.NewRequest("GET", fmt.Sprintf("/tenants/default/mailboxes/%s/messages/%d/html", address, id), nil))
+
+	html := recorder.Body.String()
+	if recorder.Code != http.StatusOK || recorder.Header().Get("Content-Security-Policy") == "" {
+		t.Fatalf("HTML is not returned: %d %v", recorder.Code, recorder.Header())
+	}
+	for _, unsafe := range []string{"<script", "onclick", "http://tracker", "cid:"} {
+		if strings.Contains(html, unsafe) {
+			t.Errorf("Sanitized HTML contains %s: %s", unsafe, html)
+		}
+	}
+	if !strings.Contains(html, `src="data:image/png;base64,iVBORw0KGgo="`) {
+		t.Errorf("Inline image is not embedded: %s", html)
+	}
+
+	address = memdb.GetInstance().InsertMailBox().Value.(*models.MailBox).Address
+	plain := memdb.GetInstance().InsertMessage(address, &models.Message{To: address, From: "from@some.domain", Subject: "Plain", Body: "Plain", ReceivedDate: time.Now()})
+	code, _ := apiRequest("GET", fmt.Sprintf("/mailboxes/%s/messages/%d/html", address, plain.Value.(*models.Message).Id), "")
+	if code != http.StatusNotFound {
+		t.Errorf("Message without HTML body has HTML: %d", code)
+	}
+}
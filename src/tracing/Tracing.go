@@ -0,0 +1,154 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Name of service and instrumentation in exported spans
+const serviceName = "mail_service"
+
+// Exporters chosen by OTEL_TRACES_EXPORTER
+const (
+	ExporterNone   = "none"
+	ExporterOtlp   = "otlp"
+	ExporterStdout = "stdout"
+)
+
+func init() {
+	// W3C trace context is accepted from clients even if spans are not exported
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+}
+
+/**
+Configure export of spans from OTEL_TRACES_EXPORTER: otlp, stdout (or console) and none by default
+OTLP exporter is configured by standard OTEL_EXPORTER_OTLP_* variables, sampler by OTEL_TRACES_SAMPLER
+@params ctx context.Context
+
+@return func(context.Context) error - flushes and stops export, error
+*/
+func Setup(ctx context.Context) (func(context.Context) error, error) {
+	var exporter sdktrace.SpanExporter
+	var err error
+
+	name := os.Getenv("OTEL_TRACES_EXPORTER")
+	switch name {
+	case "", ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterOtlp:
+		exporter, err = otlptracehttp.New(ctx)
+	case ExporterStdout, "console":
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	default:
+		err = fmt.Errorf("unknown traces exporter %q", name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES override default attributes
+	res, err := resource.New(ctx,
+		resource.WithSchemaURL(semconv.SchemaURL),
+		resource.WithAttributes(semconv.ServiceName(serviceName)),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+/**
+Start new span, child of span inside context
+@params ctx context.Context - may be nil
+@params name string
+@params opts ...trace.SpanStartOption
+
+@return context.Context, trace.Span
+*/
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	// provider is read on every call, so it can be replaced after start
+	return otel.GetTracerProvider().Tracer(serviceName).Start(ctx, name, opts...)
+}
+
+/**
+Record span of already finished interval, e.g. time spent in queue
+@params ctx context.Context - context with parent span
+@params name string
+@params started time.Time
+@params finished time.Time
+
+@return void
+*/
+func Interval(ctx context.Context, name string, started time.Time, finished time.Time) {
+	_, span := Start(ctx, name, trace.WithTimestamp(started))
+	span.End(trace.WithTimestamp(finished))
+}
+
+/**
+Mark span as failed
+@params span trace.Span
+@params err error - nil is ignored
+
+@return void
+*/
+func Fail(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+/**
+Continue trace of client from W3C traceparent header
+@params ctx context.Context
+@params header http.Header
+
+@return context.Context
+*/
+func Extract(ctx context.Context, header http.Header) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(header))
+}
+
+/**
+Get trace id of span inside context
+@params ctx context.Context - may be nil
+
+@return string - empty if there is no recorded span
+*/
+func TraceId(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.IsValid() {
+		return ""
+	}
+
+	return spanContext.TraceID().String()
+}